@@ -3,43 +3,186 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/certificado"
+)
+
+var arquivoReplay = flag.String("replay", "", "arquivo de replay (.jsonl) para reproduzir passo a passo")
+
+// TLS com o lobby; -tls-ca, -tls-cert ou -tls-inseguro também ligam o TLS
+var (
+	usarTLS     = flag.Bool("tls", false, "conecta ao lobby via TLS")
+	caTLS       = flag.String("tls-ca", "", "CA PEM que assina o certificado do servidor (padrão: CAs do sistema)")
+	certTLS     = flag.String("tls-cert", "", "certificado PEM do cliente, para servidores com TLS mútuo")
+	chaveTLS    = flag.String("tls-chave", "", "chave privada PEM do certificado do cliente")
+	inseguroTLS = flag.Bool("tls-inseguro", false, "não verifica o certificado do servidor (apenas desenvolvimento)")
+	configTLS   *tls.Config
 )
 
+// endereço TCP do lobby
+const enderecoServidor = "localhost:4000"
+
+// por quanto tempo o cliente tenta retomar a sessão após uma queda
+const prazoReconexao = 30 * time.Second
+
+// conexão TCP com o servidor, substituída quando a sessão é retomada
+type conexaoServidor struct {
+	mu    sync.Mutex
+	conn  net.Conn
+	token string // token de sessão, usado em "retomar <token>"
+}
+
+func (c *conexaoServidor) atual() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+func (c *conexaoServidor) definirToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+func (c *conexaoServidor) escrever(linha string) error {
+	_, err := c.atual().Write([]byte(linha))
+	return err
+}
+
+// lê as mensagens da conexão atual até ela cair, respondendo aos heartbeats
+func (c *conexaoServidor) ler(tratar func(msg string)) error {
+	conn := c.atual()
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if n, ok := strings.CutPrefix(strings.TrimSpace(msg), "HEARTBEAT "); ok {
+			conn.Write([]byte("/heartbeat " + n + "\n"))
+			continue
+		}
+		if strings.HasPrefix(msg, "ERRO sessao_invalida") {
+			c.definirToken("") // não adianta tentar retomar de novo
+		}
+		tratar(msg)
+	}
+}
+
+// abre a conexão com o lobby, com TLS quando configurado
+func discar() (net.Conn, error) {
+	if configTLS == nil {
+		return net.Dial("tcp", enderecoServidor)
+	}
+	return tls.Dial("tcp", enderecoServidor, configTLS)
+}
+
+// reconecta e envia "retomar <token>"; falso se não há sessão ou o prazo acabou
+func (c *conexaoServidor) reconectar() bool {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token == "" {
+		return false
+	}
+	limite := time.Now().Add(prazoReconexao)
+	for time.Now().Before(limite) {
+		time.Sleep(2 * time.Second)
+		conn, err := discar()
+		if err != nil {
+			continue
+		}
+		if _, err := conn.Write([]byte("retomar " + token + "\n")); err != nil {
+			conn.Close()
+			continue
+		}
+		fmt.Println("Reconectado, retomando a sessão...")
+		c.mu.Lock()
+		c.conn.Close()
+		c.conn = conn
+		c.mu.Unlock()
+		return true
+	}
+	return false
+}
+
 // ponto de entrada do cliente, conecta ao servidor TCP e gerencia envio/recebimento de mensagens
 func main() {
+	flag.Parse()
+	if *arquivoReplay != "" {
+		if err := reproduzirReplay(*arquivoReplay); err != nil {
+			log.Fatal("Erro ao reproduzir replay:", err)
+		}
+		return
+	}
+
 	// Conexão TCP com o servidor
-	conn, err := net.Dial("tcp", "localhost:4000")
+	protocolo := "TCP"
+	if *usarTLS || *caTLS != "" || *certTLS != "" || *inseguroTLS {
+		cfg, err := certificado.Cliente(*caTLS, *certTLS, *chaveTLS, enderecoServidor, *inseguroTLS)
+		if err != nil {
+			log.Fatal("Erro na configuração TLS:", err)
+		}
+		configTLS, protocolo = cfg, "TLS"
+	}
+	conn, err := discar()
 	if err != nil {
-		log.Fatal("Erro ao conectar TCP:", err)
+		log.Fatalf("Erro ao conectar %s: %v", protocolo, err)
 	}
-	defer conn.Close()
+	servidor := &conexaoServidor{conn: conn}
+	defer servidor.atual().Close()
+
+	fmt.Printf("Conectado ao servidor %s em :4000\n", protocolo)
 
-	fmt.Println("Conectado ao servidor TCP em :4000")
+	// pings UDP da sessão, iniciados quando o servidor envia o ID de ping
+	var ping atomic.Pointer[pingador]
 
-	// Goroutine: leitura assíncrona de mensagens enviadas pelo servidor
+	// Goroutine: leitura assíncrona de mensagens enviadas pelo servidor;
+	// se a conexão cair, tenta retomar a sessão
 	go func() {
-		reader := bufio.NewReader(conn)
 		for {
-			msg, err := reader.ReadString('\n')
-			if err != nil {
-				log.Println("Desconectado do servidor:", err)
-				return
+			err := servidor.ler(func(msg string) {
+				if strings.HasPrefix(msg, "Ping UDP: ") && ping.Load() == nil {
+					campos := strings.Fields(strings.TrimPrefix(msg, "Ping UDP: "))
+					if p, err := iniciarPingador(servidor.atual(), campos); err != nil {
+						log.Println("Erro ao iniciar ping UDP:", err)
+					} else {
+						ping.Store(p)
+					}
+				}
+				if token, ok := tokenDaSessao(msg); ok {
+					servidor.definirToken(token)
+				}
+				fmt.Print("Servidor: " + msg)
+			})
+			log.Println("Desconectado do servidor:", err)
+			if !servidor.reconectar() {
+				os.Exit(1)
 			}
-			fmt.Print("Servidor: " + msg)
 		}
 	}()
 
-	// Envia o nome do jogador ao servidor 
+	// Envia o nome do jogador e a senha ao servidor; na primeira entrada do nome,
+	// a senha passa a ser a dele
 	fmt.Print("Digite seu nome: ")
 	stdin := bufio.NewReader(os.Stdin)
 	name, _ := stdin.ReadString('\n')
-	conn.Write([]byte(name))
+	fmt.Print("Digite sua senha: ")
+	senha, _ := stdin.ReadString('\n')
+	servidor.escrever(name)
+	servidor.escrever(senha)
 
 	// captura comandos do jogador e envia para o servidor
 	for {
@@ -53,18 +196,16 @@ func main() {
 		}
 
 		if cmd == "/ping" {
-        // mede latência usando UDP
-        latencia := medirPingUDP("localhost:4001")
-        if latencia > 0 {
-            fmt.Printf("Servidor: Latência UDP = %d ms\n", latencia.Milliseconds())
-        } else {
-            fmt.Println("Servidor: ping falhou")
-        }
-        continue
-    	}
+			// latência medida pelo cliente; o servidor responde com a medição dele
+			if p := ping.Load(); p != nil {
+				fmt.Println("Servidor: Ping (cliente): " + p.resumo())
+			} else {
+				fmt.Println("Servidor: ping UDP ainda não iniciado")
+			}
+		}
 
 		// Envia comando ou JSON de ação para o servidor
-		_, err := conn.Write([]byte(cmd + "\n"))
+		err := servidor.escrever(cmd + "\n")
 		if err != nil {
 			log.Println("Erro ao enviar:", err)
 			return
@@ -72,35 +213,225 @@ func main() {
 	}
 }
 
-// medirPingUDP envia um pacote UDP "ping" e espera por "pong", retornando a latência
-func medirPingUDP(endereco string) time.Duration {
-    conn, err := net.Dial("udp", endereco)
-    if err != nil {
-        log.Println("Erro UDP:", err)
-        return 0
-    }
-    defer conn.Close()
+// token de "retomar <token>", anunciado pelo servidor ao conectar
+func tokenDaSessao(msg string) (string, bool) {
+	if !strings.HasPrefix(msg, "Sessão: ") {
+		return "", false
+	}
+	_, resto, ok := strings.Cut(msg, "\"retomar ")
+	if !ok {
+		return "", false
+	}
+	token, _, ok := strings.Cut(resto, "\"")
+	return token, ok && token != ""
+}
 
-    inicio := time.Now()
-    _, err = conn.Write([]byte("ping"))
-    if err != nil {
-        log.Println("Erro ao enviar ping:", err)
-        return 0
-    }
+// intervalo entre os pings UDP enviados ao servidor
+const intervaloPing = 2 * time.Second
 
-    buf := make([]byte, 16)
-    conn.SetReadDeadline(time.Now().Add(2 * time.Second)) // timeout
-    n, err := conn.Read(buf)
-    if err != nil {
-        log.Println("Erro ao ler resposta UDP:", err)
-        return 0
-    }
+// envia pings UDP periódicos com o ID de ping e mede o RTT pelos PONGs
+// (protocolo descrito em cmd/server/ping.go)
+type pingador struct {
+	conn net.Conn
+	id   string
 
-    resposta := strings.TrimSpace(string(buf[:n]))
-    if resposta == "pong" {
-        return time.Since(inicio)
-    }
-    return 0
+	mu        sync.Mutex
+	seq       uint64
+	enviados  int
+	recebidos int
+	ultimo    time.Duration
+	medio     time.Duration
 }
 
+// campos: "<endereço> id <id>", enviados pelo servidor ao conectar
+func iniciarPingador(tcp net.Conn, campos []string) (*pingador, error) {
+	if len(campos) != 3 || campos[1] != "id" {
+		return nil, fmt.Errorf("anúncio de ping inválido: %v", campos)
+	}
+	endereco := campos[0]
+	if strings.HasPrefix(endereco, ":") {
+		// apenas a porta: usa o mesmo host da conexão TCP
+		host, _, err := net.SplitHostPort(tcp.RemoteAddr().String())
+		if err != nil {
+			return nil, err
+		}
+		endereco = net.JoinHostPort(host, endereco[1:])
+	}
+	conn, err := net.Dial("udp", endereco)
+	if err != nil {
+		return nil, err
+	}
+	p := &pingador{conn: conn, id: campos[2]}
+	go p.receber()
+	go func() {
+		for {
+			if err := p.enviar(); err != nil {
+				log.Println("Erro ao enviar ping:", err)
+				return
+			}
+			time.Sleep(intervaloPing)
+		}
+	}()
+	return p, nil
+}
+
+// PING <id> <seq> <momento>
+func (p *pingador) enviar() error {
+	p.mu.Lock()
+	p.seq++
+	p.enviados++
+	pacote := fmt.Sprintf("PING %s %d %d", p.id, p.seq, time.Now().UnixNano())
+	p.mu.Unlock()
+	_, err := p.conn.Write([]byte(pacote))
+	return err
+}
+
+// lê os PONGs, mede o RTT e confirma cada um com um ACK
+func (p *pingador) receber() {
+	buf := make([]byte, 256)
+	for {
+		n, err := p.conn.Read(buf)
+		if err != nil {
+			log.Println("Erro ao ler resposta UDP:", err)
+			return
+		}
+		campos := strings.Fields(string(buf[:n]))
+		if len(campos) != 4 || campos[0] != "PONG" {
+			continue
+		}
+		enviado, err := strconv.ParseInt(campos[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		rtt := time.Since(time.Unix(0, enviado))
+		p.conn.Write([]byte(fmt.Sprintf("ACK %s %s", p.id, campos[1])))
+
+		p.mu.Lock()
+		if p.recebidos == 0 {
+			p.medio = rtt
+		} else {
+			p.medio += (rtt - p.medio) / 8
+		}
+		p.recebidos++
+		p.ultimo = rtt
+		p.mu.Unlock()
+	}
+}
+
+func (p *pingador) resumo() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.recebidos == 0 {
+		return fmt.Sprintf("sem respostas (%d pings enviados)", p.enviados)
+	}
+	return fmt.Sprintf("RTT %.1f ms, média %.1f ms, %d/%d respostas",
+		float64(p.ultimo.Microseconds())/1000, float64(p.medio.Microseconds())/1000, p.recebidos, p.enviados)
+}
+
+// evento de partida gravado pelo servidor no arquivo de replay
+type eventoReplay struct {
+	Seq     int       `json:"seq"`
+	Momento time.Time `json:"momento"`
+	Tipo    string    `json:"tipo"`
+	Jogador string    `json:"jogador"`
+	Acao    *struct {
+		Acao    string `json:"acao"`
+		CartaID int    `json:"carta_id"`
+	} `json:"acao"`
+	Cartas    []int            `json:"cartas"`
+	Valor     int              `json:"valor"`
+	Vida      map[string]int   `json:"vida"`
+	Mao       map[string][]int `json:"mao"`
+	Turno     string           `json:"turno"`
+	Vencedor  string           `json:"vencedor"`
+	Motivo    string           `json:"motivo"`
+	Semente   int64            `json:"semente"`
+	Jogadores []struct {
+		ID   string `json:"id"`
+		Nome string `json:"nome"`
+	} `json:"jogadores"`
+}
+
+// reproduz um arquivo de replay, avançando um evento a cada Enter
+func reproduzirReplay(caminho string) error {
+	f, err := os.Open(caminho)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	nomes := map[string]string{}
+	var ordem []string // IDs na ordem em que aparecem no início da partida
+	nome := func(id string) string {
+		if n, ok := nomes[id]; ok {
+			return n
+		}
+		return id
+	}
+	vida := func(v map[string]int) string {
+		var partes []string
+		for _, id := range ordem {
+			partes = append(partes, fmt.Sprintf("%s: %d", nome(id), v[id]))
+		}
+		return strings.Join(partes, " | ")
+	}
 
+	stdin := bufio.NewReader(os.Stdin)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var inicio time.Time
+	for scanner.Scan() {
+		var ev eventoReplay
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return err
+		}
+		if ev.Tipo == "inicio" {
+			inicio = ev.Momento
+			for _, jr := range ev.Jogadores {
+				nomes[jr.ID] = jr.Nome
+				ordem = append(ordem, jr.ID)
+			}
+		}
+
+		fmt.Printf("[%3d] +%s ", ev.Seq, ev.Momento.Sub(inicio).Truncate(time.Millisecond))
+		switch ev.Tipo {
+		case "inicio":
+			fmt.Printf("Início: %s x %s (semente %d) | %s | vez de %s\n",
+				nome(ev.Jogadores[0].ID), nome(ev.Jogadores[1].ID), ev.Semente, vida(ev.Vida), nome(ev.Turno))
+		case "compra":
+			fmt.Printf("%s comprou as cartas %v\n", nome(ev.Jogador), ev.Cartas)
+		case "acao":
+			if ev.Acao != nil && ev.Acao.Acao == "jogar_carta" {
+				fmt.Printf("%s jogou a carta [%d]\n", nome(ev.Jogador), ev.Acao.CartaID)
+			} else if ev.Acao != nil {
+				fmt.Printf("%s: %s\n", nome(ev.Jogador), ev.Acao.Acao)
+			}
+		case "dano":
+			fmt.Printf("%s sofreu %d de dano | %s\n", nome(ev.Jogador), ev.Valor, vida(ev.Vida))
+		case "turno":
+			fmt.Printf("Vez de %s\n", nome(ev.Turno))
+		case "tempo":
+			fmt.Printf("Tempo esgotado para %s\n", nome(ev.Jogador))
+		case "desconexao":
+			fmt.Printf("%s desconectou\n", nome(ev.Jogador))
+		case "encerramento_admin":
+			fmt.Printf("Partida encerrada por um administrador\n")
+		case "fim":
+			if ev.Vencedor == "" {
+				fmt.Printf("Fim (%s): sem vencedor | %s\n", ev.Motivo, vida(ev.Vida))
+			} else {
+				fmt.Printf("Fim (%s): %s venceu | %s\n", ev.Motivo, nome(ev.Vencedor), vida(ev.Vida))
+			}
+		default:
+			fmt.Printf("%s\n", ev.Tipo)
+		}
+
+		fmt.Print("(Enter para avançar, q para sair) ")
+		linha, err := stdin.ReadString('\n')
+		if err != nil || strings.TrimSpace(linha) == "q" {
+			fmt.Println()
+			return nil
+		}
+	}
+	return scanner.Err()
+}
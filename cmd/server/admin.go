@@ -0,0 +1,416 @@
+// admin.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Console de admin: as operações ficam aqui e são usadas pelos comandos /admin (apenas
+// admins autenticados) e pela API HTTP (apiadmin.go). Cada operação recebe o logger de quem a
+// pediu, para que o log mostre o autor (jogador ou requisição HTTP).
+
+const erroExpulso = "expulso"
+
+// tamanho máximo de um anúncio, em caracteres
+const tamanhoAnuncio = 500
+
+var (
+	errJogadorNaoEncontrado = errors.New("jogador não está conectado")
+	errPartidaNaoEncontrada = errors.New("partida não encontrada")
+	errColecaoDesconhecida  = errors.New("coleção desconhecida")
+	errAnuncioInvalido      = fmt.Errorf("anúncio vazio ou com mais de %d caracteres", tamanhoAnuncio)
+	errDuracaoInvalida      = errors.New("duração inválida (ex: 10m, 2h, 7d; até 3650d)")
+)
+
+// jogador conectado, como aparece no console e na API
+type InfoJogador struct {
+	ID        string `json:"id"`
+	Nome      string `json:"nome"`
+	Endereco  string `json:"endereco"`
+	Papel     string `json:"papel,omitempty"`
+	EmPartida bool   `json:"em_partida"`
+	NaFila    bool   `json:"na_fila"`
+	Suspenso  bool   `json:"suspenso"` // caiu durante uma partida e pode retomar a sessão
+	Partida   string `json:"partida,omitempty"`
+	PingMs    int64  `json:"ping_ms"`
+}
+
+// resumo de uma partida em andamento
+type InfoPartida struct {
+	ID           string         `json:"id"`
+	Jogadores    [2]string      `json:"jogadores"`
+	Turno        string         `json:"turno"`
+	Vida         map[string]int `json:"vida"` // nome -> vida
+	Espectadores int            `json:"espectadores"`
+	Privada      bool           `json:"privada"`
+	Criada       time.Time      `json:"criada"`
+	Eventos      int            `json:"eventos"`
+}
+
+// estado completo de uma partida: resumo, mãos, semente, chat e o log de eventos
+type EstadoPartidaAdmin struct {
+	InfoPartida
+	IDs          map[string]string `json:"ids"`  // nome -> ID do jogador
+	Maos         map[string][]int  `json:"maos"` // nome -> cartas na mão
+	Semente      int64             `json:"semente"`
+	Espectadores []string          `json:"nomes_espectadores"`
+	Chat         []MensagemChat    `json:"chat"`
+	Log          []EventoPartida   `json:"log"`
+}
+
+// jogador na fila de matchmaking
+type InfoFila struct {
+	Nome   string  `json:"nome"`
+	Espera float64 `json:"espera_segundos"`
+	PingMs int64   `json:"ping_ms"`
+}
+
+func infoJogador(j *Jogador) InfoJogador {
+	info := InfoJogador{ID: j.ID, Nome: j.Nome}
+	if r := j.remoto.Load(); r != nil {
+		info.Endereco = *r
+	}
+	j.mu.Lock()
+	info.Papel, info.EmPartida, info.NaFila, info.Suspenso = j.Papel, j.EmPartida, j.NaFila, j.suspenso
+	info.PingMs = j.UltimoPing.Milliseconds()
+	j.mu.Unlock()
+	if p := encontrarPartidaPorJogador(j.ID); p != nil {
+		info.Partida = p.ID
+	}
+	return info
+}
+
+// jogadores conectados (e suspensos), em ordem de nome
+func listarJogadoresAdmin() []InfoJogador {
+	lista := []InfoJogador{}
+	for _, j := range jogadoresConectados() {
+		lista = append(lista, infoJogador(j))
+	}
+	sort.Slice(lista, func(a, b int) bool { return lista[a].Nome < lista[b].Nome })
+	return lista
+}
+
+// resumo da partida (goroutine da partida)
+func (p *Partida) info() InfoPartida {
+	nomes := map[string]string{p.A.ID: p.A.Nome, p.B.ID: p.B.Nome}
+	info := InfoPartida{
+		ID: p.ID, Jogadores: [2]string{p.A.Nome, p.B.Nome}, Turno: nomes[p.Turno], Vida: map[string]int{},
+		Espectadores: len(p.Espectadores), Privada: p.Privada, Criada: p.Criada, Eventos: len(p.Eventos),
+	}
+	for id, v := range p.Vida {
+		info.Vida[nomes[id]] = v
+	}
+	return info
+}
+
+// partidas em andamento, das mais antigas para as mais novas
+func listarPartidasAdmin() []InfoPartida {
+	lista := []InfoPartida{}
+	for _, p := range partidasEmAndamento() {
+		var info InfoPartida
+		if p.executar(func(p *Partida) { info = p.info() }) {
+			lista = append(lista, info)
+		}
+	}
+	sort.Slice(lista, func(a, b int) bool { return lista[a].Criada.Before(lista[b].Criada) })
+	return lista
+}
+
+// estado completo da partida, lido pela goroutine dela
+func estadoPartidaAdmin(id string) (EstadoPartidaAdmin, error) {
+	p := partidaPorID(id)
+	if p == nil {
+		return EstadoPartidaAdmin{}, errPartidaNaoEncontrada
+	}
+	var e EstadoPartidaAdmin
+	ok := p.executar(func(p *Partida) {
+		e = EstadoPartidaAdmin{
+			InfoPartida:  p.info(),
+			IDs:          map[string]string{p.A.Nome: p.A.ID, p.B.Nome: p.B.ID},
+			Maos:         map[string][]int{},
+			Semente:      p.Semente,
+			Espectadores: []string{},
+			Chat:         append([]MensagemChat{}, p.Chat...),
+			Log:          append([]EventoPartida{}, p.Eventos...),
+		}
+		for _, jog := range []*Jogador{p.A, p.B} {
+			e.Maos[jog.Nome] = append([]int{}, p.Mao[jog.ID]...)
+		}
+		for _, esp := range p.Espectadores {
+			e.Espectadores = append(e.Espectadores, esp.Nome)
+		}
+		sort.Strings(e.Espectadores)
+	})
+	if !ok {
+		return EstadoPartidaAdmin{}, errPartidaNaoEncontrada
+	}
+	return e, nil
+}
+
+// fila de matchmaking, em ordem de chegada
+func listarFilaAdmin() []InfoFila {
+	agora := time.Now()
+	lista := []InfoFila{}
+	filaMu.Lock()
+	for _, e := range filaPartida {
+		e.Jogador.mu.Lock()
+		ping := e.Jogador.UltimoPing.Milliseconds()
+		e.Jogador.mu.Unlock()
+		lista = append(lista, InfoFila{Nome: e.Jogador.Nome, Espera: agora.Sub(e.Entrada).Seconds(), PingMs: ping})
+	}
+	filaMu.Unlock()
+	return lista
+}
+
+// desconecta o jogador sem o prazo de reconexão; se ele estava suspenso, sai na hora
+func expulsarJogador(logger *slog.Logger, nome, motivo string) error {
+	alvo := jogadorPorNome(nome)
+	if alvo == nil {
+		return errJogadorNaoEncontrado
+	}
+	alvo.mu.Lock()
+	alvo.expulso = true
+	suspenso := alvo.suspenso
+	alvo.mu.Unlock()
+
+	logger.Info("jogador expulso", "alvo", nome, "alvo_id", alvo.ID, "motivo", motivo)
+	if suspenso {
+		expirarReconexao(alvo)
+		return nil
+	}
+	aviso := "você foi desconectado por um administrador"
+	if motivo != "" {
+		aviso += " (" + motivo + ")"
+	}
+	alvo.enviarErro(erroExpulso, aviso)
+	alvo.derrubarAposAviso()
+	return nil
+}
+
+// bane o nome (conectado ou não) pela duração informada
+func banirJogador(logger *slog.Logger, autor, nome string, duracao time.Duration, motivo string) (time.Time, error) {
+	if duracao <= 0 {
+		return time.Time{}, errDuracaoInvalida
+	}
+	return aplicarPunicao(logger, autor, "banir", nome, duracao, motivo), nil
+}
+
+// encerra a partida; vencedor é o nome de um dos jogadores ou vazio (sem vencedor)
+func encerrarPartidaAdmin(logger *slog.Logger, id, vencedor string) error {
+	p := partidaPorID(id)
+	if p == nil {
+		return errPartidaNaoEncontrada
+	}
+	var err error
+	ok := p.executar(func(p *Partida) {
+		vencedorID := ""
+		switch vencedor {
+		case "":
+		case p.A.Nome:
+			vencedorID = p.A.ID
+		case p.B.Nome:
+			vencedorID = p.B.ID
+		default:
+			vencedorID = "?" // recusado pelo motor
+		}
+		err = p.encerrarPorAdmin(vencedorID)
+	})
+	if !ok {
+		return errPartidaNaoEncontrada
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("partida encerrada pelo admin", "match_id", id, "vencedor", vencedor)
+	return nil
+}
+
+// repõe n pacotes da coleção (sem coleção, todas; sem n, completa o estoque alvo); retorna
+// quantos pacotes entraram em cada coleção
+func reporBoostersAdmin(autor, colecao string, n int) (map[string]int, error) {
+	repostos := map[string]int{}
+	if colecao == "" {
+		for _, c := range colecoesBooster {
+			repostos[c.Nome] = reporBoosters(c.Nome, 0, autor)
+		}
+		return repostos, nil
+	}
+	if colecaoBooster(colecao) == nil {
+		return nil, errColecaoDesconhecida
+	}
+	repostos[colecao] = reporBoosters(colecao, n, autor)
+	return repostos, nil
+}
+
+// envia um anúncio a todos os jogadores conectados; retorna quantos receberam
+func anunciar(logger *slog.Logger, texto string) (int, error) {
+	texto = strings.Join(strings.FieldsFunc(texto, unicode.IsControl), " ")
+	texto = strings.TrimSpace(texto)
+	if texto == "" || len([]rune(texto)) > tamanhoAnuncio {
+		return 0, errAnuncioInvalido
+	}
+	msg := fmt.Sprintf("\n============================\n[Anúncio do servidor] %s\n============================", texto)
+	jogadores := jogadoresConectados()
+	for _, j := range jogadores {
+		j.enviarMensagem(msg)
+	}
+	logger.Info("anúncio enviado", "texto", texto, "jogadores", len(jogadores))
+	return len(jogadores), nil
+}
+
+// relê o catálogo de cartas e registra quem pediu
+func recarregarCartasAdmin(logger *slog.Logger) (int, error) {
+	alterados, err := recarregarCartas()
+	if err != nil {
+		logger.Error("erro ao recarregar o catálogo de cartas", "erro", err)
+		return 0, err
+	}
+	logger.Info("catálogo de cartas recarregado", "alterados", alterados)
+	return alterados, nil
+}
+
+// interpreta os subcomandos de /admin
+func tratarAdmin(j *Jogador, args []string) {
+	if !j.temPapel(papelAdmin) {
+		j.enviarErro(erroSemPermissao, "comando restrito a admins")
+		return
+	}
+	uso := "Uso: /admin jogadores|partidas|fila|estado <partida>|expulsar <nome> [motivo]|banir <nome> <duração> [motivo]|" +
+		"encerrar <partida> [vencedor]|repor [colecao] [n]|anunciar <texto>|recarregar-cartas"
+	if len(args) == 0 {
+		j.enviarMensagem(uso)
+		return
+	}
+	logger := j.logger()
+	var builder strings.Builder
+	switch args[0] {
+	case "jogadores":
+		lista := listarJogadoresAdmin()
+		builder.WriteString(fmt.Sprintf("Jogadores conectados (%d):\n", len(lista)))
+		for _, info := range lista {
+			estado := "lobby"
+			switch {
+			case info.Suspenso:
+				estado = "suspenso"
+			case info.Partida != "":
+				estado = "em " + info.Partida
+			case info.NaFila:
+				estado = "na fila"
+			}
+			builder.WriteString(fmt.Sprintf("  %-16s %-21s %-28s ping %d ms %s\n", info.Nome, info.Endereco, estado, info.PingMs, info.Papel))
+		}
+	case "partidas":
+		lista := listarPartidasAdmin()
+		builder.WriteString(fmt.Sprintf("Partidas em andamento (%d):\n", len(lista)))
+		for _, info := range lista {
+			builder.WriteString(fmt.Sprintf("  %s: %s (%d) x %s (%d), vez de %s, %d espectadores, há %s\n",
+				info.ID, info.Jogadores[0], info.Vida[info.Jogadores[0]], info.Jogadores[1], info.Vida[info.Jogadores[1]],
+				info.Turno, info.Espectadores, time.Since(info.Criada).Round(time.Second)))
+		}
+	case "fila":
+		lista := listarFilaAdmin()
+		builder.WriteString(fmt.Sprintf("Fila de matchmaking (%d):\n", len(lista)))
+		for _, info := range lista {
+			builder.WriteString(fmt.Sprintf("  %-16s esperando há %.0fs, ping %d ms\n", info.Nome, info.Espera, info.PingMs))
+		}
+	case "estado":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /admin estado <partida>")
+			return
+		}
+		e, err := estadoPartidaAdmin(args[1])
+		if err != nil {
+			j.enviarMensagem(err.Error())
+			return
+		}
+		dados, _ := json.MarshalIndent(e, "", "  ")
+		builder.WriteString(fmt.Sprintf("Estado da partida %s:\n%s\n", args[1], dados))
+	case "expulsar":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /admin expulsar <nome> [motivo]")
+			return
+		}
+		if err := expulsarJogador(logger, args[1], strings.Join(args[2:], " ")); err != nil {
+			j.enviarMensagem(err.Error())
+			return
+		}
+		builder.WriteString(fmt.Sprintf("%s expulso", args[1]))
+	case "banir":
+		if len(args) < 3 {
+			j.enviarMensagem("Uso: /admin banir <nome> <duração> [motivo]")
+			return
+		}
+		duracao, err := interpretarDuracao(args[2])
+		if err != nil {
+			duracao = 0
+		}
+		ate, err := banirJogador(logger, j.Nome, args[1], duracao, strings.Join(args[3:], " "))
+		if err != nil {
+			j.enviarMensagem(err.Error())
+			return
+		}
+		builder.WriteString(fmt.Sprintf("%s banido até %s", args[1], ate.Format("02/01 15:04")))
+	case "encerrar":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /admin encerrar <partida> [vencedor]")
+			return
+		}
+		if err := encerrarPartidaAdmin(logger, args[1], strings.Join(args[2:], " ")); err != nil {
+			j.enviarMensagem("Não foi possível encerrar: " + err.Error())
+			return
+		}
+		builder.WriteString(fmt.Sprintf("Partida %s encerrada", args[1]))
+	case "repor":
+		colecao, n := "", 0
+		if len(args) >= 2 {
+			colecao = args[1]
+		}
+		if len(args) >= 3 {
+			v, err := strconv.Atoi(args[2])
+			if err != nil || v <= 0 {
+				j.enviarMensagem("Uso: /admin repor [colecao] [quantidade]")
+				return
+			}
+			n = v
+		}
+		repostos, err := reporBoostersAdmin(j.Nome, colecao, n)
+		if err != nil {
+			j.enviarMensagem(err.Error())
+			return
+		}
+		nomes := make([]string, 0, len(repostos))
+		for nome := range repostos {
+			nomes = append(nomes, nome)
+		}
+		sort.Strings(nomes)
+		builder.WriteString("Boosters repostos:")
+		for _, nome := range nomes {
+			builder.WriteString(fmt.Sprintf(" %s %d", nome, repostos[nome]))
+		}
+	case "anunciar":
+		n, err := anunciar(logger, strings.Join(args[1:], " "))
+		if err != nil {
+			j.enviarMensagem(err.Error())
+			return
+		}
+		builder.WriteString(fmt.Sprintf("Anúncio enviado a %d jogadores", n))
+	case "recarregar-cartas":
+		alterados, err := recarregarCartasAdmin(logger)
+		if err != nil {
+			j.enviarMensagem("Catálogo mantido: " + err.Error())
+			return
+		}
+		builder.WriteString(fmt.Sprintf("Catálogo recarregado: %d cartas com nome novo", alterados))
+	default:
+		builder.WriteString(uso)
+	}
+	j.enviarMensagem(builder.String())
+}
@@ -0,0 +1,424 @@
+// amigos.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// estados de presença mostrados aos amigos
+const (
+	presencaOnline    = "online"
+	presencaNaFila    = "na fila"
+	presencaEmPartida = "em partida"
+	presencaOffline   = "offline"
+)
+
+// tempo que um convite para partida privada fica válido
+const validadeConvite = time.Minute
+
+// dados de amizade persistidos (identificados pelo nome do jogador)
+type dadosAmigos struct {
+	Amigos  map[string][]string `json:"amigos"`  // nome -> amigos
+	Pedidos map[string][]string `json:"pedidos"` // destinatário -> quem pediu
+}
+
+var (
+	amigosMu sync.Mutex
+	amigos   = map[string]map[string]bool{}      // nome -> amigos
+	pedidos  = map[string]map[string]bool{}      // destinatário -> quem pediu
+	convites = map[string]map[string]time.Time{} // convidado -> quem convidou -> validade
+
+	// serializa os avisos de presença para que cheguem na ordem em que o estado mudou
+	presencaMu     sync.Mutex
+	ultimaPresenca = map[string]string{} // nome -> último estado avisado
+)
+
+// interpreta os subcomandos de /amigo
+func tratarAmigo(j *Jogador, args []string) {
+	if len(args) == 0 {
+		j.enviarMensagem("Uso: /amigo adicionar|aceitar|recusar|remover <nome>, /amigo lista, /amigo pedidos, /amigo convidar <nome>, /amigo jogar <nome>")
+		return
+	}
+	if args[0] == "lista" {
+		listarAmigos(j)
+		return
+	}
+	if args[0] == "pedidos" {
+		listarPedidos(j)
+		return
+	}
+	if len(args) < 2 {
+		j.enviarMensagem(fmt.Sprintf("Uso: /amigo %s <nome>", args[0]))
+		return
+	}
+	nome := args[1]
+	if nome == j.Nome {
+		j.enviarMensagem("Você não pode fazer isso consigo mesmo")
+		return
+	}
+	switch args[0] {
+	case "adicionar":
+		adicionarAmigo(j, nome)
+	case "aceitar":
+		aceitarAmigo(j, nome)
+	case "recusar":
+		recusarAmigo(j, nome)
+	case "remover":
+		removerAmigo(j, nome)
+	case "convidar":
+		convidarAmigo(j, nome)
+	case "jogar":
+		aceitarConvite(j, nome)
+	default:
+		j.enviarMensagem("Subcomando de amigo desconhecido")
+	}
+}
+
+// informa se os dois jogadores são amigos
+func saoAmigos(a, b string) bool {
+	amigosMu.Lock()
+	defer amigosMu.Unlock()
+	return amigos[a][b]
+}
+
+// envia um pedido de amizade; se o outro já tinha pedido, a amizade é confirmada
+func adicionarAmigo(j *Jogador, nome string) {
+	if bloqueou(nome, j.Nome) {
+		j.enviarErro(erroBloqueado, fmt.Sprintf("%s não aceita pedidos seus", nome))
+		return
+	}
+	amigosMu.Lock()
+	if amigos[j.Nome][nome] {
+		amigosMu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("%s já é seu amigo", nome))
+		return
+	}
+	if pedidos[j.Nome][nome] {
+		amigosMu.Unlock()
+		aceitarAmigo(j, nome)
+		return
+	}
+	if pedidos[nome] == nil {
+		pedidos[nome] = map[string]bool{}
+	}
+	pedidos[nome][j.Nome] = true
+	amigosMu.Unlock()
+	salvarAmigos()
+
+	j.enviarMensagem(fmt.Sprintf("Pedido de amizade enviado para %s", nome))
+	if d := jogadorPorNome(nome); d != nil {
+		d.enviarMensagem(fmt.Sprintf("[amigos] %s quer ser seu amigo (/amigo aceitar %s ou /amigo recusar %s)", j.Nome, j.Nome, j.Nome))
+	}
+}
+
+// confirma um pedido de amizade recebido
+func aceitarAmigo(j *Jogador, nome string) {
+	amigosMu.Lock()
+	if !pedidos[j.Nome][nome] {
+		amigosMu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("Nenhum pedido de amizade de %s", nome))
+		return
+	}
+	delete(pedidos[j.Nome], nome)
+	delete(pedidos[nome], j.Nome)
+	for _, par := range [][2]string{{j.Nome, nome}, {nome, j.Nome}} {
+		if amigos[par[0]] == nil {
+			amigos[par[0]] = map[string]bool{}
+		}
+		amigos[par[0]][par[1]] = true
+	}
+	amigosMu.Unlock()
+	salvarAmigos()
+
+	j.enviarMensagem(fmt.Sprintf("Agora você e %s são amigos (%s)", nome, presencaDe(nome)))
+	if d := jogadorPorNome(nome); d != nil {
+		d.enviarMensagem(fmt.Sprintf("[amigos] %s aceitou seu pedido de amizade (%s)", j.Nome, presencaDe(j.Nome)))
+	}
+}
+
+// recusa um pedido de amizade ou um convite para partida
+func recusarAmigo(j *Jogador, nome string) {
+	amigosMu.Lock()
+	pedido := pedidos[j.Nome][nome]
+	delete(pedidos[j.Nome], nome)
+	_, convite := convites[j.Nome][nome]
+	delete(convites[j.Nome], nome)
+	amigosMu.Unlock()
+
+	if !pedido && !convite {
+		j.enviarMensagem(fmt.Sprintf("Nenhum pedido ou convite de %s", nome))
+		return
+	}
+	if pedido {
+		salvarAmigos()
+		j.enviarMensagem(fmt.Sprintf("Pedido de amizade de %s recusado", nome))
+	}
+	if convite {
+		j.enviarMensagem(fmt.Sprintf("Convite de %s recusado", nome))
+		if d := jogadorPorNome(nome); d != nil {
+			d.enviarMensagem(fmt.Sprintf("[amigos] %s recusou seu convite", j.Nome))
+		}
+	}
+}
+
+// desfaz a amizade dos dois lados
+func removerAmigo(j *Jogador, nome string) {
+	amigosMu.Lock()
+	existia := amigos[j.Nome][nome]
+	delete(amigos[j.Nome], nome)
+	delete(amigos[nome], j.Nome)
+	delete(pedidos[nome], j.Nome)
+	amigosMu.Unlock()
+	if !existia {
+		j.enviarMensagem(fmt.Sprintf("%s não é seu amigo", nome))
+		return
+	}
+	salvarAmigos()
+	j.enviarMensagem(fmt.Sprintf("%s foi removido dos seus amigos", nome))
+}
+
+// lista os amigos com a presença de cada um (online primeiro)
+func listarAmigos(j *Jogador) {
+	nomes := amigosDe(j.Nome)
+	if len(nomes) == 0 {
+		j.enviarMensagem("Você ainda não tem amigos (use /amigo adicionar <nome>)")
+		return
+	}
+	estados := make(map[string]string, len(nomes))
+	for _, nome := range nomes {
+		estados[nome] = presencaDe(nome)
+	}
+	sort.SliceStable(nomes, func(a, b int) bool {
+		return estados[nomes[a]] != presencaOffline && estados[nomes[b]] == presencaOffline
+	})
+	var builder strings.Builder
+	builder.WriteString("Amigos:\n")
+	for _, nome := range nomes {
+		builder.WriteString(fmt.Sprintf("  %s (%s)\n", nome, estados[nome]))
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// lista os pedidos de amizade pendentes
+func listarPedidos(j *Jogador) {
+	amigosMu.Lock()
+	nomes := make([]string, 0, len(pedidos[j.Nome]))
+	for nome := range pedidos[j.Nome] {
+		nomes = append(nomes, nome)
+	}
+	amigosMu.Unlock()
+	if len(nomes) == 0 {
+		j.enviarMensagem("Nenhum pedido de amizade pendente")
+		return
+	}
+	sort.Strings(nomes)
+	j.enviarMensagem("Pedidos de amizade: " + strings.Join(nomes, ", ") + " (use /amigo aceitar <nome>)")
+}
+
+// nomes dos amigos do jogador, em ordem alfabética
+func amigosDe(nome string) []string {
+	amigosMu.Lock()
+	nomes := make([]string, 0, len(amigos[nome]))
+	for a := range amigos[nome] {
+		nomes = append(nomes, a)
+	}
+	amigosMu.Unlock()
+	sort.Strings(nomes)
+	return nomes
+}
+
+// estado de presença atual do jogador
+func presencaDe(nome string) string {
+	j := jogadorPorNome(nome)
+	if j == nil {
+		return presencaOffline
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case j.desconectado:
+		return presencaOffline
+	case j.EmPartida:
+		return presencaEmPartida
+	case j.NaFila:
+		return presencaNaFila
+	}
+	return presencaOnline
+}
+
+// avisa os amigos conectados quando a presença do jogador muda
+func notificarPresenca(nome string) {
+	presencaMu.Lock()
+	defer presencaMu.Unlock()
+	estado := presencaDe(nome)
+	if ultimaPresenca[nome] == estado {
+		return
+	}
+	if estado == presencaOffline {
+		delete(ultimaPresenca, nome)
+	} else {
+		ultimaPresenca[nome] = estado
+	}
+	for _, amigo := range amigosDe(nome) {
+		if d := jogadorPorNome(amigo); d != nil {
+			d.enviarEstado("presenca:"+nome, fmt.Sprintf("[amigos] %s está %s", nome, estado))
+		}
+	}
+}
+
+// avisa sobre pedidos pendentes e amigos online ao conectar
+func avisarAmigosAoConectar(j *Jogador) {
+	amigosMu.Lock()
+	qtdPedidos := len(pedidos[j.Nome])
+	amigosMu.Unlock()
+	if qtdPedidos > 0 {
+		j.enviarMensagem(fmt.Sprintf("Você tem %d pedido(s) de amizade (use /amigo pedidos)", qtdPedidos))
+	}
+	var online []string
+	for _, nome := range amigosDe(j.Nome) {
+		if estado := presencaDe(nome); estado != presencaOffline {
+			online = append(online, fmt.Sprintf("%s (%s)", nome, estado))
+		}
+	}
+	if len(online) > 0 {
+		j.enviarMensagem("Amigos online: " + strings.Join(online, ", "))
+	}
+	notificarPresenca(j.Nome)
+}
+
+// convida um amigo para uma partida privada
+func convidarAmigo(j *Jogador, nome string) {
+	if !saoAmigos(j.Nome, nome) {
+		j.enviarMensagem(fmt.Sprintf("%s não é seu amigo", nome))
+		return
+	}
+	d := jogadorPorNome(nome)
+	if d == nil {
+		j.enviarMensagem(fmt.Sprintf("%s não está conectado", nome))
+		return
+	}
+	if presencaDe(j.Nome) == presencaEmPartida {
+		j.enviarMensagem("Você já está em uma partida")
+		return
+	}
+	if presencaDe(nome) == presencaEmPartida {
+		j.enviarMensagem(fmt.Sprintf("%s está em uma partida", nome))
+		return
+	}
+	amigosMu.Lock()
+	if convites[nome] == nil {
+		convites[nome] = map[string]time.Time{}
+	}
+	convites[nome][j.Nome] = time.Now().Add(validadeConvite)
+	amigosMu.Unlock()
+
+	j.enviarMensagem(fmt.Sprintf("Convite enviado para %s (válido por %s)", nome, validadeConvite))
+	d.enviarMensagem(fmt.Sprintf("[amigos] %s convidou você para uma partida privada (/amigo jogar %s ou /amigo recusar %s)", j.Nome, j.Nome, j.Nome))
+}
+
+// aceita o convite de um amigo e inicia a partida privada
+func aceitarConvite(j *Jogador, nome string) {
+	amigosMu.Lock()
+	validade, ok := convites[j.Nome][nome]
+	delete(convites[j.Nome], nome)
+	amigosMu.Unlock()
+	if !ok || time.Now().After(validade) {
+		j.enviarMensagem(fmt.Sprintf("Nenhum convite válido de %s", nome))
+		return
+	}
+	autor := jogadorPorNome(nome)
+	if autor == nil {
+		j.enviarMensagem(fmt.Sprintf("%s não está mais conectado", nome))
+		return
+	}
+	if !reservarParaPartida(j) {
+		j.enviarMensagem("Você já está em uma partida")
+		return
+	}
+	if !reservarParaPartida(autor) {
+		j.mu.Lock()
+		j.EmPartida = false
+		j.mu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("%s já está em uma partida", nome))
+		return
+	}
+	pararDeAssistir(j)
+	pararDeAssistir(autor)
+	criarPartida(autor, j, true)
+}
+
+// partidas privadas só aparecem para os amigos dos jogadores
+func podeVerPartida(j *Jogador, p *Partida) bool {
+	if !p.Privada || j.ID == p.A.ID || j.ID == p.B.ID {
+		return true
+	}
+	return saoAmigos(j.Nome, p.A.Nome) || saoAmigos(j.Nome, p.B.Nome)
+}
+
+// grava amizades e pedidos pendentes
+func salvarAmigos() {
+	amigosMu.Lock()
+	d := dadosAmigos{Amigos: paraListas(amigos), Pedidos: paraListas(pedidos)}
+	dados, err := json.Marshal(d)
+	amigosMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "amigos.json"), dados)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar amigos", "erro", err)
+	}
+}
+
+// converte os conjuntos de nomes em listas ordenadas
+func paraListas(conjuntos map[string]map[string]bool) map[string][]string {
+	listas := make(map[string][]string, len(conjuntos))
+	for nome, conjunto := range conjuntos {
+		if len(conjunto) == 0 {
+			continue
+		}
+		for outro := range conjunto {
+			listas[nome] = append(listas[nome], outro)
+		}
+		sort.Strings(listas[nome])
+	}
+	return listas
+}
+
+// carrega amizades e pedidos pendentes
+func carregarAmigos() error {
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "amigos.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var d dadosAmigos
+	if err := json.Unmarshal(dados, &d); err != nil {
+		return err
+	}
+	amigosMu.Lock()
+	defer amigosMu.Unlock()
+	for nome, lista := range d.Amigos {
+		amigos[nome] = map[string]bool{}
+		for _, a := range lista {
+			amigos[nome][a] = true
+		}
+	}
+	for nome, lista := range d.Pedidos {
+		pedidos[nome] = map[string]bool{}
+		for _, a := range lista {
+			pedidos[nome][a] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// apiadmin.go
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/certificado"
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// API HTTP de admin: as mesmas operações do /admin (admin.go) em JSON, autenticadas pelo
+// token de admin no cabeçalho "Authorization: Bearer <token>". Só sobe com -token-admin e,
+// por padrão, escuta apenas no loopback. Com -tls-cert, usa o mesmo certificado do lobby.
+var enderecoAdminHTTP = "127.0.0.1:4003"
+
+// tamanho máximo do corpo de uma requisição
+const tamanhoCorpoAdmin = 16 << 10
+
+type respostaErro struct {
+	Erro string `json:"erro"`
+}
+
+func responderJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// traduz os erros das operações em status HTTP
+func responderErro(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errJogadorNaoEncontrado), errors.Is(err, errPartidaNaoEncontrada), errors.Is(err, errColecaoDesconhecida):
+		status = http.StatusNotFound
+	case errors.Is(err, motor.ErrPartidaEncerrada):
+		status = http.StatusConflict
+	}
+	responderJSON(w, status, respostaErro{Erro: err.Error()})
+}
+
+// lê o corpo JSON (opcional) da requisição
+func lerCorpo(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, tamanhoCorpoAdmin))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return errors.New("corpo JSON inválido: " + err.Error())
+	}
+	return nil
+}
+
+// logger da requisição: método, caminho e endereço de quem pediu
+func loggerRequisicao(r *http.Request) *slog.Logger {
+	return slog.With("admin_http", true, "metodo", r.Method, "caminho", r.URL.Path, "remote_addr", r.RemoteAddr)
+}
+
+// confere o token e registra cada requisição (auditoria)
+func autenticarAdmin(h func(w http.ResponseWriter, r *http.Request, logger *slog.Logger)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := loggerRequisicao(r)
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(tokenAdmin)) != 1 {
+			logger.Warn("falha de autenticação na API de admin")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			responderJSON(w, http.StatusUnauthorized, respostaErro{Erro: "token inválido"})
+			return
+		}
+		logger.Info("requisição na API de admin")
+		h(w, r, logger)
+	}
+}
+
+func rotasAdmin() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/jogadores", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, _ *slog.Logger) {
+		responderJSON(w, http.StatusOK, listarJogadoresAdmin())
+	}))
+	mux.HandleFunc("POST /admin/jogadores/{nome}/expulsar", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+		var corpo struct {
+			Motivo string `json:"motivo"`
+		}
+		if err := lerCorpo(r, &corpo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		if err := expulsarJogador(logger, r.PathValue("nome"), corpo.Motivo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]string{"expulso": r.PathValue("nome")})
+	}))
+	mux.HandleFunc("POST /admin/jogadores/{nome}/banir", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+		var corpo struct {
+			Duracao string `json:"duracao"`
+			Motivo  string `json:"motivo"`
+		}
+		if err := lerCorpo(r, &corpo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		duracao, err := interpretarDuracao(corpo.Duracao)
+		if err != nil {
+			responderErro(w, errDuracaoInvalida)
+			return
+		}
+		ate, err := banirJogador(logger, "api", r.PathValue("nome"), duracao, corpo.Motivo)
+		if err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]any{"banido": r.PathValue("nome"), "ate": ate})
+	}))
+	mux.HandleFunc("GET /admin/partidas", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, _ *slog.Logger) {
+		responderJSON(w, http.StatusOK, listarPartidasAdmin())
+	}))
+	mux.HandleFunc("GET /admin/partidas/{id}", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, _ *slog.Logger) {
+		e, err := estadoPartidaAdmin(r.PathValue("id"))
+		if err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, e)
+	}))
+	mux.HandleFunc("POST /admin/partidas/{id}/encerrar", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+		var corpo struct {
+			Vencedor string `json:"vencedor"`
+		}
+		if err := lerCorpo(r, &corpo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		if err := encerrarPartidaAdmin(logger, r.PathValue("id"), corpo.Vencedor); err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]string{"encerrada": r.PathValue("id"), "vencedor": corpo.Vencedor})
+	}))
+	mux.HandleFunc("GET /admin/fila", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, _ *slog.Logger) {
+		responderJSON(w, http.StatusOK, listarFilaAdmin())
+	}))
+	mux.HandleFunc("POST /admin/boosters/repor", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, _ *slog.Logger) {
+		var corpo struct {
+			Colecao    string `json:"colecao"`
+			Quantidade int    `json:"quantidade"`
+		}
+		if err := lerCorpo(r, &corpo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		if corpo.Quantidade < 0 {
+			responderErro(w, errors.New("quantidade negativa"))
+			return
+		}
+		repostos, err := reporBoostersAdmin("api", corpo.Colecao, corpo.Quantidade)
+		if err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]any{"repostos": repostos})
+	}))
+	mux.HandleFunc("POST /admin/anuncios", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+		var corpo struct {
+			Texto string `json:"texto"`
+		}
+		if err := lerCorpo(r, &corpo); err != nil {
+			responderErro(w, err)
+			return
+		}
+		n, err := anunciar(logger, corpo.Texto)
+		if err != nil {
+			responderErro(w, err)
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]int{"jogadores": n})
+	}))
+	mux.HandleFunc("POST /admin/cartas/recarregar", autenticarAdmin(func(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+		alterados, err := recarregarCartasAdmin(logger)
+		if err != nil {
+			responderJSON(w, http.StatusUnprocessableEntity, respostaErro{Erro: err.Error()})
+			return
+		}
+		responderJSON(w, http.StatusOK, map[string]int{"alterados": alterados})
+	}))
+	return mux
+}
+
+// sobe a API de admin; sem token de admin ela fica desligada
+func servirAdminHTTP(endereco string) {
+	if tokenAdmin == "" {
+		slog.Warn("API de admin desativada: defina -token-admin para usá-la", "endereco", endereco)
+		return
+	}
+	srv := &http.Server{
+		Addr: endereco, Handler: rotasAdmin(),
+		ReadHeaderTimeout: 5 * time.Second, ReadTimeout: 10 * time.Second, WriteTimeout: 30 * time.Second,
+	}
+	ln, err := net.Listen("tcp", endereco)
+	if err != nil {
+		slog.Error("erro ao abrir a API de admin", "endereco", endereco, "erro", err)
+		return
+	}
+	modo := "HTTP"
+	if certificadoTLS != "" {
+		cfg, err := certificado.Servidor(certificadoTLS, chaveTLS, "")
+		if err != nil {
+			ln.Close()
+			slog.Error("erro no certificado da API de admin", "erro", err)
+			return
+		}
+		srv.TLSConfig = cfg
+		modo = "HTTPS"
+	}
+	slog.Info("API de admin disponível", "endereco", endereco, "modo", modo)
+	if srv.TLSConfig != nil {
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	slog.Error("erro na API de admin", "erro", err)
+}
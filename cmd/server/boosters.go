@@ -0,0 +1,336 @@
+// boosters.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// representa um pacote booster de cartas
+type PacoteBooster struct {
+	ID      string
+	Colecao string    // coleção do pacote
+	Cartas  []string  // lista de cartas do pacote
+	Criado  time.Time // momento da reposição que gerou o pacote
+}
+
+// coleção de boosters vendida na loja
+type ColecaoBooster struct {
+	Nome      string
+	Descricao string
+	Preco     int // preço em moedas
+	Raras     int // cartas raras por pacote
+	Incomuns  int // cartas incomuns por pacote
+	Comuns    int // cartas comuns por pacote
+}
+
+// coleção vendida pelo /booster
+const colecaoBasica = "basico"
+
+// coleções disponíveis, na ordem em que aparecem na loja
+var colecoesBooster = []ColecaoBooster{
+	{Nome: colecaoBasica, Descricao: "1 rara, 1 incomum, 1 comum", Preco: 100, Raras: 1, Incomuns: 1, Comuns: 1},
+	{Nome: "avancado", Descricao: "2 raras, 2 incomuns, 1 comum", Preco: 250, Raras: 2, Incomuns: 2, Comuns: 1},
+	{Nome: "lendario", Descricao: "3 raras, 2 incomuns", Preco: 600, Raras: 3, Incomuns: 2},
+}
+
+// retorna a coleção pelo nome (nil se não existir)
+func colecaoBooster(nome string) *ColecaoBooster {
+	for i := range colecoesBooster {
+		if colecoesBooster[i].Nome == nome {
+			return &colecoesBooster[i]
+		}
+	}
+	return nil
+}
+
+// sorteia as cartas de um pacote da coleção (deve ser chamada com boostersMu travado)
+func (c *ColecaoBooster) gerarCartas(rng *rand.Rand) []string {
+	cartas := make([]string, 0, c.Raras+c.Incomuns+c.Comuns)
+	for i := 0; i < c.Raras; i++ {
+		cartas = append(cartas, fmt.Sprintf("C%03d-R", rng.Intn(cartasPorRaridade["R"]))) // carta rara
+	}
+	for i := 0; i < c.Incomuns; i++ {
+		cartas = append(cartas, fmt.Sprintf("C%03d-U", rng.Intn(cartasPorRaridade["U"]))) // carta incomum
+	}
+	for i := 0; i < c.Comuns; i++ {
+		cartas = append(cartas, fmt.Sprintf("C%03d-C", rng.Intn(cartasPorRaridade["C"]))) // carta comum
+	}
+	return cartas
+}
+
+// linha do log de auditoria dos boosters (append-only)
+type RegistroBooster struct {
+	Seq      int64     `json:"seq"`
+	Momento  time.Time `json:"momento"`
+	Evento   string    `json:"evento"`             // reposicao, abertura
+	Colecao  string    `json:"colecao,omitempty"`  // coleção do pacote ou da reposição
+	Pacote   string    `json:"pacote,omitempty"`   // pacote aberto (abertura)
+	Cartas   []string  `json:"cartas,omitempty"`   // cartas do pacote aberto (abertura)
+	Jogador  string    `json:"jogador,omitempty"`  // quem abriu (abertura)
+	Primeiro string    `json:"primeiro,omitempty"` // primeiro pacote criado (reposicao)
+	Ultimo   string    `json:"ultimo,omitempty"`   // último pacote criado (reposicao)
+	Autor    string    `json:"autor,omitempty"`    // quem pediu a reposição ("agenda" para a automática)
+	Estoque  int       `json:"estoque"`            // estoque após o evento
+}
+
+var (
+	// estoque alvo de cada coleção e intervalo da reposição automática (0 desativa)
+	estoqueBoosters   = 50
+	reposicaoBoosters = 10 * time.Minute
+
+	// inventário de boosters
+	boostersMu    sync.Mutex
+	boosters      = map[string][]*PacoteBooster{} // coleção -> pacotes em estoque
+	numeroBooster int                             // último número de pacote gerado (protegido por boostersMu)
+	seqAuditoria  int64                           // último número de sequência da auditoria (protegido por boostersMu)
+	auditoriaMu   sync.Mutex
+
+	// gerador próprio dos boosters (protegido por boostersMu); a semente é registrada no log
+	sementeBoosters int64
+	rngBoosters     *rand.Rand
+)
+
+func caminhoAuditoriaBoosters() string {
+	return filepath.Join(diretorioDados, "boosters_auditoria.jsonl")
+}
+
+// retoma a numeração da auditoria, abastece o estoque e inicia a reposição automática
+func iniciarBoosters() error {
+	if err := retomarAuditoriaBoosters(); err != nil {
+		return err
+	}
+	reporColecoes("inicio")
+	if reposicaoBoosters > 0 {
+		go loopReposicaoBoosters()
+	}
+	return nil
+}
+
+// lê o log de auditoria para que pacotes e sequências nunca se repitam entre execuções
+func retomarAuditoriaBoosters() error {
+	f, err := os.Open(caminhoAuditoriaBoosters())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	boostersMu.Lock()
+	defer boostersMu.Unlock()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r RegistroBooster
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("auditoria de boosters, seq após %d: %w", seqAuditoria, err)
+		}
+		if r.Seq > seqAuditoria {
+			seqAuditoria = r.Seq
+		}
+		if n := numeroDoPacote(r.Ultimo); n > numeroBooster {
+			numeroBooster = n
+		}
+	}
+	return scanner.Err()
+}
+
+// extrai o número de um ID "booster-0001"
+func numeroDoPacote(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "booster-"))
+	return n
+}
+
+// completa o estoque de todas as coleções até o estoque alvo
+func reporColecoes(autor string) {
+	for _, c := range colecoesBooster {
+		reporBoosters(c.Nome, 0, autor)
+	}
+}
+
+// acrescenta n pacotes novos à coleção (sem n, completa até o estoque alvo)
+func reporBoosters(colecao string, n int, autor string) int {
+	c := colecaoBooster(colecao)
+	if c == nil {
+		return 0
+	}
+	boostersMu.Lock()
+	if rngBoosters == nil {
+		if sementeBoosters == 0 {
+			sementeBoosters = rand.Int63()
+		}
+		rngBoosters = rand.New(rand.NewSource(sementeBoosters))
+		slog.Info("gerador de boosters iniciado", "semente", sementeBoosters)
+	}
+	if n <= 0 {
+		n = estoqueBoosters - len(boosters[colecao])
+	}
+	if n <= 0 {
+		boostersMu.Unlock()
+		return 0
+	}
+	agora := time.Now()
+	primeiro := numeroBooster + 1
+	for i := 0; i < n; i++ {
+		numeroBooster++
+		id := fmt.Sprintf("booster-%04d", numeroBooster)
+		pacote := &PacoteBooster{ID: id, Colecao: colecao, Cartas: c.gerarCartas(rngBoosters), Criado: agora}
+		boosters[colecao] = append(boosters[colecao], pacote)
+	}
+	seqAuditoria++
+	r := RegistroBooster{
+		Seq: seqAuditoria, Momento: agora, Evento: "reposicao", Colecao: colecao, Autor: autor, Estoque: len(boosters[colecao]),
+		Primeiro: fmt.Sprintf("booster-%04d", primeiro), Ultimo: fmt.Sprintf("booster-%04d", numeroBooster),
+	}
+	boostersMu.Unlock()
+
+	auditarBooster(r)
+	slog.Info("boosters repostos", "quantidade", n, "colecao", colecao, "autor", autor, "estoque", r.Estoque)
+	return n
+}
+
+// reposição automática: completa o estoque a cada intervalo
+func loopReposicaoBoosters() {
+	for range time.Tick(reposicaoBoosters) {
+		reporColecoes("agenda")
+	}
+}
+
+// retira um pacote da coleção para o jogador; o mesmo pacote nunca é entregue duas vezes
+func pegarBooster(colecao, nome string) (*PacoteBooster, bool) {
+	boostersMu.Lock()
+	estoque := boosters[colecao]
+	if len(estoque) == 0 {
+		boostersMu.Unlock()
+		return nil, false
+	}
+	idx := len(estoque) - 1
+	pacote := estoque[idx]
+	estoque[idx] = nil
+	boosters[colecao] = estoque[:idx] // remove do inventário
+	seqAuditoria++
+	r := RegistroBooster{
+		Seq: seqAuditoria, Momento: time.Now(), Evento: "abertura", Colecao: colecao,
+		Pacote: pacote.ID, Cartas: pacote.Cartas, Jogador: nome, Estoque: idx,
+	}
+	boostersMu.Unlock()
+
+	auditarBooster(r)
+	return pacote, true
+}
+
+// acrescenta o registro ao log de auditoria
+func auditarBooster(r RegistroBooster) {
+	linha, err := json.Marshal(r)
+	if err == nil {
+		err = anexarLinha(caminhoAuditoriaBoosters(), linha, &auditoriaMu)
+	}
+	if err != nil {
+		slog.Error("erro ao gravar auditoria de boosters", "seq", r.Seq, "erro", err)
+	}
+}
+
+// /mod boosters estoque|repor [colecao] [n]|historico <nome>|pacote <id> (apenas admins)
+func tratarBoostersAdmin(j *Jogador, args []string) {
+	if !j.temPapel(papelAdmin) {
+		j.enviarErro(erroSemPermissao, "apenas admins podem gerenciar os boosters")
+		return
+	}
+	if len(args) == 0 || args[0] == "estoque" {
+		proxima := "desativada"
+		if reposicaoBoosters > 0 {
+			proxima = "a cada " + reposicaoBoosters.String()
+		}
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("Boosters em estoque (alvo %d, reposição automática %s):\n", estoqueBoosters, proxima))
+		boostersMu.Lock()
+		for _, c := range colecoesBooster {
+			builder.WriteString(fmt.Sprintf("  %s: %d\n", c.Nome, len(boosters[c.Nome])))
+		}
+		boostersMu.Unlock()
+		j.enviarMensagem(builder.String())
+		return
+	}
+	switch args[0] {
+	case "repor":
+		if len(args) < 2 {
+			reporColecoes(j.Nome)
+			j.enviarMensagem("Estoque de todas as coleções completado")
+			return
+		}
+		if colecaoBooster(args[1]) == nil {
+			j.enviarMensagem("Coleção desconhecida")
+			return
+		}
+		n := 0
+		if len(args) >= 3 {
+			v, err := strconv.Atoi(args[2])
+			if err != nil || v <= 0 {
+				j.enviarMensagem("Uso: /mod boosters repor [colecao] [quantidade]")
+				return
+			}
+			n = v
+		}
+		repostos := reporBoosters(args[1], n, j.Nome)
+		j.enviarMensagem(fmt.Sprintf("%d boosters %s repostos", repostos, args[1]))
+	case "historico", "pacote":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /mod boosters historico <nome> ou /mod boosters pacote <id>")
+			return
+		}
+		consultarAuditoriaBoosters(j, args[0], args[1])
+	default:
+		j.enviarMensagem("Uso: /mod boosters estoque|repor [colecao] [n]|historico <nome>|pacote <id>")
+	}
+}
+
+// procura no log de auditoria os pacotes abertos por um jogador, ou quem abriu um pacote
+func consultarAuditoriaBoosters(j *Jogador, tipo, valor string) {
+	auditoriaMu.Lock()
+	f, err := os.Open(caminhoAuditoriaBoosters())
+	if err != nil {
+		auditoriaMu.Unlock()
+		j.enviarMensagem("Nenhum registro de boosters")
+		return
+	}
+	var encontrados []RegistroBooster
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r RegistroBooster
+		if json.Unmarshal(scanner.Bytes(), &r) != nil || r.Evento != "abertura" {
+			continue
+		}
+		if (tipo == "historico" && r.Jogador == valor) || (tipo == "pacote" && r.Pacote == valor) {
+			encontrados = append(encontrados, r)
+		}
+	}
+	f.Close()
+	auditoriaMu.Unlock()
+
+	if len(encontrados) == 0 {
+		j.enviarMensagem("Nenhum registro encontrado")
+		return
+	}
+	if len(encontrados) > 20 {
+		encontrados = encontrados[len(encontrados)-20:]
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Aberturas (%s %s):\n", tipo, valor))
+	for _, r := range encontrados {
+		builder.WriteString(fmt.Sprintf("  #%d %s %s abriu %s (%s) %v\n", r.Seq, r.Momento.Format("02/01 15:04:05"), r.Jogador, r.Pacote, r.Colecao, r.Cartas))
+	}
+	j.enviarMensagem(builder.String())
+}
@@ -0,0 +1,87 @@
+// boosters_test.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+// centenas de goroutines disputam o estoque; nenhum pacote pode ser entregue duas vezes e toda
+// abertura precisa estar na auditoria (rode com go test -race)
+func TestPegarBoosterConcorrente(t *testing.T) {
+	goroutines, pacotes := 500, 5000
+	if testing.Short() {
+		goroutines, pacotes = 50, 500
+	}
+	diretorioDados = t.TempDir()
+	boostersMu.Lock()
+	boosters = map[string][]*PacoteBooster{}
+	boostersMu.Unlock()
+	reporBoosters(colecaoBasica, pacotes, "teste")
+
+	var mu sync.Mutex
+	entregues := map[string]string{}
+	var wg sync.WaitGroup
+	inicio := make(chan struct{})
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(nome string) {
+			defer wg.Done()
+			<-inicio
+			for {
+				pacote, ok := pegarBooster(colecaoBasica, nome)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				if dono, existe := entregues[pacote.ID]; existe {
+					t.Errorf("pacote %s entregue a %s e a %s", pacote.ID, dono, nome)
+				}
+				entregues[pacote.ID] = nome
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("teste-%d", i))
+	}
+	close(inicio)
+	wg.Wait()
+
+	if len(entregues) != pacotes {
+		t.Fatalf("%d pacotes entregues, esperados %d", len(entregues), pacotes)
+	}
+	aberturas, err := contarAberturasAuditadas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aberturas != pacotes {
+		t.Fatalf("auditoria registra %d aberturas, esperadas %d", aberturas, pacotes)
+	}
+}
+
+// conta as aberturas distintas registradas no log de auditoria
+func contarAberturasAuditadas() (int, error) {
+	f, err := os.Open(caminhoAuditoriaBoosters())
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	vistos := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var r RegistroBooster
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return 0, err
+		}
+		if r.Evento == "abertura" {
+			if vistos[r.Pacote] {
+				return 0, fmt.Errorf("auditoria registra o pacote %s duas vezes", r.Pacote)
+			}
+			vistos[r.Pacote] = true
+		}
+	}
+	return len(vistos), scanner.Err()
+}
@@ -0,0 +1,103 @@
+// cartas.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// catálogo de cartas do jogo (ID -> nome com a raridade). Os IDs e as raridades vêm do motor;
+// os nomes podem vir de <dados>/cartas.json e ser recarregados em execução. O mapa é trocado
+// inteiro, então quem o lê nunca vê uma troca pela metade
+var catalogoCartas atomic.Pointer[map[int]string]
+
+// tamanho máximo do nome de uma carta no arquivo do catálogo
+const tamanhoNomeCarta = 40
+
+func caminhoCatalogo() string {
+	return filepath.Join(diretorioDados, "cartas.json")
+}
+
+// catálogo atual; não deve ser alterado
+func cartasDisponiveis() map[int]string {
+	if c := catalogoCartas.Load(); c != nil {
+		return *c
+	}
+	return nil
+}
+
+func nomeCarta(id int) string {
+	return cartasDisponiveis()[id]
+}
+
+// monta o catálogo com os nomes do arquivo (opcional, {"1": "Dragão Ancestral", ...});
+// cartas fora do arquivo ficam com o nome padrão "Carta <id>"
+func montarCatalogo() (map[int]string, error) {
+	nomes := map[string]string{}
+	dados, err := os.ReadFile(caminhoCatalogo())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(dados, &nomes); err != nil {
+			return nil, fmt.Errorf("%s: %w", caminhoCatalogo(), err)
+		}
+	}
+
+	catalogo := make(map[int]string, motor.TotalCartas)
+	for i := 1; i <= motor.TotalCartas; i++ {
+		catalogo[i] = fmt.Sprintf("Carta %d (%s)", i, motor.Raridade(i))
+	}
+	for chave, nome := range nomes {
+		id, err := strconv.Atoi(chave)
+		if err != nil || id < 1 || id > motor.TotalCartas {
+			return nil, fmt.Errorf("%s: carta %q fora do catálogo (1 a %d)", caminhoCatalogo(), chave, motor.TotalCartas)
+		}
+		nome = strings.TrimSpace(nome)
+		if nome == "" || utf8.RuneCountInString(nome) > tamanhoNomeCarta || strings.IndexFunc(nome, unicode.IsControl) >= 0 {
+			return nil, fmt.Errorf("%s: nome inválido para a carta %d: %q", caminhoCatalogo(), id, nome)
+		}
+		catalogo[id] = fmt.Sprintf("%s (%s)", nome, motor.Raridade(id))
+	}
+	return catalogo, nil
+}
+
+// carrega o catálogo ao iniciar
+func inicializarCartas() error {
+	catalogo, err := montarCatalogo()
+	if err != nil {
+		return err
+	}
+	catalogoCartas.Store(&catalogo)
+	slog.Info("catálogo de cartas inicializado", "cartas", len(catalogo))
+	return nil
+}
+
+// relê o arquivo do catálogo; com erro, o catálogo atual continua valendo. Retorna quantos
+// nomes mudaram
+func recarregarCartas() (int, error) {
+	catalogo, err := montarCatalogo()
+	if err != nil {
+		return 0, err
+	}
+	antigo := catalogoCartas.Swap(&catalogo)
+	alterados := 0
+	for id, nome := range catalogo {
+		if antigo == nil || (*antigo)[id] != nome {
+			alterados++
+		}
+	}
+	return alterados, nil
+}
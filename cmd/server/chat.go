@@ -0,0 +1,372 @@
+// chat.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// canal em que todo jogador entra ao conectar
+const canalGeral = "geral"
+
+// nome reservado: "/canal usar partida" e "canal": "partida" falam no chat da partida
+const canalPartida = "partida"
+
+// canais em que cada jogador pode estar (o geral incluído) e canais existentes no servidor
+const (
+	maxCanaisPorJogador = 10
+	maxCanais           = 500
+)
+
+// quantidade de mensagens guardadas no histórico de cada canal e partida
+const tamanhoHistoricoChat = 50
+
+// nomes de canal aceitos: letras minúsculas, dígitos, _ e -
+var nomeCanalValido = regexp.MustCompile(`^[a-z0-9_-]{1,20}$`)
+
+// mensagem de chat guardada no histórico
+type MensagemChat struct {
+	Momento time.Time
+	Autor   string
+	Texto   string
+}
+
+// canal de chat nomeado
+type Canal struct {
+	Nome      string
+	Membros   map[string]*Jogador // ID jogador -> jogador
+	Historico []MensagemChat
+}
+
+var (
+	canaisMu sync.Mutex
+	canais   = map[string]*Canal{canalGeral: {Nome: canalGeral, Membros: map[string]*Jogador{}}}
+)
+
+// formata uma mensagem de chat com horário e origem
+func (m MensagemChat) formatar(origem string) string {
+	return fmt.Sprintf("[%s] [%s] %s: %s", m.Momento.Format("15:04"), origem, m.Autor, m.Texto)
+}
+
+// acrescenta a mensagem ao histórico, descartando as mais antigas
+func adicionarHistorico(historico []MensagemChat, m MensagemChat) []MensagemChat {
+	historico = append(historico, m)
+	if len(historico) > tamanhoHistoricoChat {
+		historico = historico[len(historico)-tamanhoHistoricoChat:]
+	}
+	return historico
+}
+
+// interpreta os subcomandos de /canal
+func tratarCanal(j *Jogador, args []string) {
+	if len(args) == 0 {
+		j.enviarMensagem("Uso: /canal entrar <nome>, /canal sair <nome>, /canal usar <nome>, /canal lista, /canal historico [nome]")
+		return
+	}
+	switch args[0] {
+	case "entrar", "sair", "usar":
+		if len(args) < 2 {
+			j.enviarMensagem(fmt.Sprintf("Uso: /canal %s <nome>", args[0]))
+			return
+		}
+		switch args[0] {
+		case "entrar":
+			entrarCanal(j, args[1])
+		case "sair":
+			sairCanal(j, args[1])
+		case "usar":
+			usarCanal(j, args[1])
+		}
+	case "lista":
+		listarCanais(j)
+	case "historico":
+		nome := ""
+		if len(args) >= 2 {
+			nome = args[1]
+		}
+		mostrarHistoricoCanal(j, nome)
+	default:
+		j.enviarMensagem("Subcomando de canal desconhecido")
+	}
+}
+
+// inscreve o jogador no canal (criando-o se necessário), torna-o o canal ativo e envia o histórico
+func entrarCanal(j *Jogador, nome string) {
+	nome = strings.ToLower(nome)
+	if !nomeCanalValido.MatchString(nome) {
+		j.enviarMensagem("Nome de canal inválido (use até 20 letras minúsculas, dígitos, _ ou -)")
+		return
+	}
+	if nome == canalPartida {
+		j.enviarMensagem("O nome #partida é reservado para o chat da partida")
+		return
+	}
+	canaisMu.Lock()
+	c := canais[nome]
+	if c == nil || c.Membros[j.ID] == nil {
+		inscricoes := 0
+		for _, outro := range canais {
+			if outro.Membros[j.ID] != nil {
+				inscricoes++
+			}
+		}
+		if inscricoes >= maxCanaisPorJogador {
+			canaisMu.Unlock()
+			j.enviarMensagem(fmt.Sprintf("Você já está em %d canais; saia de um antes de entrar em outro", maxCanaisPorJogador))
+			return
+		}
+	}
+	if c == nil {
+		if len(canais) >= maxCanais {
+			canaisMu.Unlock()
+			j.enviarMensagem("O servidor já tem canais demais; entre em um existente (/canal lista)")
+			return
+		}
+		c = &Canal{Nome: nome, Membros: map[string]*Jogador{}}
+		canais[nome] = c
+	}
+	c.Membros[j.ID] = j
+	historico := append([]MensagemChat(nil), c.Historico...)
+	canaisMu.Unlock()
+
+	j.mu.Lock()
+	j.CanalAtivo = nome
+	j.mu.Unlock()
+
+	j.enviarMensagem(fmt.Sprintf("Você entrou no canal #%s", nome))
+	enviarHistorico(j, "#"+nome, historico)
+}
+
+// remove o jogador do canal; canais vazios (exceto o geral) são descartados
+func sairCanal(j *Jogador, nome string) {
+	nome = strings.ToLower(nome)
+	canaisMu.Lock()
+	c := canais[nome]
+	if c == nil || c.Membros[j.ID] == nil {
+		canaisMu.Unlock()
+		j.enviarMensagem("Você não está nesse canal")
+		return
+	}
+	delete(c.Membros, j.ID)
+	if len(c.Membros) == 0 && nome != canalGeral {
+		delete(canais, nome)
+	}
+	canaisMu.Unlock()
+
+	j.mu.Lock()
+	if j.CanalAtivo == nome {
+		j.CanalAtivo = ""
+	}
+	j.mu.Unlock()
+	j.enviarMensagem(fmt.Sprintf("Você saiu do canal #%s", nome))
+}
+
+// define o canal para onde vão as mensagens de chat sem comando
+func usarCanal(j *Jogador, nome string) {
+	nome = strings.ToLower(nome)
+	canaisMu.Lock()
+	c := canais[nome]
+	membro := c != nil && c.Membros[j.ID] != nil
+	canaisMu.Unlock()
+	if !membro {
+		j.enviarMensagem("Você não está nesse canal (use /canal entrar)")
+		return
+	}
+	j.mu.Lock()
+	j.CanalAtivo = nome
+	j.mu.Unlock()
+	j.enviarMensagem(fmt.Sprintf("Mensagens de chat agora vão para #%s", nome))
+}
+
+// lista os canais existentes, marcando os que o jogador participa
+func listarCanais(j *Jogador) {
+	canaisMu.Lock()
+	nomes := make([]string, 0, len(canais))
+	for nome := range canais {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	var builder strings.Builder
+	builder.WriteString("Canais:\n")
+	for _, nome := range nomes {
+		c := canais[nome]
+		marca := " "
+		if c.Membros[j.ID] != nil {
+			marca = "*"
+		}
+		builder.WriteString(fmt.Sprintf(" %s #%s (%d membros)\n", marca, nome, len(c.Membros)))
+	}
+	canaisMu.Unlock()
+	j.enviarMensagem(builder.String())
+}
+
+// reenvia o histórico de um canal (ou do canal ativo)
+func mostrarHistoricoCanal(j *Jogador, nome string) {
+	if nome == "" {
+		j.mu.Lock()
+		nome = j.CanalAtivo
+		j.mu.Unlock()
+	}
+	nome = strings.ToLower(nome)
+	canaisMu.Lock()
+	c := canais[nome]
+	if c == nil || c.Membros[j.ID] == nil {
+		canaisMu.Unlock()
+		j.enviarMensagem("Você não está nesse canal")
+		return
+	}
+	historico := append([]MensagemChat(nil), c.Historico...)
+	canaisMu.Unlock()
+	enviarHistorico(j, "#"+nome, historico)
+}
+
+// envia o histórico de mensagens em um único bloco
+func enviarHistorico(j *Jogador, origem string, historico []MensagemChat) {
+	if len(historico) == 0 {
+		return
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("--- histórico de %s ---\n", origem))
+	for _, m := range historico {
+		builder.WriteString(m.formatar(origem) + "\n")
+	}
+	builder.WriteString("--- fim do histórico ---")
+	j.enviarMensagem(builder.String())
+}
+
+// envia uma mensagem de chat: no canal indicado, ou conforme o contexto do jogador
+// (partida jogada ou assistida, senão o canal ativo)
+func enviarChat(j *Jogador, canal, texto string) {
+	texto = strings.TrimSpace(texto)
+	if texto == "" || verificarSilencio(j) {
+		return
+	}
+	texto = filtrarTexto(texto)
+	if canal == "" {
+		if p := partidaDoChat(j); p != nil {
+			falarNaPartida(j, p, texto)
+			return
+		}
+		j.mu.Lock()
+		canal = j.CanalAtivo
+		j.mu.Unlock()
+		if canal == "" {
+			j.enviarMensagem("Você não tem um canal ativo (use /canal entrar <nome>)")
+			return
+		}
+	}
+	if canal == canalPartida {
+		p := partidaDoChat(j)
+		if p == nil {
+			j.enviarMensagem("Você não está jogando nem assistindo uma partida")
+			return
+		}
+		falarNaPartida(j, p, texto)
+		return
+	}
+	falarNoCanal(j, strings.ToLower(canal), texto)
+}
+
+// publica a mensagem para os membros do canal
+func falarNoCanal(j *Jogador, nome, texto string) {
+	m := MensagemChat{Momento: time.Now(), Autor: j.Nome, Texto: texto}
+	canaisMu.Lock()
+	c := canais[nome]
+	if c == nil || c.Membros[j.ID] == nil {
+		canaisMu.Unlock()
+		j.enviarMensagem("Você não está nesse canal (use /canal entrar)")
+		return
+	}
+	c.Historico = adicionarHistorico(c.Historico, m)
+	destinos := make([]*Jogador, 0, len(c.Membros))
+	for _, membro := range c.Membros {
+		destinos = append(destinos, membro)
+	}
+	canaisMu.Unlock()
+	registrarChatRecente("#"+nome, j.Nome, "", texto)
+
+	msg := m.formatar("#" + nome)
+	for _, d := range destinos {
+		if !bloqueou(d.Nome, j.Nome) {
+			d.enviarDescartavel(msg)
+		}
+	}
+}
+
+// partida cujo chat o jogador usa: a que ele joga ou a que ele assiste
+func partidaDoChat(j *Jogador) *Partida {
+	if p := encontrarPartidaPorJogador(j.ID); p != nil {
+		return p
+	}
+	j.mu.Lock()
+	idPartida := j.Assistindo
+	j.mu.Unlock()
+	if idPartida == "" {
+		return nil
+	}
+	return partidaPorID(idPartida)
+}
+
+// publica a mensagem no chat da partida, para os dois jogadores e os espectadores
+func falarNaPartida(j *Jogador, p *Partida, texto string) {
+	m := MensagemChat{Momento: time.Now(), Autor: j.Nome, Texto: texto}
+	registrarChatRecente(p.ID, j.Nome, "", texto)
+	ok := p.executar(func(p *Partida) {
+		p.Chat = adicionarHistorico(p.Chat, m)
+
+		// como p.publicar, mas respeitando os bloqueios de cada destinatário
+		msg := m.formatar("partida")
+		aceitar := func(d *Jogador) bool { return !bloqueou(d.Nome, j.Nome) }
+		for _, jog := range []*Jogador{p.A, p.B} {
+			if aceitar(jog) {
+				jog.enviarDescartavel(msg)
+			}
+		}
+		p.transmitirEspectadoresFiltrado(msg, msgDescartavel, aceitar)
+	})
+	if !ok {
+		j.enviarMensagem("A partida já terminou")
+	}
+}
+
+// envia uma mensagem privada para outro jogador conectado
+func sussurrar(j *Jogador, destino, texto string) {
+	texto = strings.TrimSpace(texto)
+	if destino == "" || texto == "" {
+		j.enviarMensagem("Uso: /sussurrar <nome> <mensagem>")
+		return
+	}
+	if verificarSilencio(j) {
+		return
+	}
+	d := jogadorPorNome(destino)
+	if d == nil {
+		j.enviarMensagem(fmt.Sprintf("%s não está conectado", destino))
+		return
+	}
+	if bloqueou(d.Nome, j.Nome) {
+		j.enviarErro(erroBloqueado, fmt.Sprintf("%s não aceita mensagens suas", d.Nome))
+		return
+	}
+	texto = filtrarTexto(texto)
+	registrarChatRecente("sussurro", j.Nome, d.Nome, texto)
+	agora := time.Now().Format("15:04")
+	d.enviarMensagem(fmt.Sprintf("[%s] [sussurro de %s] %s", agora, j.Nome, texto))
+	j.enviarMensagem(fmt.Sprintf("[%s] [sussurro para %s] %s", agora, d.Nome, texto))
+}
+
+// remove o jogador de todos os canais ao desconectar
+func sairDeTodosCanais(j *Jogador) {
+	canaisMu.Lock()
+	defer canaisMu.Unlock()
+	for nome, c := range canais {
+		delete(c.Membros, j.ID)
+		if len(c.Membros) == 0 && nome != canalGeral {
+			delete(canais, nome)
+		}
+	}
+}
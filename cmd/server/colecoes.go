@@ -0,0 +1,165 @@
+// colecoes.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// nomes das raridades pelo sufixo do ID da carta (ex: C034-R)
+var nomesRaridade = map[string]string{"R": "Rara", "U": "Incomum", "C": "Comum"}
+
+// quantidade de cartas distintas de cada raridade (C000-R a C099-R, ...)
+var cartasPorRaridade = map[string]int{"R": 100, "U": 200, "C": 300}
+
+// formato do ID de uma carta colecionável
+var formatoCarta = regexp.MustCompile(`^C(\d{3})-([RUC])$`)
+
+var (
+	colecoesMu       sync.Mutex
+	colecoes         = map[string]map[string]int{} // nome -> carta -> quantidade
+	salvarColecoesMu sync.Mutex                    // serializa as gravações do arquivo
+
+	// colecoes.json é regravado inteiro: boosters, desencantos e criações só marcam o arquivo,
+	// e a gravação sai no máximo uma vez por intervalo (gravacao.go)
+	gravacaoColecoes = novaGravacaoAdiada("colecoes", gravarColecoes)
+)
+
+// raridade da carta pelo sufixo do ID ("R", "U" ou "C"); vazio se o ID for inválido
+func raridadeDe(carta string) string {
+	i := strings.LastIndex(carta, "-")
+	if i < 0 || nomesRaridade[carta[i+1:]] == "" {
+		return ""
+	}
+	return carta[i+1:]
+}
+
+// indica se o ID corresponde a uma carta existente no catálogo
+func cartaValida(carta string) bool {
+	m := formatoCarta.FindStringSubmatch(carta)
+	if m == nil {
+		return false
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n < cartasPorRaridade[m[2]]
+}
+
+// acrescenta as cartas à coleção do jogador
+func adicionarCartas(nome string, cartas []string) {
+	colecoesMu.Lock()
+	if colecoes[nome] == nil {
+		colecoes[nome] = map[string]int{}
+	}
+	for _, c := range cartas {
+		colecoes[nome][c]++
+	}
+	colecoesMu.Unlock()
+	salvarColecoes()
+}
+
+// quantidade de cópias da carta na coleção (deve ser chamada com colecoesMu travado)
+func copiasDe(nome, carta string) int {
+	return colecoes[nome][carta]
+}
+
+// altera a quantidade de cópias da carta, removendo-a quando chega a zero
+// (deve ser chamada com colecoesMu travado)
+func alterarCopias(nome, carta string, delta int) {
+	if colecoes[nome] == nil {
+		colecoes[nome] = map[string]int{}
+	}
+	colecoes[nome][carta] += delta
+	if colecoes[nome][carta] <= 0 {
+		delete(colecoes[nome], carta)
+	}
+}
+
+// /colecao: cartas do jogador agrupadas por raridade
+func mostrarColecao(j *Jogador) {
+	colecoesMu.Lock()
+	cartas := make([]string, 0, len(colecoes[j.Nome]))
+	qtd := map[string]int{}
+	for c, n := range colecoes[j.Nome] {
+		cartas = append(cartas, c)
+		qtd[c] = n
+	}
+	colecoesMu.Unlock()
+	if len(cartas) == 0 {
+		j.enviarMensagem("Sua coleção está vazia (compre boosters na /loja)")
+		return
+	}
+	sort.Strings(cartas)
+
+	var builder strings.Builder
+	total := 0
+	for _, n := range qtd {
+		total += n
+	}
+	builder.WriteString(fmt.Sprintf("Sua coleção (%d cartas, %d distintas):\n", total, len(cartas)))
+	for _, r := range []string{"R", "U", "C"} {
+		var linha []string
+		for _, c := range cartas {
+			if raridadeDe(c) == r {
+				linha = append(linha, fmt.Sprintf("%s x%d", c, qtd[c]))
+			}
+		}
+		if len(linha) > 0 {
+			builder.WriteString(fmt.Sprintf("  %s: %s\n", nomesRaridade[r], strings.Join(linha, ", ")))
+		}
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// agenda a gravação das coleções
+func salvarColecoes() {
+	gravacaoColecoes.marcar()
+}
+
+// grava as coleções de todos os jogadores
+func gravarColecoes() {
+	salvarColecoesMu.Lock()
+	defer salvarColecoesMu.Unlock()
+
+	colecoesMu.Lock()
+	dados, err := json.Marshal(colecoes)
+	colecoesMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "colecoes.json"), dados)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar coleções", "erro", err)
+	}
+}
+
+// grava as coleções sem soltar as travas, para quem precisa da mudança no disco antes de
+// liberá-las (deve ser chamada com salvarColecoesMu e colecoesMu travados)
+func gravarColecoesTravado() error {
+	dados, err := json.Marshal(colecoes)
+	if err != nil {
+		return err
+	}
+	return gravarArquivo(filepath.Join(diretorioDados, "colecoes.json"), dados)
+}
+
+// carrega as coleções gravadas
+func carregarColecoes() error {
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "colecoes.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	colecoesMu.Lock()
+	defer colecoesMu.Unlock()
+	return json.Unmarshal(dados, &colecoes)
+}
@@ -0,0 +1,222 @@
+// conexao.go
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/comando"
+)
+
+// O servidor envia "HEARTBEAT <n>" a cada intervaloHeartbeat e o cliente responde com
+// "/heartbeat <n>". Qualquer linha recebida renova o prazo de leitura: sem nada do cliente
+// por prazoHeartbeat, a conexão é considerada morta. Escritas que demoram mais que
+// prazoEscrita derrubam a conexão. Quem cai durante uma partida tem prazoReconexao para
+// retomar a sessão enviando "retomar <token>" no lugar do nome; depois disso perde por W.O.
+var (
+	intervaloHeartbeat = 15 * time.Second
+	prazoHeartbeat     = 45 * time.Second
+	prazoEscrita       = 10 * time.Second
+	prazoReconexao     = 30 * time.Second
+)
+
+// tamanho máximo de uma linha recebida do cliente; linhas maiores são descartadas
+var tamanhoLinha = comando.TamanhoMaximo
+
+// código de erro enviado quando o token de retomada não vale mais
+const erroSessaoInvalida = "sessao_invalida"
+
+// código de erro enviado quando a linha passa de tamanhoLinha
+const erroLinhaLonga = "linha_longa"
+
+var (
+	tokensSessao = novoMapaFragmentado[*Jogador]() // token de sessão -> jogador
+	idsPing      = novoMapaFragmentado[*Jogador]() // ID de ping UDP -> jogador
+)
+
+// gera o token de sessão e o ID de ping do jogador e os associa a ele. São valores
+// independentes: o ID de ping viaja em claro nos datagramas UDP, e quem o observa não pode
+// usá-lo para retomar a sessão
+func registrarTokenSessao(j *Jogador) {
+	token, idPing := make([]byte, 16), make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		j.logger().Error("erro ao gerar token de sessão", "erro", err)
+		return
+	}
+	if _, err := rand.Read(idPing); err != nil {
+		j.logger().Error("erro ao gerar ID de ping", "erro", err)
+		return
+	}
+	j.TokenSessao = hex.EncodeToString(token)
+	j.IDPing = hex.EncodeToString(idPing)
+	tokensSessao.definir(j.TokenSessao, j)
+	idsPing.definir(j.IDPing, j)
+}
+
+// descarta o token de sessão e o ID de ping do jogador
+func removerTokenSessao(j *Jogador) {
+	tokensSessao.remover(j.TokenSessao)
+	idsPing.remover(j.IDPing)
+}
+
+func jogadorPorIDPing(id string) *Jogador {
+	j, _ := idsPing.obter(id)
+	return j
+}
+
+func jogadorPorToken(token string) *Jogador {
+	j, _ := tokensSessao.obter(token)
+	return j
+}
+
+// lê comandos até a conexão cair ou ficar sem resposta
+func lerComandos(j *Jogador, conn net.Conn, reader *bufio.Reader) {
+	lim := novosLimites(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(prazoHeartbeat))
+		linha, err := comando.LerLinha(reader, tamanhoLinha)
+		if errors.Is(err, comando.ErrLinhaLonga) {
+			j.enviarErro(erroLinhaLonga, fmt.Sprintf("linha descartada (máximo de %d bytes)", tamanhoLinha))
+			lim.violacao(j, "linha longa")
+			continue
+		}
+		if err != nil {
+			perderConexao(j, conn, err)
+			return
+		}
+		tratarLinha(j, lim, strings.TrimSpace(linha))
+	}
+}
+
+// trata a queda da conexão: durante uma partida o jogador fica suspenso aguardando a
+// retomada; fora dela (ou banido) é removido imediatamente
+func perderConexao(j *Jogador, conn net.Conn, err error) {
+	p := encontrarPartidaPorJogador(j.ID)
+	suspender := p != nil && prazoReconexao > 0 && banidoAte(j.Nome).IsZero() && !ipBanido(conn)
+
+	j.mu.Lock()
+	if j.Conexao != conn {
+		j.mu.Unlock()
+		return // a sessão já foi retomada em outra conexão
+	}
+	// a partida pode ter terminado entre a busca acima e a trava: confere de novo, senão o
+	// jogador ficaria suspenso sem partida até o fim do prazo
+	suspender = suspender && !j.expulso && j.EmPartida && encontrarPartidaPorJogador(j.ID) == p
+	if suspender {
+		j.suspenso = true
+		j.fecharSaida()
+		j.expiracaoReconexao = time.AfterFunc(prazoReconexao, func() { expirarReconexao(j) })
+	} else {
+		j.removido = true // impede uma retomada enquanto o jogador é removido
+	}
+	j.mu.Unlock()
+
+	motivo := err.Error()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		motivo = "sem resposta ao heartbeat"
+	}
+	logger := j.logger()
+	if p != nil {
+		logger = p.logger(j)
+	}
+	logger.Info("jogador desconectou", "motivo", motivo, "suspenso", suspender)
+	if !suspender {
+		removerJogador(j)
+		return
+	}
+
+	aviso := fmt.Sprintf("%s perdeu a conexão; aguardando reconexão por %s", j.Nome, prazoReconexao)
+	p.executar(func(p *Partida) { p.publicar(aviso) })
+}
+
+// fim do prazo sem retomada: o jogador é removido e perde as partidas em andamento
+func expirarReconexao(j *Jogador) {
+	j.mu.Lock()
+	if !j.suspenso {
+		j.mu.Unlock()
+		return
+	}
+	j.suspenso = false
+	j.removido = true
+	j.mu.Unlock()
+	j.logger().Info("jogador não reconectou a tempo")
+	removerJogador(j)
+}
+
+// "retomar <token>": associa a nova conexão à sessão suspensa (ou ainda ativa numa conexão
+// meio aberta) e segue lendo comandos
+func retomarSessao(conn net.Conn, reader *bufio.Reader, token string) {
+	j := jogadorPorToken(token)
+	if j == nil {
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: sessão não encontrada ou expirada\n", erroSessaoInvalida)))
+		return
+	}
+	if ate := banidoAte(j.Nome); !ate.IsZero() {
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: você está banido até %s\n", erroBanido, ate.Format("02/01 15:04"))))
+		return
+	}
+
+	j.mu.Lock()
+	if j.removido {
+		j.mu.Unlock()
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: sessão não encontrada ou expirada\n", erroSessaoInvalida)))
+		return
+	}
+	if j.suspenso {
+		j.suspenso = false
+		j.expiracaoReconexao.Stop()
+	}
+	antiga := j.Conexao
+	j.fecharSaida()
+	j.Conexao = conn
+	remoto := conn.RemoteAddr().String()
+	j.remoto.Store(&remoto)
+	j.Saida = novaFilaSaida()
+	j.desconectado = false
+	saida := j.Saida
+	j.mu.Unlock()
+	if antiga != nil {
+		antiga.Close() // o leitor antigo percebe a troca e não remove o jogador
+	}
+
+	go escritorJogador(j, conn, saida)
+	j.logger().Info("jogador retomou a sessão")
+	j.enviarMensagem(fmt.Sprintf("Sessão retomada, bem-vindo de volta %s", j.Nome))
+	if p := encontrarPartidaPorJogador(j.ID); p != nil {
+		aviso := fmt.Sprintf("%s reconectou", j.Nome)
+		ok := p.executar(func(p *Partida) {
+			outro := p.A
+			if outro == j {
+				outro = p.B
+			}
+			outro.enviarMensagem(aviso)
+			p.transmitirEspectadores(aviso)
+			vez := "do oponente"
+			if p.Turno == j.ID {
+				vez = "sua"
+			}
+			j.enviarMensagem(fmt.Sprintf("Partida %s | sua vida: %d | vida do oponente: %d | vez: %s", p.ID, p.Vida[j.ID], p.Vida[outro.ID], vez))
+		})
+		if ok {
+			mostrarMao(j)
+		}
+	}
+	notificarPresenca(j.Nome)
+
+	lerComandos(j, conn, reader)
+}
+
+// fecha a fila de saída, encerrando o escritor (deve ser chamada com j.mu travado)
+func (j *Jogador) fecharSaida() {
+	if !j.desconectado {
+		j.desconectado = true
+		j.Saida.fechar()
+	}
+}
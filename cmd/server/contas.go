@@ -0,0 +1,247 @@
+// contas.go
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Contas: saldos, coleções, trocas e perfis são identificados pelo nome do jogador, então o
+// nome precisa de dono. Ao conectar, o cliente envia o nome e, na linha seguinte, a senha; na
+// primeira entrada de um nome a senha enviada passa a ser a dele. O servidor guarda só o sal e
+// a derivação da senha (PBKDF2 com HMAC-SHA256), uma conta por linha em contas.jsonl.
+//
+// Cada entrada gasta uma ficha do IP (limites.go). Senhas erradas bloqueiam o nome apenas para
+// o IP que errou, e cada IP cria no máximo maxContasPorIP contas por janelaContasIP.
+
+// códigos de erro da entrada
+const (
+	erroSenhaIncorreta = "senha_incorreta"
+	erroSenhaBloqueada = "senha_bloqueada"
+	erroLimiteContas   = "limite_contas"
+)
+
+var (
+	errSenhaVazia     = errors.New("a senha não pode ser vazia")
+	errSenhaIncorreta = errors.New("senha incorreta para este nome")
+	errSenhaBloqueada = errors.New("muitas tentativas erradas para este nome, aguarde")
+	errLimiteContas   = errors.New("contas demais criadas deste endereço, tente mais tarde")
+)
+
+var (
+	iteracoesSenha     = 20000            // iterações da derivação da senha
+	maxFalhasSenha     = 5                // senhas erradas seguidas antes de bloquear o nome
+	bloqueioFalhaSenha = time.Minute      // tempo que o nome fica bloqueado após as falhas
+	janelaFalhasSenha  = 10 * time.Minute // falhas mais antigas que isso são esquecidas
+	maxContasPorIP     = 5                // contas novas de um IP por janela
+	janelaContasIP     = time.Hour
+)
+
+// bytes de sal por conta
+const tamanhoSal = 16
+
+var (
+	contasMu        sync.Mutex
+	contas          = map[string]Conta{}           // nome -> conta
+	falhasSenha     = map[chaveFalha]*falhaSenha{} // (IP, nome) -> senhas erradas recentes
+	contasPorIP     = map[string]*contasIP{}       // IP -> contas criadas na janela
+	limpezaContas   time.Time
+	arquivoContasMu sync.Mutex // serializa a escrita de contas.jsonl
+)
+
+// conta persistida; a última linha de um nome vale
+type Conta struct {
+	Nome   string    `json:"nome"`
+	Sal    string    `json:"sal"`   // hex
+	Senha  string    `json:"senha"` // derivação da senha, em hex
+	Criada time.Time `json:"criada"`
+}
+
+// o bloqueio por senhas erradas vale para o nome a partir de um IP: quem erra de propósito
+// não tranca o dono do nome
+type chaveFalha struct {
+	ip, nome string
+}
+
+type falhaSenha struct {
+	seguidas int
+	ultima   time.Time // última senha errada
+	ate      time.Time // fim do bloqueio
+}
+
+type contasIP struct {
+	criadas int
+	desde   time.Time // início da janela
+}
+
+// informa se o nome já tem conta
+func contaExiste(nome string) bool {
+	contasMu.Lock()
+	defer contasMu.Unlock()
+	_, existe := contas[nome]
+	return existe
+}
+
+func caminhoContas() string {
+	return filepath.Join(diretorioDados, "contas.jsonl")
+}
+
+// confere a senha do nome vinda do IP; se o nome ainda não tem conta, cria-a com essa senha e
+// devolve criada = true
+func autenticarConta(nome, senha, ip string) (criada bool, err error) {
+	if senha == "" {
+		return false, errSenhaVazia
+	}
+	chave := chaveFalha{ip: ip, nome: nome}
+	contasMu.Lock()
+	limparFalhas(time.Now())
+	if f := falhasSenha[chave]; f != nil && time.Now().Before(f.ate) {
+		contasMu.Unlock()
+		return false, errSenhaBloqueada
+	}
+	c, existe := contas[nome]
+	contasMu.Unlock()
+
+	if !existe {
+		return criarConta(nome, senha, ip)
+	}
+	sal, _ := hex.DecodeString(c.Sal)
+	esperada, _ := hex.DecodeString(c.Senha)
+	ok := subtle.ConstantTimeCompare(derivarSenha(senha, sal), esperada) == 1
+
+	contasMu.Lock()
+	defer contasMu.Unlock()
+	if ok {
+		delete(falhasSenha, chave)
+		return false, nil
+	}
+	agora := time.Now()
+	f := falhasSenha[chave]
+	if f == nil || agora.Sub(f.ultima) > janelaFalhasSenha {
+		f = &falhaSenha{}
+		falhasSenha[chave] = f
+	}
+	f.seguidas++
+	f.ultima = agora
+	if f.seguidas >= maxFalhasSenha {
+		f.seguidas = 0
+		f.ate = agora.Add(bloqueioFalhaSenha)
+		slog.Warn("nome bloqueado após senhas erradas", "jogador", nome, "ip", ip, "ate", f.ate)
+	}
+	return false, errSenhaIncorreta
+}
+
+// esquece de tempos em tempos as falhas antigas e as janelas de criação vencidas
+// (deve ser chamada com contasMu travado)
+func limparFalhas(agora time.Time) {
+	if agora.Sub(limpezaContas) < janelaFalhasSenha {
+		return
+	}
+	limpezaContas = agora
+	for chave, f := range falhasSenha {
+		if !agora.Before(f.ate) && agora.Sub(f.ultima) > janelaFalhasSenha {
+			delete(falhasSenha, chave)
+		}
+	}
+	for ip, c := range contasPorIP {
+		if agora.Sub(c.desde) > janelaContasIP {
+			delete(contasPorIP, ip)
+		}
+	}
+}
+
+// grava a conta nova antes de aceitá-la; se outra conexão criou a conta do mesmo nome
+// enquanto a senha era derivada, confere a senha contra a conta dela
+func criarConta(nome, senha, ip string) (bool, error) {
+	contasMu.Lock()
+	if c := contasPorIP[ip]; limitesAtivos && limiteIPFator > 0 && c != nil && time.Since(c.desde) <= janelaContasIP && c.criadas >= maxContasPorIP {
+		contasMu.Unlock()
+		return false, errLimiteContas
+	}
+	contasMu.Unlock()
+
+	sal := make([]byte, tamanhoSal)
+	if _, err := rand.Read(sal); err != nil {
+		return false, err
+	}
+	c := Conta{Nome: nome, Sal: hex.EncodeToString(sal), Senha: hex.EncodeToString(derivarSenha(senha, sal)), Criada: time.Now()}
+	linha, err := json.Marshal(c)
+	if err != nil {
+		return false, err
+	}
+
+	contasMu.Lock()
+	if _, existe := contas[nome]; existe {
+		contasMu.Unlock()
+		return autenticarConta(nome, senha, ip)
+	}
+	defer contasMu.Unlock()
+	if err := anexarLinha(caminhoContas(), linha, &arquivoContasMu); err != nil {
+		slog.Error("erro ao gravar conta", "jogador", nome, "erro", err)
+		return false, err
+	}
+	contas[nome] = c
+	n := contasPorIP[ip]
+	if n == nil || time.Since(n.desde) > janelaContasIP {
+		n = &contasIP{desde: time.Now()}
+		contasPorIP[ip] = n
+	}
+	n.criadas++
+	slog.Info("conta criada", "jogador", nome, "ip", ip)
+	return true, nil
+}
+
+// PBKDF2-HMAC-SHA256 com um único bloco de saída (32 bytes)
+func derivarSenha(senha string, sal []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(senha))
+	mac.Write(sal)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+	resultado := append([]byte(nil), u...)
+	for i := 1; i < iteracoesSenha; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for k := range resultado {
+			resultado[k] ^= u[k]
+		}
+	}
+	return resultado
+}
+
+// carrega as contas gravadas
+func carregarContas() error {
+	f, err := os.Open(caminhoContas())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	contasMu.Lock()
+	defer contasMu.Unlock()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		var c Conta
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return fmt.Errorf("conta na linha %d: %w", n, err)
+		}
+		contas[c.Nome] = c
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,121 @@
+// criacao.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pó recebido ao desencantar e gasto ao criar uma cópia de cada raridade
+type TaxaPo struct {
+	Desencantar int
+	Criar       int
+}
+
+var taxasPo = map[string]TaxaPo{
+	"R": {Desencantar: 100, Criar: 400},
+	"U": {Desencantar: 20, Criar: 80},
+	"C": {Desencantar: 5, Criar: 20},
+}
+
+// tipos de transação de pó
+const (
+	tipoDesencantar = "desencantar"
+	tipoCriar       = "criar"
+)
+
+// tabela de taxas, enviada quando o comando vem sem argumentos
+func mostrarTaxasPo(j *Jogador, uso string) {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Uso: %s\nTaxas de pó por cópia:\n", uso))
+	for _, r := range []string{"R", "U", "C"} {
+		builder.WriteString(fmt.Sprintf("  %-8s desencantar +%d, criar -%d\n", nomesRaridade[r], taxasPo[r].Desencantar, taxasPo[r].Criar))
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// /desencantar <id> [qtd]: transforma cópias extras em pó (a última cópia é mantida)
+func desencantarCarta(j *Jogador, args []string) {
+	const uso = "/desencantar <idCarta> [quantidade]"
+	if len(args) == 0 || len(args) > 2 {
+		mostrarTaxasPo(j, uso)
+		return
+	}
+	carta := strings.ToUpper(args[0])
+	if !cartaValida(carta) {
+		j.enviarMensagem("Carta inválida: " + args[0])
+		return
+	}
+	qtd := 1
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			j.enviarMensagem("Quantidade inválida")
+			return
+		}
+		qtd = n
+	}
+	po := qtd * taxasPo[raridadeDe(carta)].Desencantar
+
+	colecoesMu.Lock()
+	economiaMu.Lock()
+	if extras := copiasDe(j.Nome, carta) - 1; extras < qtd {
+		economiaMu.Unlock()
+		colecoesMu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("Você tem apenas %d cópia(s) extra(s) de %s", max(extras, 0), carta))
+		return
+	}
+	t, err := aplicarTransacao(Transacao{Jogador: j.Nome, Tipo: tipoDesencantar, Po: po, Carta: carta, Quantidade: qtd})
+	if err == nil {
+		alterarCopias(j.Nome, carta, -qtd)
+	}
+	economiaMu.Unlock()
+	colecoesMu.Unlock()
+	if err != nil {
+		j.enviarMensagem("Não foi possível desencantar agora, tente novamente")
+		return
+	}
+
+	salvarSaldos()
+	salvarColecoes()
+	j.enviarMensagem(fmt.Sprintf("%d cópia(s) de %s desencantada(s): +%d de pó (total %d)", qtd, carta, po, t.SaldoPo))
+}
+
+// /criar <id>: gasta pó para criar uma cópia da carta
+func criarCarta(j *Jogador, args []string) {
+	const uso = "/criar <idCarta>"
+	if len(args) != 1 {
+		mostrarTaxasPo(j, uso)
+		return
+	}
+	carta := strings.ToUpper(args[0])
+	if !cartaValida(carta) {
+		j.enviarMensagem("Carta inválida: " + args[0])
+		return
+	}
+	custo := taxasPo[raridadeDe(carta)].Criar
+
+	colecoesMu.Lock()
+	economiaMu.Lock()
+	disponivel := saldosPo[j.Nome]
+	t, err := aplicarTransacao(Transacao{Jogador: j.Nome, Tipo: tipoCriar, Po: -custo, Carta: carta, Quantidade: 1})
+	if err == nil {
+		alterarCopias(j.Nome, carta, 1)
+	}
+	economiaMu.Unlock()
+	colecoesMu.Unlock()
+	if errors.Is(err, errSaldoInsuficiente) {
+		j.enviarErro(erroSaldoInsuficiente, fmt.Sprintf("criar %s custa %d de pó e você tem %d", carta, custo, disponivel))
+		return
+	}
+	if err != nil {
+		j.enviarMensagem("Não foi possível criar a carta agora, tente novamente")
+		return
+	}
+
+	salvarSaldos()
+	salvarColecoes()
+	j.enviarMensagem(fmt.Sprintf("%s criada: -%d de pó (restam %d)", carta, custo, t.SaldoPo))
+}
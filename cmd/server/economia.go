@@ -0,0 +1,521 @@
+// economia.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recompensas em moedas
+const (
+	recompensaVitoria      = 50
+	recompensaParticipacao = 10
+	recompensaLoginDiario  = 100
+)
+
+// tipos de transação
+const (
+	tipoVitoria      = "vitoria"
+	tipoParticipacao = "participacao"
+	tipoLoginDiario  = "login_diario"
+	tipoMissao       = "missao"
+	tipoCompra       = "compra"
+	tipoEstorno      = "estorno"
+	tipoAjuste       = "ajuste"
+)
+
+// código de erro enviado quando o saldo não cobre o débito
+const erroSaldoInsuficiente = "saldo_insuficiente"
+
+// quantidade de transações recentes mantidas em memória para o /extrato
+const tamanhoExtrato = 20
+
+var errSaldoInsuficiente = errors.New("saldo insuficiente")
+
+// crédito (valor positivo) ou débito (valor negativo) no saldo de um jogador
+type Transacao struct {
+	Seq        int64     `json:"seq"`
+	Momento    time.Time `json:"momento"`
+	Jogador    string    `json:"jogador"`
+	Tipo       string    `json:"tipo"`
+	Valor      int       `json:"valor"`
+	Saldo      int       `json:"saldo"`                // saldo após a transação
+	Referencia string    `json:"referencia,omitempty"` // partida, coleção, missão...
+	Autor      string    `json:"autor,omitempty"`      // admin que fez um ajuste
+
+	// pó de cartas, obtido ao desencantar e gasto ao criar cartas
+	Po         int    `json:"po,omitempty"`         // variação de pó
+	SaldoPo    int    `json:"saldo_po,omitempty"`   // pó após a transação
+	Carta      string `json:"carta,omitempty"`      // carta desencantada ou criada
+	Quantidade int    `json:"quantidade,omitempty"` // cópias desencantadas ou criadas
+}
+
+var (
+	economiaMu        sync.Mutex
+	saldos            = map[string]int{}         // nome -> saldo
+	saldosPo          = map[string]int{}         // nome -> pó de cartas
+	extratos          = map[string][]Transacao{} // nome -> transações recentes
+	ultimoLoginDiario = map[string]string{}      // nome -> dia da última recompensa de login
+	seqTransacao      int64                      // última transação gravada (protegido por economiaMu)
+	transacoesMu      sync.Mutex                 // serializa a escrita do arquivo de transações
+	salvarSaldosMu    sync.Mutex                 // serializa as gravações do arquivo de saldos
+
+	// os snapshots só servem à conciliação (os saldos são reconstruídos pelas transações ao
+	// iniciar), então podem ser gravados com atraso: um login por jogador regravava o arquivo inteiro
+	gravacaoSaldos = novaGravacaoAdiada("saldos", gravarSaldos)
+)
+
+func caminhoTransacoes() string {
+	return filepath.Join(diretorioDados, "transacoes.jsonl")
+}
+
+// reconstrói os saldos a partir do arquivo de transações e confere com o último snapshot
+func carregarEconomia() error {
+	l, err := lerTransacoes()
+	if err != nil {
+		return err
+	}
+	snapshot, snapshotPo, err := lerSnapshotSaldos()
+	if err != nil {
+		return err
+	}
+	divergencias := l.comparar("snapshot", snapshot, snapshotPo)
+	for _, d := range divergencias {
+		slog.Warn("divergência na conciliação de moedas", "divergencia", d)
+	}
+
+	economiaMu.Lock()
+	saldos, saldosPo, extratos, ultimoLoginDiario, seqTransacao = l.saldos, l.saldosPo, l.extratos, l.logins, l.seq
+	economiaMu.Unlock()
+	if len(divergencias) > 0 {
+		// os saldos passam a ser os calculados pelas transações
+		salvarSaldos()
+	}
+	slog.Info("economia carregada", "transacoes", l.seq, "jogadores", len(l.saldos))
+	return nil
+}
+
+// resultado da leitura do arquivo de transações
+type leituraTransacoes struct {
+	saldos       map[string]int
+	saldosPo     map[string]int
+	extratos     map[string][]Transacao
+	logins       map[string]string
+	seq          int64
+	divergencias []string
+}
+
+// relê o arquivo de transações, conferindo a sequência e os saldos registrados em cada linha
+func lerTransacoes() (leituraTransacoes, error) {
+	l := leituraTransacoes{saldos: map[string]int{}, saldosPo: map[string]int{}, extratos: map[string][]Transacao{}, logins: map[string]string{}}
+	transacoesMu.Lock()
+	defer transacoesMu.Unlock()
+	f, err := os.Open(caminhoTransacoes())
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var t Transacao
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return l, fmt.Errorf("transação após a seq %d: %w", l.seq, err)
+		}
+		if t.Seq != l.seq+1 {
+			l.divergencias = append(l.divergencias, fmt.Sprintf("seq %d encontrada após a seq %d", t.Seq, l.seq))
+		}
+		l.seq = t.Seq
+		l.saldos[t.Jogador] += t.Valor
+		if l.saldos[t.Jogador] != t.Saldo {
+			l.divergencias = append(l.divergencias, fmt.Sprintf("seq %d: %s com saldo registrado %d, calculado %d", t.Seq, t.Jogador, t.Saldo, l.saldos[t.Jogador]))
+		}
+		if l.saldos[t.Jogador] < 0 {
+			l.divergencias = append(l.divergencias, fmt.Sprintf("seq %d: saldo negativo de %s (%d)", t.Seq, t.Jogador, l.saldos[t.Jogador]))
+		}
+		l.saldosPo[t.Jogador] += t.Po
+		if l.saldosPo[t.Jogador] != t.SaldoPo {
+			l.divergencias = append(l.divergencias, fmt.Sprintf("seq %d: %s com pó registrado %d, calculado %d", t.Seq, t.Jogador, t.SaldoPo, l.saldosPo[t.Jogador]))
+		}
+		if l.saldosPo[t.Jogador] < 0 {
+			l.divergencias = append(l.divergencias, fmt.Sprintf("seq %d: pó negativo de %s (%d)", t.Seq, t.Jogador, l.saldosPo[t.Jogador]))
+		}
+		if t.Tipo == tipoLoginDiario {
+			l.logins[t.Jogador] = t.Referencia
+		}
+		l.extratos[t.Jogador] = adicionarExtrato(l.extratos[t.Jogador], t)
+	}
+	return l, scanner.Err()
+}
+
+// lê os snapshots de moedas e de pó gravados após cada transação
+func lerSnapshotSaldos() (moedas, po map[string]int, err error) {
+	ler := func(arquivo string) (map[string]int, error) {
+		snapshot := map[string]int{}
+		dados, err := os.ReadFile(filepath.Join(diretorioDados, arquivo))
+		if errors.Is(err, os.ErrNotExist) {
+			return snapshot, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return snapshot, json.Unmarshal(dados, &snapshot)
+	}
+	if moedas, err = ler("saldos.json"); err != nil {
+		return nil, nil, err
+	}
+	po, err = ler("po.json")
+	return moedas, po, err
+}
+
+// divergências da leitura somadas às diferenças entre os saldos informados e os calculados
+func (l leituraTransacoes) comparar(origem string, moedas, po map[string]int) []string {
+	divergencias := append([]string(nil), l.divergencias...)
+	divergencias = append(divergencias, compararSaldos(origem, moedas, l.saldos)...)
+	return append(divergencias, compararSaldos(origem+" (pó)", po, l.saldosPo)...)
+}
+
+// lista os jogadores cujo saldo difere do calculado pelas transações
+func compararSaldos(origem string, esperado, calculados map[string]int) []string {
+	nomes := map[string]bool{}
+	for nome := range esperado {
+		nomes[nome] = true
+	}
+	for nome := range calculados {
+		nomes[nome] = true
+	}
+	var divergencias []string
+	for nome := range nomes {
+		if esperado[nome] != calculados[nome] {
+			divergencias = append(divergencias, fmt.Sprintf("%s: %s %d, transações %d", nome, origem, esperado[nome], calculados[nome]))
+		}
+	}
+	sort.Strings(divergencias)
+	return divergencias
+}
+
+// acrescenta a transação ao extrato, descartando as mais antigas
+func adicionarExtrato(extrato []Transacao, t Transacao) []Transacao {
+	extrato = append(extrato, t)
+	if len(extrato) > tamanhoExtrato {
+		extrato = extrato[len(extrato)-tamanhoExtrato:]
+	}
+	return extrato
+}
+
+// credita (valor positivo) ou debita (valor negativo) o saldo do jogador;
+// débitos que deixariam o saldo negativo são recusados com errSaldoInsuficiente
+func movimentar(nome, tipo string, valor int, referencia, autor string) (Transacao, error) {
+	economiaMu.Lock()
+	t, err := movimentarTravado(nome, tipo, valor, referencia, autor)
+	economiaMu.Unlock()
+	if err == nil {
+		salvarSaldos()
+	}
+	return t, err
+}
+
+// movimenta apenas moedas (deve ser chamada com economiaMu travado)
+func movimentarTravado(nome, tipo string, valor int, referencia, autor string) (Transacao, error) {
+	return aplicarTransacao(Transacao{Jogador: nome, Tipo: tipo, Valor: valor, Referencia: referencia, Autor: autor})
+}
+
+// completa sequência e saldos da transação, grava-a e só então aplica os novos saldos;
+// recusa com errSaldoInsuficiente se moedas ou pó ficariam negativos
+// (deve ser chamada com economiaMu travado)
+func aplicarTransacao(t Transacao) (Transacao, error) {
+	t.Saldo = saldos[t.Jogador] + t.Valor
+	t.SaldoPo = saldosPo[t.Jogador] + t.Po
+	if t.Saldo < 0 || t.SaldoPo < 0 {
+		return Transacao{}, errSaldoInsuficiente
+	}
+	t.Seq = seqTransacao + 1
+	t.Momento = time.Now()
+	linha, err := json.Marshal(t)
+	if err == nil {
+		err = anexarLinha(caminhoTransacoes(), linha, &transacoesMu)
+	}
+	if err != nil {
+		slog.Error("erro ao gravar transação", "jogador", t.Jogador, "tipo", t.Tipo, "valor", t.Valor, "po", t.Po, "erro", err)
+		return Transacao{}, err
+	}
+	seqTransacao = t.Seq
+	saldos[t.Jogador] = t.Saldo
+	saldosPo[t.Jogador] = t.SaldoPo
+	extratos[t.Jogador] = adicionarExtrato(extratos[t.Jogador], t)
+	return t, nil
+}
+
+// agenda a gravação dos snapshots de moedas e de pó
+func salvarSaldos() {
+	gravacaoSaldos.marcar()
+}
+
+// grava os snapshots de moedas e de pó, usados na conciliação
+func gravarSaldos() {
+	salvarSaldosMu.Lock()
+	defer salvarSaldosMu.Unlock()
+
+	economiaMu.Lock()
+	dados, err := json.Marshal(saldos)
+	var dadosPo []byte
+	if err == nil {
+		dadosPo, err = json.Marshal(saldosPo)
+	}
+	economiaMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "saldos.json"), dados)
+	}
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "po.json"), dadosPo)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar saldos", "erro", err)
+	}
+}
+
+// saldo atual do jogador
+func saldoDe(nome string) int {
+	economiaMu.Lock()
+	defer economiaMu.Unlock()
+	return saldos[nome]
+}
+
+// credita a recompensa de login uma vez por dia
+func recompensarLoginDiario(j *Jogador) {
+	hoje := time.Now().Format("2006-01-02")
+	economiaMu.Lock()
+	if ultimoLoginDiario[j.Nome] == hoje {
+		economiaMu.Unlock()
+		return
+	}
+	t, err := movimentarTravado(j.Nome, tipoLoginDiario, recompensaLoginDiario, hoje, "")
+	if err == nil {
+		ultimoLoginDiario[j.Nome] = hoje
+	}
+	economiaMu.Unlock()
+	if err != nil {
+		return
+	}
+	salvarSaldos()
+	j.enviarMensagem(fmt.Sprintf("Recompensa diária: +%d moedas (saldo: %d)", recompensaLoginDiario, t.Saldo))
+}
+
+// credita as recompensas de uma partida encerrada
+func recompensarPartida(r ResultadoPartida) {
+	if r.Vencedor == "" {
+		return
+	}
+	for _, nome := range r.Nomes {
+		tipo, valor := tipoVitoria, recompensaVitoria
+		if nome != r.Vencedor {
+			if r.Motivo == "desconexao" {
+				continue // quem abandonou não recebe nada
+			}
+			tipo, valor = tipoParticipacao, recompensaParticipacao
+		}
+		t, err := movimentar(nome, tipo, valor, r.ID, "")
+		if err != nil {
+			continue
+		}
+		if j := jogadorPorNome(nome); j != nil {
+			j.enviarMensagem(fmt.Sprintf("+%d moedas (%s), saldo: %d", valor, tipo, t.Saldo))
+		}
+	}
+}
+
+// credita a recompensa de uma missão ou conquista concluída
+func recompensarMissao(nome, missao string, valor int) {
+	t, err := movimentar(nome, tipoMissao, valor, missao, "")
+	if err != nil {
+		return
+	}
+	if j := jogadorPorNome(nome); j != nil {
+		j.enviarMensagem(fmt.Sprintf("+%d moedas (%s), saldo: %d", valor, missao, t.Saldo))
+	}
+}
+
+// /loja [comprar <colecao>]
+func tratarLoja(j *Jogador, args []string) {
+	if len(args) == 0 {
+		mostrarLoja(j)
+		return
+	}
+	if args[0] != "comprar" || len(args) < 2 {
+		j.enviarMensagem("Uso: /loja, /loja comprar <colecao>")
+		return
+	}
+	comprarBooster(j, args[1])
+}
+
+// lista as coleções à venda com preço e estoque
+func mostrarLoja(j *Jogador) {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Loja (seu saldo: %d moedas):\n", saldoDe(j.Nome)))
+	boostersMu.Lock()
+	for _, c := range colecoesBooster {
+		builder.WriteString(fmt.Sprintf("  %-9s %4d moedas | %s | estoque %d\n", c.Nome, c.Preco, c.Descricao, len(boosters[c.Nome])))
+	}
+	boostersMu.Unlock()
+	builder.WriteString("Use /loja comprar <colecao> (ou /booster para o básico)")
+	j.enviarMensagem(builder.String())
+}
+
+// debita o preço e abre um pacote da coleção; sem estoque, o valor é estornado
+func comprarBooster(j *Jogador, colecao string) {
+	c := colecaoBooster(colecao)
+	if c == nil {
+		j.enviarMensagem("Coleção desconhecida (veja /loja)")
+		return
+	}
+	boostersMu.Lock()
+	estoque := len(boosters[colecao])
+	boostersMu.Unlock()
+	if estoque == 0 {
+		j.enviarMensagem("Não há boosters dessa coleção, aguarde a próxima reposição")
+		return
+	}
+
+	if _, err := movimentar(j.Nome, tipoCompra, -c.Preco, colecao, ""); err != nil {
+		if errors.Is(err, errSaldoInsuficiente) {
+			j.enviarErro(erroSaldoInsuficiente, fmt.Sprintf("o booster %s custa %d moedas e você tem %d", colecao, c.Preco, saldoDe(j.Nome)))
+			return
+		}
+		j.enviarMensagem("Não foi possível concluir a compra, tente novamente")
+		return
+	}
+	pacote, ok := pegarBooster(colecao, j.Nome)
+	if ok {
+		adicionarCartas(j.Nome, pacote.Cartas)
+	} else {
+		// outro jogador levou o último pacote entre a conferência e a retirada
+		if _, err := movimentar(j.Nome, tipoEstorno, c.Preco, colecao, ""); err != nil {
+			j.logger().Error("erro ao estornar compra", "colecao", colecao, "erro", err)
+		}
+		j.enviarMensagem("Não há boosters dessa coleção, o valor foi estornado")
+		return
+	}
+	j.enviarMensagem(fmt.Sprintf("Você abriu booster %s (%s) -> cartas: %v | saldo: %d", pacote.ID, colecao, pacote.Cartas, saldoDe(j.Nome)))
+}
+
+// /saldo
+func mostrarSaldo(j *Jogador) {
+	economiaMu.Lock()
+	moedas, po := saldos[j.Nome], saldosPo[j.Nome]
+	economiaMu.Unlock()
+	j.enviarMensagem(fmt.Sprintf("Saldo: %d moedas, %d de pó", moedas, po))
+}
+
+// /extrato: transações recentes do jogador
+func mostrarExtrato(j *Jogador) {
+	economiaMu.Lock()
+	extrato := append([]Transacao(nil), extratos[j.Nome]...)
+	economiaMu.Unlock()
+	if len(extrato) == 0 {
+		j.enviarMensagem("Nenhuma transação registrada")
+		return
+	}
+	var builder strings.Builder
+	builder.WriteString("Extrato:\n")
+	for _, t := range extrato {
+		linha := fmt.Sprintf("  #%d %s %-13s %+5d  saldo %5d", t.Seq, t.Momento.Format("02/01 15:04"), t.Tipo, t.Valor, t.Saldo)
+		if t.Po != 0 {
+			linha += fmt.Sprintf("  pó %+5d (%d)", t.Po, t.SaldoPo)
+		}
+		if t.Carta != "" {
+			linha += fmt.Sprintf("  %s x%d", t.Carta, t.Quantidade)
+		}
+		if t.Referencia != "" {
+			linha += "  " + t.Referencia
+		}
+		builder.WriteString(linha + "\n")
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// /mod moedas <nome> <valor> [motivo]: ajuste manual (apenas admins)
+func ajustarMoedas(j *Jogador, args []string) {
+	if !j.temPapel(papelAdmin) {
+		j.enviarErro(erroSemPermissao, "apenas admins podem ajustar moedas")
+		return
+	}
+	if len(args) < 2 {
+		j.enviarMensagem("Uso: /mod moedas <nome> <valor> [motivo]")
+		return
+	}
+	valor, err := strconv.Atoi(args[1])
+	if err != nil || valor == 0 {
+		j.enviarMensagem("Valor inválido")
+		return
+	}
+	t, err := movimentar(args[0], tipoAjuste, valor, strings.Join(args[2:], " "), j.Nome)
+	if errors.Is(err, errSaldoInsuficiente) {
+		j.enviarErro(erroSaldoInsuficiente, fmt.Sprintf("%s tem apenas %d moedas", args[0], saldoDe(args[0])))
+		return
+	}
+	if err != nil {
+		j.enviarMensagem("Não foi possível registrar o ajuste")
+		return
+	}
+	j.logger().Info("moedas ajustadas", "alvo", args[0], "valor", valor)
+	j.enviarMensagem(fmt.Sprintf("Saldo de %s: %d (transação #%d)", args[0], t.Saldo, t.Seq))
+}
+
+// /mod conciliar: confere os saldos em memória com o arquivo de transações (apenas admins)
+func conciliarMoedas(j *Jogador) {
+	if !j.temPapel(papelAdmin) {
+		j.enviarErro(erroSemPermissao, "apenas admins podem conciliar as moedas")
+		return
+	}
+	// economiaMu impede novas transações durante a leitura
+	economiaMu.Lock()
+	l, err := lerTransacoes()
+	var divergencias []string
+	if err == nil {
+		divergencias = l.comparar("memória", saldos, saldosPo)
+	}
+	economiaMu.Unlock()
+	if err != nil {
+		j.enviarMensagem("Erro na conciliação: " + err.Error())
+		return
+	}
+	if len(divergencias) == 0 {
+		j.enviarMensagem(fmt.Sprintf("Conciliação OK: %d transações conferem com os saldos", l.seq))
+		return
+	}
+	j.enviarMensagem(fmt.Sprintf("Conciliação com %d divergências:\n  %s", len(divergencias), strings.Join(divergencias, "\n  ")))
+}
+
+// subcomando "conciliar": confere o arquivo de transações com o snapshot de saldos
+func executarConciliacao() error {
+	l, err := lerTransacoes()
+	if err != nil {
+		return err
+	}
+	snapshot, snapshotPo, err := lerSnapshotSaldos()
+	if err != nil {
+		return err
+	}
+	divergencias := l.comparar("snapshot", snapshot, snapshotPo)
+	for _, d := range divergencias {
+		fmt.Println("Divergência:", d)
+	}
+	if len(divergencias) > 0 {
+		return fmt.Errorf("%d divergências", len(divergencias))
+	}
+	fmt.Printf("Conciliação OK: %d transações de %d jogadores conferidas\n", l.seq, len(l.saldos))
+	return nil
+}
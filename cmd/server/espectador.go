@@ -0,0 +1,258 @@
+// espectador.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// atraso aplicado às mensagens enviadas aos espectadores (evita stream-sniping)
+var atrasoEspectador time.Duration
+
+// lista as partidas em andamento para o jogador
+func listarPartidas(j *Jogador) {
+	lista := partidasEmAndamento()
+	visiveis := lista[:0]
+	for _, p := range lista {
+		if podeVerPartida(j, p) {
+			visiveis = append(visiveis, p)
+		}
+	}
+	lista = visiveis
+
+	if len(lista) == 0 {
+		j.enviarMensagem("Nenhuma partida em andamento")
+		return
+	}
+	sort.Slice(lista, func(a, b int) bool { return lista[a].Criada.Before(lista[b].Criada) })
+
+	var builder strings.Builder
+	builder.WriteString("Partidas em andamento:\n")
+	for _, p := range lista {
+		p.executar(func(p *Partida) {
+			vida := p.vidaAtrasada()
+			builder.WriteString(fmt.Sprintf("  %s: %s (%d) x %s (%d) | %s | %d espectador(es)\n",
+				p.ID, p.A.Nome, vida[p.A.ID], p.B.Nome, vida[p.B.ID],
+				time.Since(p.Criada).Truncate(time.Second), len(p.Espectadores)))
+		})
+	}
+	builder.WriteString("Use /assistir <id> para acompanhar uma partida")
+	j.enviarMensagem(builder.String())
+}
+
+// inscreve o jogador como espectador da partida indicada
+func assistirPartida(j *Jogador, idPartida string) {
+	j.mu.Lock()
+	if j.EmPartida {
+		j.mu.Unlock()
+		j.enviarMensagem("Você não pode assistir enquanto joga uma partida")
+		return
+	}
+	j.mu.Unlock()
+
+	p := partidaPorID(idPartida)
+	if p == nil || !podeVerPartida(j, p) {
+		j.enviarMensagem("Partida não encontrada")
+		return
+	}
+
+	// deixa a partida que estava assistindo antes, se houver
+	pararDeAssistir(j)
+
+	var resumo string
+	var chat []MensagemChat
+	ok := p.executar(func(p *Partida) {
+		p.Espectadores[j.ID] = j
+		vida := p.vidaAtrasada()
+		resumo = fmt.Sprintf("Vida de %s: %d | Vida de %s: %d", p.A.Nome, vida[p.A.ID], p.B.Nome, vida[p.B.ID])
+		chat = p.chatAtrasado()
+		// marcado aqui para que encerrarEspectadores, na mesma goroutine, veja o espectador
+		j.mu.Lock()
+		j.Assistindo = p.ID
+		j.mu.Unlock()
+	})
+	if !ok {
+		j.enviarMensagem("Partida não encontrada")
+		return
+	}
+
+	msg := fmt.Sprintf("\n============================\nAssistindo %s: %s x %s\n%s\n============================", p.ID, p.A.Nome, p.B.Nome, resumo)
+	if atrasoEspectador > 0 {
+		msg += fmt.Sprintf("\nEventos com atraso de %s", atrasoEspectador)
+	}
+	j.enviarMensagem(msg)
+	enviarHistorico(j, "partida", chat)
+}
+
+// vidas dos jogadores a partir de um momento
+type vidaMarcada struct {
+	desde time.Time
+	vida  map[string]int // o motor nunca altera o mapa de um estado já aplicado
+}
+
+// guarda as vidas atuais para o estado atrasado, descartando as marcações que já saíram da
+// janela de atraso (goroutine da partida)
+func (p *Partida) marcarVida() {
+	if atrasoEspectador <= 0 {
+		return
+	}
+	agora := time.Now()
+	limite := agora.Add(-atrasoEspectador)
+	// fica a última marcação anterior ao limite, que é a vida vista pelos espectadores agora
+	i := 0
+	for i+1 < len(p.vidas) && !p.vidas[i+1].desde.After(limite) {
+		i++
+	}
+	p.vidas = append(p.vidas[i:], vidaMarcada{desde: agora, vida: p.Vida})
+}
+
+// vidas como os espectadores as veem, com o mesmo atraso dos eventos (goroutine da partida)
+func (p *Partida) vidaAtrasada() map[string]int {
+	if atrasoEspectador <= 0 || len(p.vidas) == 0 {
+		return p.Vida
+	}
+	limite := time.Now().Add(-atrasoEspectador)
+	vida := p.vidas[0].vida // a partida é mais nova que o atraso: vidas iniciais
+	for _, v := range p.vidas[1:] {
+		if v.desde.After(limite) {
+			break
+		}
+		vida = v.vida
+	}
+	return vida
+}
+
+// chat da partida sem as mensagens que os espectadores ainda não deveriam ter recebido
+// (goroutine da partida)
+func (p *Partida) chatAtrasado() []MensagemChat {
+	limite := time.Now().Add(-atrasoEspectador)
+	chat := make([]MensagemChat, 0, len(p.Chat))
+	for _, m := range p.Chat {
+		if atrasoEspectador <= 0 || !m.Momento.After(limite) {
+			chat = append(chat, m)
+		}
+	}
+	return chat
+}
+
+// remove o jogador da lista de espectadores da partida que ele assiste;
+// retorna false se ele não estava assistindo nenhuma
+func pararDeAssistir(j *Jogador) bool {
+	j.mu.Lock()
+	idPartida := j.Assistindo
+	j.Assistindo = ""
+	j.mu.Unlock()
+	if idPartida == "" {
+		return false
+	}
+
+	if p := partidaPorID(idPartida); p != nil {
+		p.executar(func(p *Partida) { delete(p.Espectadores, j.ID) })
+	}
+	return true
+}
+
+// envia uma mensagem pública para os jogadores e espectadores da partida
+// (goroutine da partida)
+func (p *Partida) publicar(msg string) {
+	p.A.enviarMensagem(msg)
+	p.B.enviarMensagem(msg)
+	p.transmitirEspectadores(msg)
+}
+
+// mensagem pública aguardando o atraso para ser entregue aos espectadores
+type envioAtrasado struct {
+	quando     time.Time
+	msg        string
+	prioridade prioridade
+	destinos   []*Jogador
+}
+
+// envia uma mensagem apenas aos espectadores, respeitando o atraso configurado
+// (goroutine da partida)
+func (p *Partida) transmitirEspectadores(msg string) {
+	p.transmitirEspectadoresFiltrado(msg, msgCritica, nil)
+}
+
+// como transmitirEspectadores, mas apenas para os espectadores aceitos pelo filtro
+// (goroutine da partida)
+func (p *Partida) transmitirEspectadoresFiltrado(msg string, prio prioridade, aceitar func(*Jogador) bool) {
+	destinos := make([]*Jogador, 0, len(p.Espectadores))
+	for _, e := range p.Espectadores {
+		if aceitar == nil || aceitar(e) {
+			destinos = append(destinos, e)
+		}
+	}
+	if len(destinos) == 0 {
+		return
+	}
+	if atrasoEspectador <= 0 {
+		for _, e := range destinos {
+			e.enviar(msg, prio, "")
+		}
+		return
+	}
+	p.atrasados.adicionar(envioAtrasado{quando: time.Now().Add(atrasoEspectador), msg: msg, prioridade: prio, destinos: destinos})
+}
+
+// mensagens de uma partida aguardando o atraso, sem limite de tamanho: nenhum evento é
+// descartado aqui (quem não acompanha é tratado pela fila de saída do espectador). O
+// temporizador entrega as mensagens vencidas e se reagenda para a próxima.
+type filaAtrasada struct {
+	mu        sync.Mutex
+	envios    []envioAtrasado // em ordem de publicação (protegido por mu)
+	agendada  *time.Timer     // próxima entrega (protegido por mu)
+	entregaMu sync.Mutex      // mantém a ordem entre entregas seguidas
+}
+
+func (f *filaAtrasada) adicionar(envio envioAtrasado) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.envios = append(f.envios, envio)
+	if f.agendada == nil {
+		f.agendada = time.AfterFunc(time.Until(envio.quando), f.entregar)
+	}
+}
+
+// entrega as mensagens vencidas na ordem em que foram publicadas
+func (f *filaAtrasada) entregar() {
+	f.entregaMu.Lock()
+	defer f.entregaMu.Unlock()
+
+	f.mu.Lock()
+	agora := time.Now()
+	n := 0
+	for n < len(f.envios) && !f.envios[n].quando.After(agora) {
+		n++
+	}
+	vencidos := append([]envioAtrasado(nil), f.envios[:n]...)
+	f.envios = append(f.envios[:0], f.envios[n:]...)
+	f.agendada = nil
+	if len(f.envios) > 0 {
+		f.agendada = time.AfterFunc(time.Until(f.envios[0].quando), f.entregar)
+	}
+	f.mu.Unlock()
+
+	for _, envio := range vencidos {
+		for _, e := range envio.destinos {
+			e.enviar(envio.msg, envio.prioridade, "")
+		}
+	}
+}
+
+// dispensa os espectadores de uma partida encerrada
+// (goroutine da partida)
+func (p *Partida) encerrarEspectadores() {
+	for id, e := range p.Espectadores {
+		e.mu.Lock()
+		if e.Assistindo == p.ID {
+			e.Assistindo = ""
+		}
+		e.mu.Unlock()
+		delete(p.Espectadores, id)
+	}
+	// as mensagens ainda no atraso, como o fim da partida, são entregues pelo temporizador
+}
@@ -0,0 +1,378 @@
+// estatisticas.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diretório onde os dados persistentes do servidor são gravados
+var diretorioDados = "dados"
+
+// tamanho de cada página do ranking
+const tamanhoPaginaRanking = 10
+
+// estatísticas acumuladas de um jogador (no geral ou em uma temporada)
+type Estatisticas struct {
+	Vitorias        int         `json:"vitorias"`
+	Derrotas        int         `json:"derrotas"`
+	Sequencia       int         `json:"sequencia"`        // > 0 vitórias seguidas, < 0 derrotas seguidas
+	MelhorSequencia int         `json:"melhor_sequencia"` // maior sequência de vitórias
+	DuracaoTotal    int64       `json:"duracao_total_ms"` // soma da duração das partidas
+	DanoCausado     int         `json:"dano_causado"`
+	CartasJogadas   map[int]int `json:"cartas_jogadas"` // ID da carta -> vezes jogada
+}
+
+// perfil persistido de um jogador, identificado pelo nome
+type PerfilJogador struct {
+	Nome       string                   `json:"nome"`
+	Geral      Estatisticas             `json:"geral"`
+	Temporadas map[string]*Estatisticas `json:"temporadas"` // "2006-01" -> estatísticas
+}
+
+// resumo de uma partida encerrada, extraído do log de eventos
+type ResultadoPartida struct {
+	ID       string
+	Nomes    [2]string // nomes dos jogadores A e B
+	Vencedor string    // nome do vencedor
+	Motivo   string
+	Duracao  time.Duration
+	Fim      time.Time
+	Dano     map[string]int   // nome -> dano causado
+	Cartas   map[string][]int // nome -> cartas jogadas
+}
+
+// posição de um jogador no ranking de uma temporada
+type entradaRanking struct {
+	nome     string
+	vitorias int
+	derrotas int
+}
+
+var (
+	estatisticasMu sync.Mutex
+	perfis         = map[string]*PerfilJogador{}    // nome -> perfil
+	rankings       = map[string][]*entradaRanking{} // temporada -> entradas ordenadas
+	salvarPerfisMu sync.Mutex                       // serializa as gravações do arquivo
+
+	// estatisticas.json é regravado inteiro; os fins de partida só marcam o arquivo
+	gravacaoPerfis = novaGravacaoAdiada("estatisticas", gravarPerfis)
+)
+
+// temporada (ano-mês) a que um instante pertence
+func temporadaDe(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// extrai o resultado de uma partida a partir do seu log (goroutine da partida)
+func (p *Partida) resultado(vencedorID, motivo string) ResultadoPartida {
+	nomes := map[string]string{p.A.ID: p.A.Nome, p.B.ID: p.B.Nome}
+	r := ResultadoPartida{
+		ID:       p.ID,
+		Nomes:    [2]string{p.A.Nome, p.B.Nome},
+		Vencedor: nomes[vencedorID],
+		Motivo:   motivo,
+		Fim:      time.Now(),
+		Dano:     map[string]int{},
+		Cartas:   map[string][]int{},
+	}
+	r.Duracao = r.Fim.Sub(p.Criada)
+	for _, ev := range p.Eventos {
+		switch ev.Tipo {
+		case "acao":
+			if ev.Acao != nil && ev.Acao.Acao == "jogar_carta" {
+				r.Cartas[nomes[ev.Jogador]] = append(r.Cartas[nomes[ev.Jogador]], ev.Acao.CartaID)
+			}
+		case "dano":
+			// o evento de dano identifica o alvo; o dano é creditado ao oponente
+			r.Dano[nomes[outroJogador(p, ev.Jogador)]] += ev.Valor
+		}
+	}
+	return r
+}
+
+// aplica o resultado de uma partida às estatísticas dos dois jogadores
+func registrarEstatisticas(r ResultadoPartida) {
+	if r.Vencedor == "" {
+		return // encerrada por um admin sem vencedor: não conta para ninguém
+	}
+	estatisticasMu.Lock()
+	temporada := temporadaDe(r.Fim)
+	for _, nome := range r.Nomes {
+		perfil := perfis[nome]
+		if perfil == nil {
+			perfil = &PerfilJogador{Nome: nome, Temporadas: map[string]*Estatisticas{}}
+			perfis[nome] = perfil
+		}
+		est := perfil.Temporadas[temporada]
+		if est == nil {
+			est = &Estatisticas{}
+			perfil.Temporadas[temporada] = est
+		} else {
+			removerDoRanking(temporada, nome, est)
+		}
+		venceu := nome == r.Vencedor
+		perfil.Geral.somar(r, nome, venceu)
+		est.somar(r, nome, venceu)
+		inserirNoRanking(temporada, nome, est)
+	}
+	estatisticasMu.Unlock()
+	gravacaoPerfis.marcar()
+}
+
+// soma uma partida às estatísticas
+func (e *Estatisticas) somar(r ResultadoPartida, nome string, venceu bool) {
+	if venceu {
+		e.Vitorias++
+		if e.Sequencia < 0 {
+			e.Sequencia = 0
+		}
+		e.Sequencia++
+		if e.Sequencia > e.MelhorSequencia {
+			e.MelhorSequencia = e.Sequencia
+		}
+	} else {
+		e.Derrotas++
+		if e.Sequencia > 0 {
+			e.Sequencia = 0
+		}
+		e.Sequencia--
+	}
+	e.DuracaoTotal += r.Duracao.Milliseconds()
+	e.DanoCausado += r.Dano[nome]
+	if e.CartasJogadas == nil {
+		e.CartasJogadas = map[int]int{}
+	}
+	for _, cid := range r.Cartas[nome] {
+		e.CartasJogadas[cid]++
+	}
+}
+
+// ordem do ranking: mais vitórias, depois menos derrotas, depois nome
+func (a *entradaRanking) antes(b *entradaRanking) bool {
+	if a.vitorias != b.vitorias {
+		return a.vitorias > b.vitorias
+	}
+	if a.derrotas != b.derrotas {
+		return a.derrotas < b.derrotas
+	}
+	return a.nome < b.nome
+}
+
+// insere o jogador no ranking da temporada na posição dada pela busca binária
+// (deve ser chamada com estatisticasMu travado)
+func inserirNoRanking(temporada, nome string, est *Estatisticas) {
+	lista := rankings[temporada]
+	nova := &entradaRanking{nome: nome, vitorias: est.Vitorias, derrotas: est.Derrotas}
+	pos := sort.Search(len(lista), func(i int) bool { return nova.antes(lista[i]) })
+	lista = append(lista, nil)
+	copy(lista[pos+1:], lista[pos:])
+	lista[pos] = nova
+	rankings[temporada] = lista
+}
+
+// remove o jogador do ranking da temporada, localizando-o pelas estatísticas atuais
+// (deve ser chamada com estatisticasMu travado, antes de alterar est)
+func removerDoRanking(temporada, nome string, est *Estatisticas) {
+	if pos := posicaoNoRanking(temporada, nome, est); pos > 0 {
+		lista := rankings[temporada]
+		rankings[temporada] = append(lista[:pos-1], lista[pos:]...)
+	}
+}
+
+// localiza a entrada do jogador no ranking pela ordem (deve ser chamada com estatisticasMu travado)
+func posicaoNoRanking(temporada, nome string, est *Estatisticas) int {
+	lista := rankings[temporada]
+	alvo := &entradaRanking{nome: nome, vitorias: est.Vitorias, derrotas: est.Derrotas}
+	pos := sort.Search(len(lista), func(i int) bool { return !lista[i].antes(alvo) })
+	if pos < len(lista) && lista[pos].nome == nome {
+		return pos + 1
+	}
+	return 0
+}
+
+// grava os perfis no diretório de dados (arquivo temporário + rename)
+func gravarPerfis() {
+	salvarPerfisMu.Lock()
+	defer salvarPerfisMu.Unlock()
+
+	estatisticasMu.Lock()
+	dados, err := json.Marshal(perfis)
+	estatisticasMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "estatisticas.json"), dados)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar estatísticas", "erro", err)
+	}
+}
+
+// grava um arquivo de forma atômica dentro do diretório de dados
+func gravarArquivo(caminho string, dados []byte) error {
+	if err := os.MkdirAll(filepath.Dir(caminho), 0o755); err != nil {
+		return err
+	}
+	tmp := caminho + ".tmp"
+	if err := os.WriteFile(tmp, dados, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, caminho)
+}
+
+// carrega os perfis gravados e reconstrói os rankings
+func carregarEstatisticas() error {
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "estatisticas.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	carregados := map[string]*PerfilJogador{}
+	if err := json.Unmarshal(dados, &carregados); err != nil {
+		return err
+	}
+
+	estatisticasMu.Lock()
+	defer estatisticasMu.Unlock()
+	perfis = carregados
+	rankings = map[string][]*entradaRanking{}
+	for nome, perfil := range perfis {
+		for temporada, est := range perfil.Temporadas {
+			rankings[temporada] = append(rankings[temporada], &entradaRanking{nome: nome, vitorias: est.Vitorias, derrotas: est.Derrotas})
+		}
+	}
+	for _, lista := range rankings {
+		sort.Slice(lista, func(i, k int) bool { return lista[i].antes(lista[k]) })
+	}
+	slog.Info("estatísticas carregadas", "jogadores", len(perfis))
+	return nil
+}
+
+// exibe o perfil de um jogador: /perfil [nome]
+func mostrarPerfil(j *Jogador, nome string) {
+	if nome == "" {
+		nome = j.Nome
+	}
+	j.enviarMensagem(textoPerfil(nome, temporadaDe(time.Now())))
+}
+
+// texto do /perfil; monta a mensagem com estatisticasMu travado e a devolve para ser
+// enviada depois de soltar a trava
+func textoPerfil(nome, temporada string) string {
+	estatisticasMu.Lock()
+	defer estatisticasMu.Unlock()
+	perfil := perfis[nome]
+	if perfil == nil {
+		return fmt.Sprintf("Nenhuma partida registrada para %s", nome)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Perfil de %s\n", perfil.Nome))
+	builder.WriteString(formatarEstatisticas("Geral", &perfil.Geral))
+	if est := perfil.Temporadas[temporada]; est != nil {
+		titulo := fmt.Sprintf("Temporada %s", temporada)
+		if pos := posicaoNoRanking(temporada, nome, est); pos > 0 {
+			titulo += fmt.Sprintf(" (#%d de %d)", pos, len(rankings[temporada]))
+		}
+		builder.WriteString(formatarEstatisticas(titulo, est))
+	}
+	return builder.String()
+}
+
+// formata um bloco de estatísticas para exibição
+func formatarEstatisticas(titulo string, est *Estatisticas) string {
+	partidas := est.Vitorias + est.Derrotas
+	if partidas == 0 {
+		return fmt.Sprintf("  %s: sem partidas\n", titulo)
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("  %s: %d partidas | %d vitórias | %d derrotas | %.0f%% de vitórias\n",
+		titulo, partidas, est.Vitorias, est.Derrotas, 100*float64(est.Vitorias)/float64(partidas)))
+
+	sequencia := "nenhuma"
+	if est.Sequencia > 0 {
+		sequencia = fmt.Sprintf("%d vitória(s)", est.Sequencia)
+	} else if est.Sequencia < 0 {
+		sequencia = fmt.Sprintf("%d derrota(s)", -est.Sequencia)
+	}
+	builder.WriteString(fmt.Sprintf("    Sequência atual: %s | melhor sequência: %d\n", sequencia, est.MelhorSequencia))
+	builder.WriteString(fmt.Sprintf("    Duração média: %s | dano causado: %d (%.1f por partida)\n",
+		(time.Duration(est.DuracaoTotal/int64(partidas)) * time.Millisecond).Truncate(time.Second),
+		est.DanoCausado, float64(est.DanoCausado)/float64(partidas)))
+
+	if len(est.CartasJogadas) > 0 {
+		cartas := make([]int, 0, len(est.CartasJogadas))
+		for cid := range est.CartasJogadas {
+			cartas = append(cartas, cid)
+		}
+		sort.Slice(cartas, func(a, b int) bool {
+			if est.CartasJogadas[cartas[a]] != est.CartasJogadas[cartas[b]] {
+				return est.CartasJogadas[cartas[a]] > est.CartasJogadas[cartas[b]]
+			}
+			return cartas[a] < cartas[b]
+		})
+		if len(cartas) > 3 {
+			cartas = cartas[:3]
+		}
+		partes := make([]string, 0, len(cartas))
+		for _, cid := range cartas {
+			partes = append(partes, fmt.Sprintf("[%d] %s x%d", cid, nomeCarta(cid), est.CartasJogadas[cid]))
+		}
+		builder.WriteString("    Cartas mais jogadas: " + strings.Join(partes, ", ") + "\n")
+	}
+	return builder.String()
+}
+
+// exibe uma página do ranking: /ranking [temporada] [pagina]
+func mostrarRanking(j *Jogador, args []string) {
+	temporada := temporadaDe(time.Now())
+	pagina := 1
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			pagina = n
+		} else {
+			temporada = arg
+		}
+	}
+	if pagina < 1 {
+		j.enviarMensagem("Página inválida")
+		return
+	}
+	j.enviarMensagem(textoRanking(temporada, pagina))
+}
+
+// texto de uma página do /ranking, montado com estatisticasMu travado
+func textoRanking(temporada string, pagina int) string {
+	estatisticasMu.Lock()
+	defer estatisticasMu.Unlock()
+	lista := rankings[temporada]
+	if len(lista) == 0 {
+		return fmt.Sprintf("Nenhuma partida registrada na temporada %s", temporada)
+	}
+	totalPaginas := (len(lista) + tamanhoPaginaRanking - 1) / tamanhoPaginaRanking
+	if pagina > totalPaginas {
+		return fmt.Sprintf("O ranking da temporada %s tem %d página(s)", temporada, totalPaginas)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Ranking da temporada %s (página %d de %d):\n", temporada, pagina, totalPaginas))
+	inicio := (pagina - 1) * tamanhoPaginaRanking
+	fim := inicio + tamanhoPaginaRanking
+	if fim > len(lista) {
+		fim = len(lista)
+	}
+	for i, e := range lista[inicio:fim] {
+		builder.WriteString(fmt.Sprintf("  %3d. %s - %d V / %d D\n", inicio+i+1, e.nome, e.vitorias, e.derrotas))
+	}
+	return builder.String()
+}
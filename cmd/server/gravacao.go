@@ -0,0 +1,97 @@
+// gravacao.go
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Gravações adiadas: arquivos de snapshot que são regravados inteiros (saldos, coleções,
+// perfis) não são gravados a cada mudança. A primeira mudança agenda uma gravação para daqui
+// a intervaloGravacao e as seguintes só marcam o arquivo como sujo, então cada intervalo custa
+// uma única gravação, qualquer que seja o número de jogadores. Ao receber SIGINT/SIGTERM o
+// servidor grava o que estiver pendente antes de sair.
+
+var intervaloGravacao = time.Second
+
+type gravacaoAdiada struct {
+	nome     string
+	gravar   func() // grava o arquivo inteiro; serializada pelo próprio arquivo
+	mu       sync.Mutex
+	agendada *time.Timer // gravação pendente (protegido por mu)
+}
+
+var (
+	gravacoesMu sync.Mutex
+	gravacoes   []*gravacaoAdiada // todas as gravações adiadas, descarregadas ao encerrar
+)
+
+func novaGravacaoAdiada(nome string, gravar func()) *gravacaoAdiada {
+	g := &gravacaoAdiada{nome: nome, gravar: gravar}
+	gravacoesMu.Lock()
+	gravacoes = append(gravacoes, g)
+	gravacoesMu.Unlock()
+	return g
+}
+
+// marca o arquivo como sujo; a gravação acontece ao fim do intervalo
+func (g *gravacaoAdiada) marcar() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.agendada == nil {
+		g.agendada = time.AfterFunc(intervaloGravacao, g.executar)
+	}
+}
+
+func (g *gravacaoAdiada) executar() {
+	g.mu.Lock()
+	g.agendada = nil
+	g.mu.Unlock()
+	g.gravar()
+}
+
+// grava agora, cancelando a gravação agendada
+func (g *gravacaoAdiada) agora() {
+	g.mu.Lock()
+	if g.agendada != nil {
+		g.agendada.Stop()
+		g.agendada = nil
+	}
+	g.mu.Unlock()
+	g.gravar()
+}
+
+// grava agora apenas se havia mudança pendente
+func (g *gravacaoAdiada) descarregar() {
+	g.mu.Lock()
+	pendente := g.agendada != nil && g.agendada.Stop()
+	g.agendada = nil
+	g.mu.Unlock()
+	if pendente {
+		g.gravar()
+	}
+}
+
+// grava tudo o que estiver pendente
+func descarregarGravacoes() {
+	gravacoesMu.Lock()
+	lista := append([]*gravacaoAdiada(nil), gravacoes...)
+	gravacoesMu.Unlock()
+	for _, g := range lista {
+		g.descarregar()
+	}
+}
+
+// ao receber SIGINT ou SIGTERM, grava o que estiver pendente e encerra o processo
+func aguardarEncerramento() {
+	sinais := make(chan os.Signal, 1)
+	signal.Notify(sinais, os.Interrupt, syscall.SIGTERM)
+	s := <-sinais
+	slog.Info("encerrando o servidor", "sinal", s.String())
+	descarregarGravacoes()
+	os.Exit(0)
+}
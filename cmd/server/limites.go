@@ -0,0 +1,424 @@
+// limites.go
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cada linha recebida gasta uma ficha de um balde da sua categoria (heartbeat, chat, jogo,
+// comandos caros, demais comandos) na conexão e no IP de origem; o balde do IP é compartilhado
+// por todas as conexões dele e tem limiteIPFator vezes o orçamento de uma conexão. Linhas
+// sem ficha são recusadas com "ERRO limite_excedido" e contam como violação, assim como
+// linhas longas demais. Um IP com violacoesBanimento violações dentro de janelaViolacoes
+// é banido por duracaoBanimentoIP: suas conexões caem e novas são recusadas. A entrada (nome
+// e senha) gasta uma ficha de um balde só do IP, antes da derivação da senha.
+
+// categoria de uma linha para os limites
+type categoria int
+
+const (
+	catHeartbeat categoria = iota // respostas aos heartbeats, com balde próprio e pequeno
+	catChat                       // mensagens de chat e sussurros
+	catJogo                       // jogadas, fila e mão
+	catCaro                       // comandos que percorrem catálogos, rankings ou compram boosters
+	catComando                    // demais comandos e linhas inválidas
+	catEntrada                    // nome e senha ao conectar (apenas no balde do IP)
+	numCategorias
+)
+
+var nomesCategoria = [numCategorias]string{"heartbeat", "chat", "jogo", "caro", "comando", "entrada"}
+
+// orçamento de um balde: rajada máxima e fichas devolvidas por segundo
+type orcamento struct {
+	capacidade float64
+	porSegundo float64
+}
+
+// orçamento de cada conexão por categoria (o de heartbeat depende do intervalo; veja orcamentoDe)
+var orcamentos = [numCategorias]orcamento{
+	catChat:    {capacidade: 5, porSegundo: 1},
+	catJogo:    {capacidade: 10, porSegundo: 5},
+	catCaro:    {capacidade: 3, porSegundo: 0.5},
+	catComando: {capacidade: 20, porSegundo: 10},
+	catEntrada: {capacidade: 5, porSegundo: 0.2},
+}
+
+// comandos fora da categoria catComando
+var categoriaComandos = map[string]categoria{
+	"heartbeat": catHeartbeat,
+	"sussurrar": catChat, "denunciar": catChat,
+	"jogar": catJogo, "fim": catJogo, "mao": catJogo, "entrar": catJogo, "sair": catJogo,
+	"booster": catCaro, "cartas": catCaro, "loja": catCaro, "ranking": catCaro, "perfil": catCaro,
+	"partidas": catCaro, "extrato": catCaro, "colecao": catCaro, "desencantar": catCaro, "criar": catCaro,
+	"missoes": catCaro,
+}
+
+var (
+	limitesAtivos        = true             // desligado por -limites=false (testes de carga)
+	limiteIPFator        = 4.0              // orçamento do IP em múltiplos do de uma conexão (0 desativa)
+	conexoesPorIP        = 20               // conexões simultâneas por IP (0 = sem limite)
+	prazoNome            = 10 * time.Second // tempo para enviar a linha do nome (slow-loris)
+	violacoesBanimento   = 30               // violações na janela que banem o IP
+	janelaViolacoes      = time.Minute
+	duracaoBanimentoIP   = 5 * time.Minute
+	totalLimitadas       atomic.Int64 // linhas recusadas por falta de fichas
+	totalBanimentosIP    atomic.Int64
+	totalConexoesNegadas atomic.Int64 // conexões recusadas pelo limite por IP ou por banimento
+)
+
+// códigos de erro dos limites
+const (
+	erroLimite         = "limite_excedido"
+	erroLimiteConexoes = "limite_conexoes"
+	erroIPBanido       = "ip_banido"
+)
+
+// balde de fichas
+type balde struct {
+	fichas float64
+	ultimo time.Time
+}
+
+// retira uma ficha; sem ficha, retorna quanto falta para a próxima
+func (b *balde) retirar(o orcamento, fator float64, agora time.Time) time.Duration {
+	capacidade, taxa := o.capacidade*fator, o.porSegundo*fator
+	if b.ultimo.IsZero() {
+		b.fichas = capacidade
+	} else {
+		b.fichas = min(capacidade, b.fichas+agora.Sub(b.ultimo).Seconds()*taxa)
+	}
+	b.ultimo = agora
+	if b.fichas >= 1 {
+		b.fichas--
+		return 0
+	}
+	return time.Duration((1 - b.fichas) / taxa * float64(time.Second))
+}
+
+// estado de um IP (protegido por ipsMu)
+type estadoIP struct {
+	conexoes      map[net.Conn]struct{}
+	baldes        [numCategorias]balde
+	violacoes     int
+	janela        time.Time // início da janela de violações
+	banidoAte     time.Time // por abuso ou junto com o banimento de um nome (moderacao.go)
+	silenciadoAte time.Time // junto com o silêncio de um nome
+}
+
+var (
+	ipsMu sync.Mutex // folha: nenhuma outra trava é adquirida com ela
+	ips   = map[string]*estadoIP{}
+)
+
+// limites de uma conexão; usados apenas pela goroutine que lê a conexão
+type limitesConexao struct {
+	ip     string
+	baldes [numCategorias]balde
+}
+
+// IP de origem da conexão, sem a porta
+func enderecoIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// IP da conexão atual do jogador
+func (j *Jogador) ipAtual() string {
+	j.mu.Lock()
+	conn := j.Conexao
+	j.mu.Unlock()
+	return enderecoIP(conn)
+}
+
+// registra a conexão no IP; retorna o erro a enviar se ela deve ser recusada
+func entrarIP(conn net.Conn) string {
+	ip := enderecoIP(conn)
+	agora := time.Now()
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	e := ips[ip]
+	if e == nil {
+		e = &estadoIP{conexoes: map[net.Conn]struct{}{}}
+		ips[ip] = e
+	}
+	switch {
+	case agora.Before(e.banidoAte):
+		totalConexoesNegadas.Add(1)
+		return fmt.Sprintf("ERRO %s: endereço bloqueado até %s", erroIPBanido, e.banidoAte.Format("02/01 15:04:05"))
+	case conexoesPorIP > 0 && len(e.conexoes) >= conexoesPorIP:
+		totalConexoesNegadas.Add(1)
+		return fmt.Sprintf("ERRO %s: máximo de %d conexões por endereço", erroLimiteConexoes, conexoesPorIP)
+	}
+	e.conexoes[conn] = struct{}{}
+	return ""
+}
+
+// retira a conexão do IP; o estado é descartado quando não resta nada a lembrar
+func sairIP(conn net.Conn) {
+	ip := enderecoIP(conn)
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	if e := ips[ip]; e != nil {
+		delete(e.conexoes, conn)
+		esquecerIP(ip, e, time.Now())
+	}
+}
+
+// (com ipsMu)
+func esquecerIP(ip string, e *estadoIP, agora time.Time) {
+	// o balde de entrada só é esquecido depois de encher de novo, senão reconectar o zeraria
+	o := orcamentoDe(catEntrada)
+	entradaCheia := agora.Sub(e.baldes[catEntrada].ultimo).Seconds()*o.porSegundo >= o.capacidade
+	if len(e.conexoes) == 0 && entradaCheia && !agora.Before(e.banidoAte) && !agora.Before(e.silenciadoAte) && agora.Sub(e.janela) > janelaViolacoes {
+		delete(ips, ip)
+	}
+}
+
+// descarta periodicamente os IPs com banimento vencido e sem conexões
+func loopLimpezaIPs() {
+	for range time.Tick(time.Minute) {
+		agora := time.Now()
+		ipsMu.Lock()
+		for ip, e := range ips {
+			esquecerIP(ip, e, agora)
+		}
+		ipsMu.Unlock()
+	}
+}
+
+// informa se o IP da conexão está banido
+func ipBanido(conn net.Conn) bool {
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	e := ips[enderecoIP(conn)]
+	return e != nil && time.Now().Before(e.banidoAte)
+}
+
+// estende o banimento e o silêncio do IP até os prazos de uma punição (moderacao.go)
+func punirIP(ip string, banidoAte, silenciadoAte time.Time) {
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	e := ips[ip]
+	if e == nil {
+		e = &estadoIP{conexoes: map[net.Conn]struct{}{}}
+		ips[ip] = e
+	}
+	if banidoAte.After(e.banidoAte) {
+		e.banidoAte = banidoAte
+	}
+	if silenciadoAte.After(e.silenciadoAte) {
+		e.silenciadoAte = silenciadoAte
+	}
+}
+
+// retira o banimento e o silêncio do IP
+func liberarIP(ip string) {
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	if e := ips[ip]; e != nil {
+		e.banidoAte, e.silenciadoAte, e.violacoes = time.Time{}, time.Time{}, 0
+		esquecerIP(ip, e, time.Now())
+	}
+}
+
+// até quando o IP está silenciado (zero se não estiver)
+func silencioIP(ip string) time.Time {
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	if e := ips[ip]; e != nil && time.Now().Before(e.silenciadoAte) {
+		return e.silenciadoAte
+	}
+	return time.Time{}
+}
+
+// gasta uma ficha de entrada do IP antes de conferir a senha; sem ficha, retorna quanto
+// falta para a próxima
+func permitirEntrada(ip string) time.Duration {
+	if !limitesAtivos || limiteIPFator <= 0 {
+		return 0
+	}
+	ipsMu.Lock()
+	defer ipsMu.Unlock()
+	e := ips[ip]
+	if e == nil {
+		return 0
+	}
+	espera := e.baldes[catEntrada].retirar(orcamentoDe(catEntrada), 1, time.Now())
+	if espera > 0 {
+		totalLimitadas.Add(1)
+		espera = max(espera, 100*time.Millisecond).Round(100 * time.Millisecond)
+	}
+	return espera
+}
+
+func novosLimites(conn net.Conn) *limitesConexao {
+	return &limitesConexao{ip: enderecoIP(conn)}
+}
+
+// categoria de um comando de texto pela primeira palavra da linha, separada como em
+// comando.Analisar (espaço ou tabulação)
+func categoriaDoComando(linha string) categoria {
+	nome := strings.TrimPrefix(linha, "/")
+	if fim := strings.IndexAny(nome, " \t"); fim >= 0 {
+		nome = nome[:fim]
+	}
+	if cat, ok := categoriaComandos[strings.ToLower(nome)]; ok {
+		return cat
+	}
+	return catComando
+}
+
+// categoria de uma ação JSON
+func categoriaDaAcao(acao string) categoria {
+	switch acao {
+	case "chat", "sussurrar":
+		return catChat
+	case "jogar_carta", "fim_turno":
+		return catJogo
+	}
+	return catComando
+}
+
+// orçamento da categoria; o servidor pede uma resposta de heartbeat a cada
+// intervaloHeartbeat, então o balde aceita duas por intervalo, com uma rajada pequena para
+// as respostas acumuladas após uma retomada
+func orcamentoDe(cat categoria) orcamento {
+	if cat == catHeartbeat {
+		return orcamento{capacidade: 3, porSegundo: 2 / intervaloHeartbeat.Seconds()}
+	}
+	return orcamentos[cat]
+}
+
+// gasta uma ficha da categoria na conexão e no IP; sem ficha, avisa o jogador, conta a
+// violação e retorna falso
+func (l *limitesConexao) permitir(j *Jogador, cat categoria) bool {
+	if !limitesAtivos {
+		return true
+	}
+	agora := time.Now()
+	espera := l.baldes[cat].retirar(orcamentoDe(cat), 1, agora)
+	if espera == 0 && limiteIPFator > 0 {
+		ipsMu.Lock()
+		if e := ips[l.ip]; e != nil {
+			espera = e.baldes[cat].retirar(orcamentoDe(cat), limiteIPFator, agora)
+		}
+		ipsMu.Unlock()
+	}
+	if espera == 0 {
+		return true
+	}
+	totalLimitadas.Add(1)
+	espera = max(espera, 100*time.Millisecond).Round(100 * time.Millisecond)
+	j.enviarErro(erroLimite, fmt.Sprintf("%s: muitas mensagens, tente de novo em %s", nomesCategoria[cat], espera))
+	l.violacao(j, "limite de "+nomesCategoria[cat])
+	return false
+}
+
+// conta uma violação do IP; ao atingir violacoesBanimento o IP é banido e suas conexões caem
+func (l *limitesConexao) violacao(j *Jogador, motivo string) {
+	if !limitesAtivos {
+		return
+	}
+	agora := time.Now()
+	ipsMu.Lock()
+	e := ips[l.ip]
+	if e == nil || agora.Before(e.banidoAte) {
+		ipsMu.Unlock()
+		return
+	}
+	if agora.Sub(e.janela) > janelaViolacoes {
+		e.janela, e.violacoes = agora, 0
+	}
+	e.violacoes++
+	if e.violacoes < violacoesBanimento {
+		ipsMu.Unlock()
+		return
+	}
+	e.banidoAte = agora.Add(duracaoBanimentoIP)
+	e.violacoes = 0
+	conexoes := make([]net.Conn, 0, len(e.conexoes))
+	for conn := range e.conexoes {
+		conexoes = append(conexoes, conn)
+	}
+	ipsMu.Unlock()
+
+	totalBanimentosIP.Add(1)
+	j.logger().Warn("IP banido por abuso", "ip", l.ip, "ate", agora.Add(duracaoBanimentoIP), "motivo", motivo)
+	aviso := fmt.Sprintf("ERRO %s: endereço bloqueado por abuso até %s\n", erroIPBanido, agora.Add(duracaoBanimentoIP).Format("15:04:05"))
+	for _, conn := range conexoes {
+		conn.SetWriteDeadline(agora.Add(time.Second))
+		conn.Write([]byte(aviso))
+		conn.Close() // o leitor de cada conexão percebe e remove o jogador
+	}
+}
+
+// /mod ips [liberar <ip>]: IPs com conexões, violações ou banimentos
+func tratarIPs(j *Jogador, args []string) {
+	if len(args) >= 2 && args[0] == "liberar" {
+		ipsMu.Lock()
+		e := ips[args[1]]
+		banido := e != nil && time.Now().Before(e.banidoAte)
+		if e != nil {
+			e.banidoAte, e.violacoes = time.Time{}, 0
+		}
+		ipsMu.Unlock()
+		if !banido {
+			j.enviarMensagem(fmt.Sprintf("%s não está banido", args[1]))
+			return
+		}
+		j.logger().Info("IP liberado", "ip", args[1])
+		j.enviarMensagem(fmt.Sprintf("IP %s liberado", args[1]))
+		return
+	}
+
+	type linhaIP struct {
+		ip                  string
+		conexoes, violacoes int
+		banidoAte           time.Time
+		silenciadoAte       time.Time
+	}
+	agora := time.Now()
+	ipsMu.Lock()
+	linhas := make([]linhaIP, 0, len(ips))
+	for ip, e := range ips {
+		l := linhaIP{ip: ip, conexoes: len(e.conexoes), banidoAte: e.banidoAte, silenciadoAte: e.silenciadoAte}
+		if agora.Sub(e.janela) <= janelaViolacoes {
+			l.violacoes = e.violacoes
+		}
+		linhas = append(linhas, l)
+	}
+	ipsMu.Unlock()
+	sort.Slice(linhas, func(a, b int) bool {
+		if linhas[a].conexoes != linhas[b].conexoes {
+			return linhas[a].conexoes > linhas[b].conexoes
+		}
+		return linhas[a].ip < linhas[b].ip
+	})
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Limites: %d linhas recusadas, %d conexões negadas, %d IPs banidos desde o início\n",
+		totalLimitadas.Load(), totalConexoesNegadas.Load(), totalBanimentosIP.Load()))
+	for i, l := range linhas {
+		if i == 20 {
+			break
+		}
+		situacao := ""
+		if agora.Before(l.banidoAte) {
+			situacao = ", banido até " + l.banidoAte.Format("02/01 15:04:05")
+		}
+		if agora.Before(l.silenciadoAte) {
+			situacao += ", silenciado até " + l.silenciadoAte.Format("02/01 15:04:05")
+		}
+		builder.WriteString(fmt.Sprintf("  %-40s %d conexões, %d violações%s\n", l.ip, l.conexoes, l.violacoes, situacao))
+	}
+	j.enviarMensagem(builder.String())
+}
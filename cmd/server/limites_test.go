@@ -0,0 +1,29 @@
+// limites_test.go
+package main
+
+import "testing"
+
+// o nome do comando é separado como em comando.Analisar: por espaço ou tabulação
+func TestCategoriaDoComando(t *testing.T) {
+	casos := []struct {
+		linha string
+		cat   categoria
+	}{
+		{"/ranking 2", catCaro},
+		{"/ranking\tX", catCaro},
+		{"/perfil\tx", catCaro},
+		{"/loja\tcomprar basica", catCaro},
+		{"/LOJA\tcomprar", catCaro},
+		{"/jogar\t7", catJogo},
+		{"/jogar 7", catJogo},
+		{"/sussurrar\tana oi", catChat},
+		{"/heartbeat\t3", catHeartbeat},
+		{"/saldo", catComando},
+		{"/desconhecido\tx", catComando},
+	}
+	for _, c := range casos {
+		if got := categoriaDoComando(c.linha); got != c.cat {
+			t.Errorf("categoriaDoComando(%q) = %s, esperado %s", c.linha, nomesCategoria[got], nomesCategoria[c.cat])
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// logs.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// Logs estruturados com log/slog, em texto ou JSON. As linhas sobre uma conexão ou partida
+// levam os campos de correlação player_id, match_id e remote_addr (j.logger, p.logger,
+// loggerConexao). O nível pode ser trocado em execução por /mod log <nivel>.
+
+// nível mínimo dos logs, alterável em execução
+var nivelLog slog.LevelVar
+
+// nomes aceitos pelos flags e por /mod log, com os equivalentes do slog
+var niveisLog = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"aviso": slog.LevelWarn, "warn": slog.LevelWarn,
+	"erro": slog.LevelError, "error": slog.LevelError,
+}
+
+func interpretarNivel(nome string) (slog.Level, error) {
+	nivel, ok := niveisLog[strings.ToLower(nome)]
+	if !ok {
+		return 0, fmt.Errorf("nível de log desconhecido %q (use debug, info, aviso ou erro)", nome)
+	}
+	return nivel, nil
+}
+
+// instala o logger padrão; o pacote log também passa a escrever por ele
+func configurarLogs(formato, nivel string) error {
+	n, err := interpretarNivel(nivel)
+	if err != nil {
+		return err
+	}
+	nivelLog.Set(n)
+	opcoes := &slog.HandlerOptions{Level: &nivelLog}
+	var h slog.Handler
+	switch formato {
+	case "texto", "text":
+		h = slog.NewTextHandler(os.Stderr, opcoes)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opcoes)
+	default:
+		return fmt.Errorf("formato de log desconhecido %q (use texto ou json)", formato)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// registra o erro e encerra o processo
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// logger de uma conexão que ainda não tem jogador
+func loggerConexao(conn net.Conn) *slog.Logger {
+	return slog.With("remote_addr", conn.RemoteAddr().String())
+}
+
+// logger com os campos de correlação do jogador (sem travas; pode ser chamado com j.mu travado)
+func (j *Jogador) logger() *slog.Logger {
+	remoto := ""
+	if r := j.remoto.Load(); r != nil {
+		remoto = *r
+	}
+	return slog.With("player_id", j.ID, "jogador", j.Nome, "remote_addr", remoto)
+}
+
+// logger da partida; com j, inclui também os campos do jogador
+func (p *Partida) logger(j *Jogador) *slog.Logger {
+	if j == nil {
+		return slog.With("match_id", p.ID)
+	}
+	return j.logger().With("match_id", p.ID)
+}
+
+// logger de uma ação da partida; sem j (prazo do turno), só o ID do jogador da vez
+func (p *Partida) loggerAcao(j *Jogador, acao motor.Acao) *slog.Logger {
+	if j == nil {
+		return p.logger(nil).With("player_id", acao.Jogador, "acao", acao.Tipo)
+	}
+	return p.logger(j).With("acao", acao.Tipo)
+}
+
+// evita montar os campos de linhas de depuração que não seriam escritas
+func depurando() bool {
+	return nivelLog.Level() <= slog.LevelDebug
+}
+
+// /mod log [nivel]: mostra ou troca o nível dos logs (apenas admins)
+func tratarNivelLog(j *Jogador, args []string) {
+	if !j.temPapel(papelAdmin) {
+		j.enviarErro(erroSemPermissao, "apenas admins podem alterar os logs")
+		return
+	}
+	if len(args) == 0 {
+		j.enviarMensagem(fmt.Sprintf("Nível dos logs: %s (use /mod log debug|info|aviso|erro)", nivelLog.Level()))
+		return
+	}
+	n, err := interpretarNivel(args[0])
+	if err != nil {
+		j.enviarMensagem(err.Error())
+		return
+	}
+	anterior := nivelLog.Level()
+	nivelLog.Set(n)
+	// no maior entre info e o novo nível, para a troca aparecer mesmo em "erro"
+	j.logger().Log(context.Background(), max(n, slog.LevelInfo), "nível dos logs alterado", "de", anterior.String(), "para", n.String())
+	j.enviarMensagem(fmt.Sprintf("Nível dos logs: %s → %s", anterior, n))
+}
@@ -3,240 +3,392 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"math/rand"
 	"net"
-	"strconv"
+	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/comando"
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
 )
 
-// representa uma ação enviada pelo jogador (JSON)
-type AcaoJogo struct {
-	Acao   string `json:"acao"`          // tipo da ação, ex: "jogar_carta", "fim_turno"
-	CartaID int   `json:"carta_id,omitempty"` // id da carta, se aplicável
-}
+// representa uma ação enviada pelo jogador (JSON), decodificada por comando.DecodificarAcao
+type AcaoJogo = comando.Acao
 
 // representa um jogador conectado
 type Jogador struct {
-	ID        string
-	Nome      string
-	Conexao   net.Conn      // conexão TCP com o jogador
-	Saida     chan string   // canal para enviar mensagens ao jogador
-	EmPartida bool          // se está em uma partida
-	EnderecoUDP string       // endereço UDP do jogador (para ping)
-	mu        sync.Mutex    // mutex para proteger campos como EmPartida
-	UltimoPing time.Duration // último ping registrado
+	ID                 string
+	Nome               string
+	Conexao            net.Conn               // conexão TCP com o jogador
+	Saida              *FilaSaida             // fila de mensagens a enviar ao jogador (saida.go)
+	EmPartida          bool                   // se está em uma partida
+	NaFila             bool                   // se está na fila de matchmaking
+	EnderecoUDP        string                 // endereço UDP de onde chegam os pings do jogador
+	mu                 sync.Mutex             // mutex para proteger campos como EmPartida
+	UltimoPing         time.Duration          // último ping registrado
+	TokenSessao        string                 // credencial para retomar a conexão; nunca sai da conexão TCP
+	IDPing             string                 // identifica o jogador nos pings UDP, que viajam sem criptografia
+	Ping               EstatisticasPing       // RTT, jitter e perda medidos pelo servidor
+	Assistindo         string                 // ID da partida que o jogador assiste como espectador
+	CanalAtivo         string                 // canal que recebe as mensagens de chat sem comando
+	Papel              string                 // "", "moderador" ou "admin" (concedido por /autenticar)
+	desconectado       bool                   // fila de saída já foi fechada
+	suspenso           bool                   // conexão caiu durante uma partida; aguardando a retomada
+	removido           bool                   // saiu do servidor; a sessão não pode mais ser retomada
+	expulso            bool                   // expulso por um admin; a queda não suspende a sessão
+	expiracaoReconexao *time.Timer            // remove o jogador suspenso ao fim do prazo de reconexão
+	remoto             atomic.Pointer[string] // endereço da conexão atual, para os logs (logs.go)
 }
 
-// representa uma partida entre dois jogadores
+// representa uma partida entre dois jogadores; o estado é lido e alterado apenas
+// pela goroutine da partida (partida.go)
 type Partida struct {
-	ID      string
-	A, B    *Jogador        // jogadores da partida
-	Turno   string           // ID do jogador que tem a vez
-	Criada  time.Time        // timestamp da criação
-	mu      sync.Mutex       // mutex para proteger o estado da partida
-	Mao     map[string][]int // cartas na mão dos jogadores (ID jogador -> cartas)
-	Vida    map[string]int   // vida dos jogadores (ID jogador -> vida)
-}
-
-// representa um pacote booster de cartas
-type PacoteBooster struct {
-	ID    string
-	Cartas []string // lista de cartas do pacote
+	ID           string
+	A, B         *Jogador             // jogadores da partida
+	Criada       time.Time            // timestamp da criação
+	motor.Estado                      // vida, mãos e turno, alterados apenas por motor.Aplicar
+	Espectadores map[string]*Jogador  // espectadores inscritos (ID jogador -> jogador)
+	atrasados    filaAtrasada         // mensagens aguardando o atraso para os espectadores
+	vidas        []vidaMarcada        // vidas após cada ação, para o estado atrasado dos espectadores
+	Eventos      []EventoPartida      // log ordenado de eventos da partida
+	Chat         []MensagemChat       // histórico do chat da partida
+	Semente      int64                // semente do gerador aleatório da partida
+	rng          *rand.Rand           // gerador próprio da partida (usado pela goroutine da partida)
+	Privada      bool                 // partida entre amigos, visível apenas para os amigos dos jogadores
+	entrada      chan mensagemPartida // mensagens para a goroutine da partida
+	encerrada    chan struct{}        // fechado quando a goroutine da partida termina
+	relogio      *time.Timer          // prazo do turno atual
+	terminou     bool                 // a partida acabou; a goroutine sai do laço
+
+	// métricas de missões das jogadas, aplicadas quando a partida termina (nome -> métrica -> valor)
+	missoes map[string]map[string]int
 }
 
 // Variáveis globais do servidor
 var (
-	jogadoresMu sync.Mutex
-	jogadores   = map[string]*Jogador{} // mapa de ID -> jogador
-
-	filaPartida   = make(chan *Jogador, 100) // fila de matchmaking
-	partidasAtivas = map[string]*Partida{}   // partidas em andamento
-	partidasMu     sync.Mutex                // mutex para proteger partidasAtivas
+	filaMu      sync.Mutex
+	filaPartida []*EntradaFila           // fila de matchmaking, em ordem de chegada
+	avisoFila   = make(chan struct{}, 1) // acorda o matchmaking quando alguém entra na fila
+)
 
-	// inventário de boosters
-	boostersMu sync.Mutex
-	boosters   []*PacoteBooster
+func main() {
+	flag.DurationVar(&atrasoEspectador, "atraso-espectador", 0, "atraso dos eventos enviados aos espectadores")
+	flag.StringVar(&diretorioReplays, "replays", diretorioReplays, "diretório onde os replays das partidas são gravados")
+	flag.Int64Var(&sementeBoosters, "semente-boosters", 0, "semente do gerador de boosters (0 = aleatória)")
+	flag.IntVar(&estoqueBoosters, "boosters-estoque", estoqueBoosters, "estoque de boosters mantido pelas reposições")
+	flag.DurationVar(&reposicaoBoosters, "boosters-reposicao", reposicaoBoosters, "intervalo da reposição automática de boosters (0 desativa)")
+	flag.StringVar(&diretorioDados, "dados", diretorioDados, "diretório dos dados persistentes (estatísticas, rankings)")
+	flag.DurationVar(&prazoWO, "torneio-wo", prazoWO, "tempo de espera por um jogador de torneio antes do W.O.")
+	flag.DurationVar(&prazoTroca, "troca-prazo", prazoTroca, "tempo máximo de uma sessão de troca")
+	flag.StringVar(&tokenAdmin, "token-admin", os.Getenv("LOBBY_TOKEN_ADMIN"), "token que concede o papel de admin via /autenticar")
+	flag.StringVar(&tokenModerador, "token-moderador", os.Getenv("LOBBY_TOKEN_MODERADOR"), "token que concede o papel de moderador via /autenticar")
+	flag.StringVar(&enderecoAdminHTTP, "admin-http", enderecoAdminHTTP, "endereço da API HTTP de admin, autenticada pelo token de admin (vazio desativa)")
+	flag.StringVar(&enderecoMetricas, "metricas", enderecoMetricas, "endereço HTTP do endpoint /metrics do Prometheus (vazio desativa)")
+	flag.StringVar(&enderecoUDP, "udp", enderecoUDP, "endereço do respondedor UDP de ping")
+	flag.DurationVar(&intervaloHeartbeat, "heartbeat", intervaloHeartbeat, "intervalo entre os heartbeats enviados aos clientes")
+	flag.DurationVar(&prazoHeartbeat, "heartbeat-prazo", prazoHeartbeat, "tempo sem receber nada do cliente até a conexão ser considerada morta")
+	flag.DurationVar(&prazoEscrita, "escrita-prazo", prazoEscrita, "tempo máximo de uma escrita na conexão do cliente")
+	flag.DurationVar(&prazoReconexao, "reconexao", prazoReconexao, "tempo para retomar a sessão após cair durante uma partida (0 desativa)")
+	flag.DurationVar(&duracaoTurno, "turno", duracaoTurno, "tempo de cada turno; ao fim dele a vez passa para o oponente (0 desativa)")
+	flag.IntVar(&limiteDescartaveis, "saida-descartaveis", limiteDescartaveis, "mensagens descartáveis (chat, avisos) pendentes por cliente antes de descartar as mais antigas")
+	flag.IntVar(&limiteCriticas, "saida-criticas", limiteCriticas, "mensagens críticas pendentes por cliente antes de desconectá-lo por lentidão")
+	flag.DurationVar(&prazoLentidao, "saida-prazo", prazoLentidao, "tempo máximo de uma mensagem crítica na fila antes de desconectar o cliente")
+	flag.BoolVar(&limitesAtivos, "limites", limitesAtivos, "limita a taxa de mensagens por conexão e por IP e bane IPs abusivos")
+	flag.Float64Var(&limiteIPFator, "limite-ip-fator", limiteIPFator, "orçamento de mensagens de um IP, em múltiplos do orçamento de uma conexão (0 desativa)")
+	flag.IntVar(&conexoesPorIP, "ip-conexoes", conexoesPorIP, "conexões simultâneas por IP (0 = sem limite)")
+	flag.DurationVar(&prazoNome, "nome-prazo", prazoNome, "tempo para o cliente enviar o nome ao conectar")
+	flag.IntVar(&violacoesBanimento, "abuso-violacoes", violacoesBanimento, "violações de limite em um minuto que banem o IP")
+	flag.DurationVar(&duracaoBanimentoIP, "abuso-banimento", duracaoBanimentoIP, "duração do banimento de um IP abusivo")
+	flag.IntVar(&tamanhoLinha, "linha-max", tamanhoLinha, "tamanho máximo, em bytes, de uma linha recebida do cliente")
+	flag.StringVar(&certificadoTLS, "tls-cert", "", "certificado PEM do lobby; com -tls-chave, o lobby passa a aceitar apenas TLS")
+	flag.StringVar(&chaveTLS, "tls-chave", "", "chave privada PEM do certificado do lobby")
+	flag.StringVar(&caClientesTLS, "tls-ca", "", "CA PEM dos clientes; exige certificado de cliente assinado por ela (TLS mútuo)")
+	flag.StringVar(&arquivoFiltro, "filtro", "", "arquivo com as palavras filtradas do chat (padrão: <dados>/palavras_proibidas.txt)")
+	formatoLog := flag.String("log-formato", "texto", "formato dos logs: texto ou json")
+	nivelInicial := flag.String("log-nivel", "info", "nível mínimo dos logs: debug, info, aviso ou erro (alterável com /mod log)")
+	flag.Parse()
+	if err := configurarLogs(*formatoLog, *nivelInicial); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	cartasDisponiveis = map[int]string{} // catálogo de cartas do jogo
+	// subcomando: servidor replay <arquivo.jsonl>
+	if flag.Arg(0) == "replay" {
+		if flag.NArg() < 2 {
+			fatal("uso: servidor replay <arquivo.jsonl>")
+		}
+		if err := executarReplay(flag.Arg(1)); err != nil {
+			fatal("erro no replay", "erro", err)
+		}
+		return
+	}
 
-	// gerador de números aleatórios
-	rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
-)
+	// subcomando: servidor gerar-certificado [diretório] [hosts]
+	if flag.Arg(0) == "gerar-certificado" {
+		if err := gerarCertificado(flag.Arg(1), flag.Arg(2)); err != nil {
+			fatal("erro ao gerar certificados", "erro", err)
+		}
+		return
+	}
 
-func main() {
-	rand.Seed(time.Now().UnixNano())
+	// subcomando: servidor conciliar
+	if flag.Arg(0) == "conciliar" {
+		if err := executarConciliacao(); err != nil {
+			fatal("erro na conciliação", "erro", err)
+		}
+		return
+	}
 
 	// Inicializa boosters e cartas
-	prepararBoosters(50) // cria 50 pacotes booster
-	inicializarCartas()  // inicializa catálogo de cartas
+	if err := inicializarCartas(); err != nil {
+		fatal("erro ao carregar o catálogo de cartas", "erro", err)
+	}
+	if err := iniciarBoosters(); err != nil {
+		fatal("erro ao carregar auditoria de boosters", "erro", err)
+	}
+	if err := carregarEstatisticas(); err != nil {
+		fatal("erro ao carregar estatísticas", "erro", err)
+	}
+	if err := carregarModeracao(); err != nil {
+		fatal("erro ao carregar dados de moderação", "erro", err)
+	}
+	if err := carregarAmigos(); err != nil {
+		fatal("erro ao carregar amigos", "erro", err)
+	}
+	if err := carregarContas(); err != nil {
+		fatal("erro ao carregar contas", "erro", err)
+	}
+	if err := carregarEconomia(); err != nil {
+		fatal("erro ao carregar transações", "erro", err)
+	}
+	if err := carregarColecoes(); err != nil {
+		fatal("erro ao carregar coleções", "erro", err)
+	}
+	if err := carregarMissoes(); err != nil {
+		fatal("erro ao carregar missões", "erro", err)
+	}
+
+	// grava os arquivos pendentes ao receber SIGINT/SIGTERM
+	go aguardarEncerramento()
 
 	// Inicia respondedor de ping UDP
-	go iniciarRespondedorUDP(":4001")
+	go iniciarRespondedorUDP(enderecoUDP)
+	if enderecoMetricas != "" {
+		go servirMetricas(enderecoMetricas)
+	}
+	if enderecoAdminHTTP != "" {
+		go servirAdminHTTP(enderecoAdminHTTP)
+	}
 
-	// Inicia servidor TCP do lobby
-	ln, err := net.Listen("tcp", ":4000")
+	// Inicia servidor TCP do lobby (com TLS, se configurado)
+	ln, modo, err := escutarLobby(":4000")
 	if err != nil {
-		log.Fatal("Erro ao escutar:", err)
+		fatal("erro ao escutar", "erro", err)
 	}
 	defer ln.Close()
-	log.Println("Servidor TCP do lobby ouvindo em :4000")
+	slog.Info("lobby ouvindo", "endereco", ":4000", "protocolo", modo)
 
 	// Loop de matchmaking para criar partidas
 	go loopPartidas()
+	go loopTorneios()
+	go loopLimpezaIPs()
 
 	// Loop principal de aceitação de conexões TCP
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
-			log.Println("Erro ao aceitar conexão:", err)
+			slog.Error("erro ao aceitar conexão", "erro", err)
 			continue
 		}
 		go lidarConexao(conn) // trata cada jogador em goroutine separada
 	}
 }
 
-// cria o catálogo de cartas do jogo com raridades
-func inicializarCartas() {
-	for i := 1; i <= 20; i++ {
-		raridade := "Comum"
-		if i <= 5 {
-			raridade = "Rara"
-		} else if i <= 10 {
-			raridade = "Incomum"
-		}
-		cartasDisponiveis[i] = fmt.Sprintf("Carta %d (%s)", i, raridade)
-	}
-	log.Printf("Inicializado catálogo de %d cartas\n", len(cartasDisponiveis))
-}
-
-// gera pacotes booster com cartas aleatórias
-func prepararBoosters(n int) {
-	boostersMu.Lock()
-	defer boostersMu.Unlock()
-	for i := 0; i < n; i++ {
-		id := fmt.Sprintf("booster-%04d", i+1)
-		cartas := []string{
-			fmt.Sprintf("C%03d-R", rnd.Intn(100)), // carta rara
-			fmt.Sprintf("C%03d-U", rnd.Intn(200)), // carta incomum
-			fmt.Sprintf("C%03d-C", rnd.Intn(300)), // carta comum
-		}
-		boosters = append(boosters, &PacoteBooster{ID: id, Cartas: cartas})
-	}
-	log.Printf("Preparados %d boosters\n", len(boosters))
-}
-
-// inicia um servidor UDP que responde "pong" a pings
-func iniciarRespondedorUDP(endereco string) {
-	pc, err := net.ListenPacket("udp", endereco)
-	if err != nil {
-		log.Fatal("Erro ao iniciar UDP:", err)
-	}
-	defer pc.Close()
-	log.Println("Respondedor UDP ouvindo em", endereco)
-	buf := make([]byte, 1024)
-	for {
-		n, raddr, err := pc.ReadFrom(buf)
-		if err != nil {
-			log.Println("Erro ao ler UDP:", err)
-			continue
-		}
-		payload := strings.TrimSpace(string(buf[:n]))
-		_ = payload
-		_, _ = pc.WriteTo([]byte("pong\n"), raddr) // responde "pong"
-	}
-}
-
 // gerencia a conexão TCP de um jogador, leitura de comandos e mensagens
 func lidarConexao(conn net.Conn) {
 	defer conn.Close()
+	if aviso := entrarIP(conn); aviso != "" {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		conn.Write([]byte(aviso + "\n"))
+		return
+	}
+	defer sairIP(conn)
+	if err := concluirTLS(conn); err != nil {
+		loggerConexao(conn).Warn("aperto de mão TLS recusado", "erro", err)
+		return
+	}
 	reader := bufio.NewReader(conn)
 
-	// solicita nome do jogador
-	nomeLinha, err := reader.ReadString('\n')
+	// solicita nome do jogador (ou "retomar <token>" para voltar a uma sessão); o prazo
+	// curto derruba quem abre a conexão e nunca termina a linha
+	conn.SetReadDeadline(time.Now().Add(prazoNome))
+	nomeLinha, err := comando.LerLinha(reader, tamanhoLinha)
+	if errors.Is(err, comando.ErrLinhaLonga) {
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: nome muito longo\n", erroLinhaLonga)))
+		return
+	}
 	if err != nil {
-		log.Println("Erro ao ler nome:", err)
+		loggerConexao(conn).Debug("conexão fechada antes do nome", "erro", err)
 		return
 	}
 	nome := strings.TrimSpace(nomeLinha)
-	if nome == "" {
-		nome = "Jogador"
+	if token, ok := strings.CutPrefix(nome, "retomar "); ok {
+		retomarSessao(conn, reader, strings.TrimSpace(token))
+		return
 	}
 	jogadorID := fmt.Sprintf("%d", time.Now().UnixNano()) // ID único baseado em timestamp
+	if nome == "" {
+		nome = "Jogador-" + jogadorID[len(jogadorID)-6:]
+	}
+	if ate := banidoAte(nome); !ate.IsZero() {
+		vincularIP(nome, enderecoIP(conn))
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: você está banido até %s\n", erroBanido, ate.Format("02/01 15:04"))))
+		return
+	}
+
+	// senha na linha seguinte ao nome; na primeira entrada ela passa a proteger o nome
+	// (contas.go), que identifica saldos, coleção e trocas
+	senhaLinha, err := comando.LerLinha(reader, tamanhoLinha)
+	if errors.Is(err, comando.ErrLinhaLonga) {
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: senha muito longa\n", erroLinhaLonga)))
+		return
+	}
+	if err != nil {
+		loggerConexao(conn).Debug("conexão fechada antes da senha", "erro", err)
+		return
+	}
+	if espera := permitirEntrada(enderecoIP(conn)); espera > 0 {
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: entrada: muitas tentativas, tente de novo em %s\n", erroLimite, espera)))
+		return
+	}
+	contaCriada, err := autenticarConta(nome, strings.TrimSpace(senhaLinha), enderecoIP(conn))
+	if err != nil {
+		codigo := erroSenhaIncorreta
+		switch {
+		case errors.Is(err, errSenhaBloqueada):
+			codigo = erroSenhaBloqueada
+		case errors.Is(err, errLimiteContas):
+			codigo = erroLimiteContas
+		}
+		loggerConexao(conn).Warn("entrada recusada", "jogador", nome, "erro", err)
+		conn.Write([]byte(fmt.Sprintf("ERRO %s: %v\n", codigo, err)))
+		return
+	}
 
 	// cria estrutura do jogador
 	j := &Jogador{
-		ID:          jogadorID,
-		Nome:        nome,
-		Conexao:     conn,
-		Saida:       make(chan string, 10),
-		EmPartida:   false,
-		EnderecoUDP: "localhost:4001",
+		ID:        jogadorID,
+		Nome:      nome,
+		Conexao:   conn,
+		Saida:     novaFilaSaida(),
+		EmPartida: false,
 	}
+	remoto := conn.RemoteAddr().String()
+	j.remoto.Store(&remoto)
 
-	// adiciona jogador à lista global
-	jogadoresMu.Lock()
-	jogadores[j.ID] = j
-	jogadoresMu.Unlock()
+	// adiciona jogador à lista global; o nome identifica o jogador (sussurros, torneios, perfis)
+	if !registrarJogador(j) {
+		conn.Write([]byte("Nome já está em uso, conecte-se com outro nome\n"))
+		return
+	}
+	registrarTokenSessao(j)
+	vincularIP(nome, enderecoIP(conn))
+	j.logger().Info("jogador conectado")
 
-	j.enviarMensagem(fmt.Sprintf("Ping UDP: %s\n", j.EnderecoUDP))
-	j.enviarMensagem("Comandos: /entrar, /sair, /jogar <idCarta>, /mao, /cartas, /fim, /booster, ou mensagens de chat\n")
+	if contaCriada {
+		j.enviarMensagem(fmt.Sprintf("Conta criada: a senha enviada agora protege o nome %s\n", nome))
+	}
+	j.enviarMensagem(fmt.Sprintf("Ping UDP: %s id %s\n", enderecoUDP, j.IDPing))
+	j.enviarMensagem(fmt.Sprintf("Sessão: para reconectar após uma queda durante uma partida, envie \"retomar %s\" no lugar do nome (prazo %s)\n", j.TokenSessao, prazoReconexao))
+	j.enviarMensagem("Comandos: /entrar, /sair, /jogar <idCarta>, /mao, /cartas, /fim, /ping, /booster, /loja, /saldo, /extrato, /colecao, /desencantar, /criar, /missoes, /troca, /partidas, /assistir <id>, /perfil [nome], /ranking [temporada] [pagina], /torneio, /canal, /sussurrar <nome> <msg>, /bloquear <nome>, /denunciar <nome>, /amigo, ou mensagens de chat\n")
 
 	// goroutine que envia mensagens ao jogador
-	go escritorJogador(j)
+	go escritorJogador(j, conn, j.Saida)
+	entrarCanal(j, canalGeral)
+	avisarAmigosAoConectar(j)
+	recompensarLoginDiario(j)
 
 	// loop de leitura de mensagens do jogador
-	for {
-		linha, err := reader.ReadString('\n')
-		if err != nil {
-			log.Printf("Jogador %s desconectou: %v\n", j.Nome, err)
-			removerJogador(j)
+	lerComandos(j, conn, reader)
+}
+
+// código de erro enviado quando a linha não segue a gramática dos comandos (internal/comando)
+const erroComandoInvalido = "comando_invalido"
+
+// trata uma linha recebida do jogador: comando, ação JSON ou chat; cada uma gasta uma
+// ficha da sua categoria (limites.go)
+func tratarLinha(j *Jogador, lim *limitesConexao, linha string) {
+	if linha == "/" || linha == "" {
+		return
+	}
+
+	// comandos iniciados por "/"
+	if strings.HasPrefix(linha, "/") {
+		if !lim.permitir(j, categoriaDoComando(linha)) {
 			return
 		}
-		linha = strings.TrimSpace(linha)
-		if linha == "/" {
-			continue
+		c, err := comando.Analisar(linha)
+		if err != nil {
+			j.enviarErro(erroComandoInvalido, err.Error())
+			return
 		}
+		tratarComando(j, c)
+		return
+	}
 
-		// comandos iniciados por "/"
-		if strings.HasPrefix(linha, "/") {
-			tratarComando(j, linha)
-			continue
+	// ações em JSON
+	if strings.HasPrefix(linha, "{") {
+		acao, err := comando.DecodificarAcao(linha)
+		if !lim.permitir(j, categoriaDaAcao(acao.Acao)) {
+			return
 		}
-
-		// ações em JSON
-		if strings.HasPrefix(linha, "{") {
-			var acao AcaoJogo
-			if err := json.Unmarshal([]byte(linha), &acao); err != nil {
-				j.enviarMensagem("Ação inválida (JSON incorreto)\n")
-				continue
-			}
-			tratarAcao(j, acao)
-		} else {
-			// mensagem de chat normal
-			transmitir(fmt.Sprintf("[%s] %s", j.Nome, linha), j)
+		if err != nil {
+			j.enviarErro(erroComandoInvalido, "ação inválida: "+err.Error())
+			return
+		}
+		tratarAcao(j, acao)
+	} else {
+		// mensagem de chat: partida jogada/assistida ou canal ativo
+		if lim.permitir(j, catChat) {
+			enviarChat(j, "", linha)
 		}
 	}
 }
 
-// envia uma mensagem para o jogador sem travar caso o canal esteja cheio
-func (j *Jogador) enviarMensagem(msg string) {
-	select {
-	case j.Saida <- msg:
-	default:
-	}
-}
-
-// escreve continuamente mensagens do canal para a conexão TCP
-func escritorJogador(j *Jogador) {
-	for msg := range j.Saida {
-		_, err := j.Conexao.Write([]byte(msg + "\n"))
-		if err != nil {
-			log.Println("Erro ao escrever para", j.ID, err)
+// escreve as mensagens da fila de saída na conexão TCP, em lotes, intercalando heartbeats;
+// uma escrita que falha ou estoura o prazo derruba a conexão, o que encerra a leitura
+func escritorJogador(j *Jogador, conn net.Conn, saida *FilaSaida) {
+	heartbeat := time.NewTicker(intervaloHeartbeat)
+	defer heartbeat.Stop()
+	seq := 0
+	for {
+		var mensagens []string
+		fechada := false
+		select {
+		case <-saida.sinal:
+			mensagens, fechada = saida.retirar()
+		case <-heartbeat.C:
+			seq++
+			mensagens = []string{fmt.Sprintf("HEARTBEAT %d", seq)}
+		}
+		if len(mensagens) > 0 {
+			conn.SetWriteDeadline(time.Now().Add(prazoEscrita))
+			if _, err := conn.Write([]byte(strings.Join(mensagens, "\n") + "\n")); err != nil {
+				j.logger().Debug("erro ao escrever para o jogador", "erro", err)
+				conn.Close()
+				return
+			}
+		}
+		if fechada {
 			return
 		}
 	}
@@ -244,29 +396,27 @@ func escritorJogador(j *Jogador) {
 
 // remove o jogador da lista global e encerra partidas ativas se necessário
 func removerJogador(j *Jogador) {
-	jogadoresMu.Lock()
-	delete(jogadores, j.ID)
-	jogadoresMu.Unlock()
-	partidasMu.Lock()
-	for mid, p := range partidasAtivas {
-		if p.A.ID == j.ID || p.B.ID == j.ID {
-			var oponente *Jogador
-			if p.A.ID == j.ID {
-				oponente = p.B
-			} else {
-				oponente = p.A
-			}
-			if oponente != nil {
-				oponente.enviarMensagem("Oponente desconectou, partida encerrada")
-				oponente.mu.Lock()
-				oponente.EmPartida = false
-				oponente.mu.Unlock()
-			}
-			delete(partidasAtivas, mid)
-		}
+	j.mu.Lock()
+	j.removido = true
+	if j.suspenso {
+		j.suspenso = false
+		j.expiracaoReconexao.Stop()
 	}
-	partidasMu.Unlock()
-	close(j.Saida) // fecha canal de saída do jogador
+	j.mu.Unlock()
+	removerRegistroJogador(j)
+	removerTokenSessao(j)
+	sairFila(j)
+	pararDeAssistir(j)
+	sairDeTodosCanais(j)
+	cancelarTroca(j.Nome, fmt.Sprintf("%s desconectou", j.Nome))
+	notificarPresenca(j.Nome)
+	// se a partida já terminou (a vitória pode ter chegado antes), não há o que encerrar
+	if p := encontrarPartidaPorJogador(j.ID); p != nil {
+		p.desconectar(j)
+	}
+	j.mu.Lock()
+	j.fecharSaida() // encerra o escritor do jogador
+	j.mu.Unlock()
 }
 
 // exibe as cartas na mão do jogador
@@ -276,21 +426,24 @@ func mostrarMao(j *Jogador) {
 		j.enviarMensagem("Você não está em uma partida")
 		return
 	}
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	mao := p.Mao[j.ID]
 	var builder strings.Builder
-	builder.WriteString("Sua mão:\n")
-	for _, cid := range mao {
-		builder.WriteString(fmt.Sprintf("  [%d] %s\n", cid, cartasDisponiveis[cid]))
+	ok := p.executar(func(p *Partida) {
+		builder.WriteString("Sua mão:\n")
+		for _, cid := range p.Mao[j.ID] {
+			builder.WriteString(fmt.Sprintf("  [%d] %s\n", cid, nomeCarta(cid)))
+		}
+	})
+	if !ok {
+		j.enviarMensagem("Você não está em uma partida")
+		return
 	}
 	j.enviarMensagem(builder.String())
 }
 
-// interpreta comandos de texto do jogador
-func tratarComando(j *Jogador, linha string) {
-	switch {
-	case linha == "/entrar":
+// executa um comando de texto já validado pela gramática (internal/comando)
+func tratarComando(j *Jogador, c comando.Comando) {
+	switch c.Nome {
+	case "entrar":
 		// entra na fila de partidas
 		j.mu.Lock()
 		if j.EmPartida {
@@ -298,100 +451,297 @@ func tratarComando(j *Jogador, linha string) {
 			j.enviarMensagem("Você já está em uma partida")
 			return
 		}
+		j.NaFila = true
 		j.mu.Unlock()
-		select {
-		case filaPartida <- j:
-			j.enviarMensagem("Entrou na fila de partidas...")
-		default:
-			j.enviarMensagem("Fila cheia, tente mais tarde")
+		if !entrarFila(j) {
+			j.enviarMensagem("Você já está na fila")
+			return
+		}
+		j.enviarMensagem("Entrou na fila de partidas...")
+		notificarPresenca(j.Nome)
+	case "sair":
+		if pararDeAssistir(j) {
+			j.enviarMensagem("Você deixou de assistir a partida")
+			return
+		}
+		if !sairFila(j) {
+			j.enviarMensagem("Você não está na fila")
+			return
 		}
-	case linha == "/sair":
-		j.enviarMensagem("Você saiu da fila (não implementado)")
+		j.mu.Lock()
+		j.NaFila = false
+		j.mu.Unlock()
+		j.enviarMensagem("Você saiu da fila")
+		notificarPresenca(j.Nome)
+
+	case "heartbeat":
+		// resposta do cliente ao heartbeat; a leitura da linha já renovou o prazo
+
+	case "ping":
+		mostrarPing(j)
+
+	case "partidas":
+		listarPartidas(j)
 
-	case linha == "/mao":
+	case "assistir":
+		assistirPartida(j, c.Arg(0))
+
+	case "mao":
 		mostrarMao(j)
 
-	case linha == "/cartas":
+	case "cartas":
 		var builder strings.Builder
 		builder.WriteString("Cartas do jogo:\n")
-		for id, nome := range cartasDisponiveis {
+		for id, nome := range cartasDisponiveis() {
 			builder.WriteString(fmt.Sprintf("  [%d] %s\n", id, nome))
 		}
 		j.enviarMensagem(builder.String())
 
-	case strings.HasPrefix(linha, "/jogar "):
-		partes := strings.Split(linha, " ")
-		if len(partes) < 2 {
-			j.enviarMensagem("Uso: /jogar <id_carta>")
-			return
-		}
-		cartaID, err := strconv.Atoi(partes[1])
-		if err != nil {
-			j.enviarMensagem("ID da carta inválido.")
-			return
+	case "jogar":
+		tratarAcao(j, AcaoJogo{Acao: "jogar_carta", CartaID: c.Inteiro(0)})
+
+	case "fim":
+		tratarAcao(j, AcaoJogo{Acao: "fim_turno"})
+
+	case "perfil":
+		mostrarPerfil(j, c.Arg(0))
+
+	case "ranking":
+		mostrarRanking(j, c.Args)
+
+	case "torneio":
+		tratarTorneio(j, c.Args)
+
+	case "canal":
+		tratarCanal(j, c.Args)
+
+	case "sussurrar":
+		sussurrar(j, c.Arg(0), c.Resto(1))
+
+	case "autenticar":
+		autenticar(j, c.Arg(0))
+
+	case "bloquear":
+		bloquearJogador(j, c.Arg(0))
+
+	case "desbloquear":
+		desbloquearJogador(j, c.Arg(0))
+
+	case "bloqueados":
+		listarBloqueados(j)
+
+	case "denunciar":
+		denunciarJogador(j, c.Arg(0), c.Resto(1))
+
+	case "amigo":
+		tratarAmigo(j, c.Args)
+
+	case "mod":
+		tratarModeracao(j, c.Args)
+
+	case "admin":
+		tratarAdmin(j, c.Args)
+
+	case "booster":
+		comprarBooster(j, colecaoBasica)
+
+	case "loja":
+		tratarLoja(j, c.Args)
+
+	case "saldo":
+		mostrarSaldo(j)
+
+	case "extrato":
+		mostrarExtrato(j)
+
+	case "colecao":
+		mostrarColecao(j)
+
+	case "desencantar":
+		desencantarCarta(j, c.Args)
+
+	case "criar":
+		criarCarta(j, c.Args)
+
+	case "missoes":
+		tratarMissoes(j, c.Args)
+
+	case "troca":
+		tratarTroca(j, c.Args)
+
+	default:
+		// a gramática aceitou um comando que ninguém trata
+		j.enviarErro(erroComandoInvalido, "comando desconhecido: /"+c.Nome)
+	}
+}
+
+// jogador aguardando na fila de matchmaking
+type EntradaFila struct {
+	Jogador *Jogador
+	Entrada time.Time
+}
+
+// diferença de latência aceita entre adversários, que cresce com a espera na fila
+const (
+	toleranciaPingBase  = 60 * time.Millisecond
+	toleranciaPingPasso = 30 * time.Millisecond // acréscimo a cada intervaloTolerancia de espera
+	intervaloTolerancia = 5 * time.Second
+	esperaSemRestricao  = 30 * time.Second // após essa espera qualquer adversário serve
+)
+
+// coloca o jogador no fim da fila; falso se ele já estava nela
+func entrarFila(j *Jogador) bool {
+	filaMu.Lock()
+	for _, e := range filaPartida {
+		if e.Jogador == j {
+			filaMu.Unlock()
+			return false
 		}
-		tratarAcao(j, AcaoJogo{Acao: "jogar_carta", CartaID: cartaID})
+	}
+	filaPartida = append(filaPartida, &EntradaFila{Jogador: j, Entrada: time.Now()})
+	filaMu.Unlock()
+	select {
+	case avisoFila <- struct{}{}:
+	default:
+	}
+	return true
+}
 
-	case linha == "/booster":
-		pacote, ok := pegarBooster()
-		if !ok {
-			j.enviarMensagem("Não há boosters disponíveis")
-			return
+// retira o jogador da fila; falso se ele não estava nela
+func sairFila(j *Jogador) bool {
+	filaMu.Lock()
+	defer filaMu.Unlock()
+	for i, e := range filaPartida {
+		if e.Jogador == j {
+			filaPartida = append(filaPartida[:i], filaPartida[i+1:]...)
+			return true
 		}
-		j.enviarMensagem(fmt.Sprintf("Você abriu booster %s -> cartas: %v", pacote.ID, pacote.Cartas))
+	}
+	return false
+}
 
-	default:
-		j.enviarMensagem("Comando desconhecido")
+// devolve à fila quem perdeu o adversário, mantendo a posição pela hora de entrada
+func devolverFila(e *EntradaFila) {
+	filaMu.Lock()
+	i := sort.Search(len(filaPartida), func(k int) bool { return filaPartida[k].Entrada.After(e.Entrada) })
+	filaPartida = append(filaPartida, nil)
+	copy(filaPartida[i+1:], filaPartida[i:])
+	filaPartida[i] = e
+	filaMu.Unlock()
+}
+
+// diferença de latência aceita para quem espera na fila há `espera`
+func toleranciaPing(espera time.Duration) time.Duration {
+	if espera >= esperaSemRestricao {
+		return time.Duration(math.MaxInt64)
 	}
+	return toleranciaPingBase + time.Duration(espera/intervaloTolerancia)*toleranciaPingPasso
 }
 
 // realiza o matchmaking entre jogadores na fila
 func loopPartidas() {
+	ticker := time.NewTicker(time.Second)
 	for {
-		a := <-filaPartida
 		select {
-		case b := <-filaPartida:
-			a.mu.Lock()
-			if a.EmPartida {
-				a.mu.Unlock()
-				go func() { filaPartida <- b }()
+		case <-avisoFila:
+		case <-ticker.C:
+		}
+		for _, par := range parearFila() {
+			iniciarPareamento(par[0], par[1])
+		}
+	}
+}
+
+// retira da fila os pares de latência compatível: em ordem de chegada, cada jogador é pareado
+// com o adversário de latência mais próxima dentro da tolerância de quem espera há mais tempo;
+// jogadores sem medição de ping são compatíveis com qualquer um
+func parearFila() [][2]*EntradaFila {
+	filaMu.Lock()
+	defer filaMu.Unlock()
+
+	agora := time.Now()
+	latencias := make([]time.Duration, len(filaPartida))
+	medidas := make([]bool, len(filaPartida))
+	for i, e := range filaPartida {
+		e.Jogador.mu.Lock()
+		latencias[i], medidas[i] = e.Jogador.Ping.latenciaEfetiva()
+		e.Jogador.mu.Unlock()
+	}
+
+	pareado := make([]bool, len(filaPartida))
+	var pares [][2]*EntradaFila
+	for i := range filaPartida {
+		if pareado[i] {
+			continue
+		}
+		tolerancia := toleranciaPing(agora.Sub(filaPartida[i].Entrada))
+		melhor, menor := -1, time.Duration(0)
+		for k := i + 1; k < len(filaPartida); k++ {
+			if pareado[k] {
 				continue
 			}
-			a.EmPartida = true
-			a.mu.Unlock()
-
-			b.mu.Lock()
-			if b.EmPartida {
-				b.mu.Unlock()
-				a.mu.Lock()
-				a.EmPartida = false
-				a.mu.Unlock()
-				go func() { filaPartida <- a }()
-				continue
+			var diferenca time.Duration
+			if medidas[i] && medidas[k] {
+				diferenca = latencias[i] - latencias[k]
+				if diferenca < 0 {
+					diferenca = -diferenca
+				}
+			}
+			if diferenca <= tolerancia && (melhor < 0 || diferenca < menor) {
+				melhor, menor = k, diferenca
 			}
-			b.EmPartida = true
-			b.mu.Unlock()
-
-			criarPartida(a, b)
-		case <-time.After(30 * time.Second):
-			select {
-			case filaPartida <- a:
-			default:
+			if melhor >= 0 && menor == 0 {
+				break // ninguém fica mais próximo que isso
 			}
 		}
+		if melhor >= 0 {
+			pareado[i], pareado[melhor] = true, true
+			pares = append(pares, [2]*EntradaFila{filaPartida[i], filaPartida[melhor]})
+		}
+	}
+
+	restantes := make([]*EntradaFila, 0, len(filaPartida)-2*len(pares))
+	for i, e := range filaPartida {
+		if !pareado[i] {
+			restantes = append(restantes, e)
+		}
+	}
+	filaPartida = restantes
+	return pares
+}
+
+// marca os dois jogadores como em partida e a inicia; se um deles já entrou em outra
+// partida ou desconectou, o outro volta para a fila (ou é avisado, se a saída acontecer
+// durante a criação)
+func iniciarPareamento(a, b *EntradaFila) {
+	if !reservarParaPartida(a.Jogador) {
+		devolverFila(b)
+		return
+	}
+	if !reservarParaPartida(b.Jogador) {
+		a.Jogador.mu.Lock()
+		a.Jogador.EmPartida = false
+		a.Jogador.mu.Unlock()
+		devolverFila(a)
+		return
 	}
+	pararDeAssistir(a.Jogador)
+	pararDeAssistir(b.Jogador)
+	criarPartida(a.Jogador, b.Jogador, false)
 }
 
-// retorna uma mão aleatória de cartas do jogador
-func gerarMaoAleatoria(qtd int) []int {
+// retorna uma mão aleatória de cartas do jogador usando o gerador da partida
+func gerarMaoAleatoria(rng *rand.Rand, qtd int) []int {
 	mao := make([]int, 0, qtd)
-	ids := make([]int, 0, len(cartasDisponiveis))
-	for id := range cartasDisponiveis {
+	catalogo := cartasDisponiveis()
+	ids := make([]int, 0, len(catalogo))
+	for id := range catalogo {
 		ids = append(ids, id)
 	}
+	sort.Ints(ids) // ordem fixa para que a mesma semente gere a mesma mão
 
 	for i := 0; i < qtd; i++ {
-		idx := rnd.Intn(len(ids))
+		idx := rng.Intn(len(ids))
 		mao = append(mao, ids[idx])
 		// remover para não repetir cartas na mesma mão
 		ids = append(ids[:idx], ids[idx+1:]...)
@@ -402,196 +752,103 @@ func gerarMaoAleatoria(qtd int) []int {
 	return mao
 }
 
-// envia sinais periódicos para os jogadores da partida
-func rodarPartida(p *Partida) {
-	for {
-		time.Sleep(30 * time.Second)
-		partidasMu.Lock()
-		_, ok := partidasAtivas[p.ID]
-		partidasMu.Unlock()
-		if !ok {
-			return
+// inicializa uma nova partida entre dois jogadores; retorna nil se um deles saiu do
+// servidor antes do início
+func criarPartida(a, b *Jogador, privada bool) *Partida {
+	sairFila(a) // partidas de convites e torneios não passam pelo matchmaking
+	sairFila(b)
+	idPartida := fmt.Sprintf("partida-%d", time.Now().UnixNano())
+	p := novaPartida(idPartida, a, b, rand.Int63())
+	p.Privada = privada
+
+	// registrada antes de conferir as saídas: quem sair depois da conferência encontra a
+	// partida em removerJogador e a encerra; quem saiu antes é visto aqui
+	registrarPartida(p)
+	if saiu := jogadorRemovido(a, b); saiu != nil {
+		removerPartida(p)
+		close(p.encerrada) // ninguém fica esperando uma goroutine que não vai rodar
+		p.logger(nil).Info("partida cancelada antes do início", "jogador", saiu.ID)
+		for _, jog := range []*Jogador{a, b} {
+			jog.mu.Lock()
+			jog.EmPartida = false
+			jog.NaFila = false
+			jog.mu.Unlock()
+			if jog != saiu {
+				jog.enviarMensagem(fmt.Sprintf("%s desconectou antes do início da partida", saiu.Nome))
+			}
+			notificarPresenca(jog.Nome)
 		}
-		p.A.enviarMensagem(fmt.Sprintf("\nSinal da partida %s\n", p.ID))
-		p.B.enviarMensagem(fmt.Sprintf("\nSinal da partida %s\n", p.ID))
+		return nil
+	}
+	p.registrarInicio() // a goroutine da partida ainda não começou
+	go p.rodar()
+	p.logger(nil).Info("partida criada", "jogador_a", a.ID, "jogador_b", b.ID, "privada", privada, "semente", p.Semente)
+
+	a.enviarMensagem(fmt.Sprintf("\n============================\nVocê foi pareado com %s!\nID da partida: %s\nVida inicial: %d\n============================", b.Nome, idPartida, motor.VidaInicial))
+	b.enviarMensagem(fmt.Sprintf("\n============================\nVocê foi pareado com %s!\nID da partida: %s\nVida inicial: %d\n============================", a.Nome, idPartida, motor.VidaInicial))
+	for _, jog := range []*Jogador{a, b} {
+		jog.mu.Lock()
+		jog.NaFila = false
+		jog.mu.Unlock()
+		notificarPresenca(jog.Nome)
 	}
+
+	return p
 }
 
-// envia mensagem para todos jogadores fora de partidas
-func transmitir(msg string, origem *Jogador) {
-	jogadoresMu.Lock()
-	defer jogadoresMu.Unlock()
-	for _, j := range jogadores {
-		j.mu.Lock()
-		emPartida := j.EmPartida
-		j.mu.Unlock()
-		if !emPartida && j.ID != origem.ID {
-			j.enviarMensagem(msg)
+// primeiro dos jogadores que já saiu do servidor (nil se os dois estão nele)
+func jogadorRemovido(jogadores ...*Jogador) *Jogador {
+	for _, jog := range jogadores {
+		jog.mu.Lock()
+		removido := jog.removido
+		jog.mu.Unlock()
+		if removido {
+			return jog
 		}
 	}
+	return nil
 }
 
-// inicializa uma nova partida entre dois jogadores
-func criarPartida(a, b *Jogador) {
-	idPartida := fmt.Sprintf("partida-%d", time.Now().UnixNano())
+// monta o estado inicial de uma partida, distribuindo as mãos a partir da semente
+func novaPartida(id string, a, b *Jogador, semente int64) *Partida {
 	p := &Partida{
-		ID:     idPartida,
-		A:      a,
-		B:      b,
-		Criada: time.Now(),
-		Turno:  a.ID,
-		Mao: map[string][]int{
-			a.ID: gerarMaoAleatoria(5),
-			b.ID: gerarMaoAleatoria(5),
-		},
-		Vida: map[string]int{
-			a.ID: 100,
-			b.ID: 100,
-		},
-	}
-
-	partidasMu.Lock()
-	partidasAtivas[idPartida] = p
-	partidasMu.Unlock()
-
-	a.enviarMensagem(fmt.Sprintf("\n============================\nVocê foi pareado com %s!\nID da partida: %s\nVida inicial: 100\n============================", b.Nome, idPartida))
-	b.enviarMensagem(fmt.Sprintf("\n============================\nVocê foi pareado com %s!\nID da partida: %s\nVida inicial: 100\n============================", a.Nome, idPartida))
-
-	go rodarPartida(p)
-}
-
-// calcula o dano de uma carta com base em sua raridade
-func danoCarta(cartaID int) int {
-	nome := cartasDisponiveis[cartaID]
-	if strings.Contains(nome, "Rara") {
-		return 30
-	} else if strings.Contains(nome, "Incomum") {
-		return 20
+		ID:           id,
+		A:            a,
+		B:            b,
+		Criada:       time.Now(),
+		Espectadores: map[string]*Jogador{},
+		missoes:      map[string]map[string]int{},
+		Semente:      semente,
+		rng:          rand.New(rand.NewSource(semente)),
+		entrada:      make(chan mensagemPartida, 16),
+		encerrada:    make(chan struct{}),
 	}
-	return 10 // Comum
+	maoA := gerarMaoAleatoria(p.rng, 5)
+	maoB := gerarMaoAleatoria(p.rng, 5)
+	p.Estado = motor.Novo(a.ID, b.ID, maoA, maoB)
+	p.marcarVida()
+	return p
 }
 
 // processa ações do jogador dentro de uma partida
 func tratarAcao(j *Jogador, acao AcaoJogo) {
 	switch acao.Acao {
-	case "jogar_carta":
-		p := encontrarPartidaPorJogador(j.ID)
-		if p == nil {
-			j.enviarMensagem("Você não está em uma partida")
-			return
-		}
-		p.mu.Lock()
-		defer p.mu.Unlock()
+	case "chat":
+		enviarChat(j, acao.Canal, acao.Texto)
 
-		if p.Turno != j.ID {
-			j.enviarMensagem("Não é sua vez")
-			return
-		}
+	case "sussurrar":
+		sussurrar(j, acao.Destino, acao.Texto)
 
-		mao := p.Mao[j.ID]
-		pos := -1
-		for i, cid := range mao {
-			if cid == acao.CartaID {
-				pos = i
-				break
-			}
-		}
-		if pos == -1 {
-			j.enviarMensagem("Carta não encontrada na mão")
-			return
-		}
-
-		// remove carta da mão
-		mao = append(mao[:pos], mao[pos+1:]...)
-		p.Mao[j.ID] = mao
-
-		// determina o oponente
-		var oponenteID string
-		if p.A.ID == j.ID {
-			oponenteID = p.B.ID
-		} else {
-			oponenteID = p.A.ID
-		}
-
-		// calcula dano e aplica
-		dano := danoCarta(acao.CartaID)
-		p.Vida[oponenteID] -= dano
-		if p.Vida[oponenteID] < 0 {
-			p.Vida[oponenteID] = 0
-		}
+	case "canal_entrar":
+		entrarCanal(j, acao.Canal)
 
-		nomeCarta := cartasDisponiveis[acao.CartaID]
-		msg := fmt.Sprintf("\n%s jogou a carta [%d] %s causando %d de dano!\nVida de %s: %d | Vida de %s: %d\n",
-			j.Nome, acao.CartaID, nomeCarta, dano,
-			j.Nome, p.Vida[j.ID], "Oponente", p.Vida[oponenteID],
-		)
-		p.A.enviarMensagem(msg)
-		p.B.enviarMensagem(msg)
+	case "canal_sair":
+		sairCanal(j, acao.Canal)
 
-		// verifica vitória
-		if p.Vida[oponenteID] <= 0 {
-			p.A.enviarMensagem(fmt.Sprintf("\n============================\n%s venceu a partida!\n============================", j.Nome))
-			p.B.enviarMensagem(fmt.Sprintf("\n============================\n%s venceu a partida!\n============================", j.Nome))
-			p.A.mu.Lock()
-			p.A.EmPartida = false
-			p.A.mu.Unlock()
-			p.B.mu.Lock()
-			p.B.EmPartida = false
-			p.B.mu.Unlock()
-
-			partidasMu.Lock()
-			delete(partidasAtivas, p.ID)
-			partidasMu.Unlock()
-		}
-
-		// troca a vez automaticamente
-		if p.Turno == p.A.ID {
-        	p.Turno = p.B.ID
-		} else {
-			p.Turno = p.A.ID
-		}
-			p.A.enviarMensagem(fmt.Sprintf("\n============================\nVez trocada! %s passou a vez\n============================", j.Nome))
-			p.B.enviarMensagem(fmt.Sprintf("\n============================\nVez trocada! %s passou a vez\n============================", j.Nome))
-
-	case "fim_turno":
+	case "jogar_carta", "fim_turno":
 		p := encontrarPartidaPorJogador(j.ID)
-		if p == nil {
+		if p == nil || !p.agir(j, acao) {
 			j.enviarMensagem("Você não está em uma partida")
-			return
-		}
-		p.mu.Lock()
-		if p.Turno == p.A.ID {
-			p.Turno = p.B.ID
-		} else {
-			p.Turno = p.A.ID
 		}
-		p.A.enviarMensagem(fmt.Sprintf("\n============================\nVez trocada! Agora: %s\n============================", p.Turno))
-		p.B.enviarMensagem(fmt.Sprintf("\n============================\nVez trocada! Agora: %s\n============================", p.Turno))
-		p.mu.Unlock()
 	}
 }
-
-// retorna a partida em que o jogador está
-func encontrarPartidaPorJogador(jogadorID string) *Partida {
-	partidasMu.Lock()
-	defer partidasMu.Unlock()
-	for _, p := range partidasAtivas {
-		if p.A.ID == jogadorID || p.B.ID == jogadorID {
-			return p
-		}
-	}
-	return nil
-}
-
-// retorna um pacote booster disponível ou false se não houver
-func pegarBooster() (*PacoteBooster, bool) {
-	boostersMu.Lock()
-	defer boostersMu.Unlock()
-	if len(boosters) == 0 {
-		return nil, false
-	}
-	idx := len(boosters) - 1
-	pacote := boosters[idx]
-	boosters = boosters[:idx] // remove do inventário
-	return pacote, true
-}
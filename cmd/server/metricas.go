@@ -0,0 +1,188 @@
+// metricas.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Endpoint HTTP /metrics no formato texto do Prometheus, escrito à mão (sem dependências).
+// Medidores (gauges) são lidos dos registros na hora da coleta; contadores e histogramas
+// são atualizados pelo código do servidor com operações atômicas, sem travas.
+
+// endereço do HTTP das métricas ("" desativa)
+var enderecoMetricas = ":4002"
+
+// histograma com baldes fixos, seguro para uso concorrente
+type histograma struct {
+	limites    []float64       // limite superior de cada balde, em segundos
+	contagens  []atomic.Uint64 // observações por balde (não acumuladas); a última é o +Inf
+	soma       atomic.Uint64   // bits do float64 da soma das observações
+	observadas atomic.Uint64
+}
+
+func novoHistograma(limites ...float64) *histograma {
+	return &histograma{limites: limites, contagens: make([]atomic.Uint64, len(limites)+1)}
+}
+
+func (h *histograma) observar(d time.Duration) {
+	v := d.Seconds()
+	h.contagens[sort.SearchFloat64s(h.limites, v)].Add(1)
+	for {
+		antiga := h.soma.Load()
+		if h.soma.CompareAndSwap(antiga, math.Float64bits(math.Float64frombits(antiga)+v)) {
+			break
+		}
+	}
+	h.observadas.Add(1)
+}
+
+// histogramas com um rótulo de valores conhecidos; valores fora da lista não são contados
+type familiaHistogramas struct {
+	rotulo string
+	series map[string]*histograma
+}
+
+func novaFamilia(rotulo string, valores []string, limites ...float64) *familiaHistogramas {
+	f := &familiaHistogramas{rotulo: rotulo, series: map[string]*histograma{}}
+	for _, v := range valores {
+		f.series[v] = novoHistograma(limites...)
+	}
+	return f
+}
+
+func (f *familiaHistogramas) observar(valor string, d time.Duration) {
+	if h := f.series[valor]; h != nil {
+		h.observar(d)
+	}
+}
+
+var (
+	// do fim da criação ao encerramento da partida, por motivo (vitoria, desconexao, admin)
+	duracaoPartidas = novaFamilia("motivo", []string{"vitoria", "desconexao", "admin"},
+		30, 60, 120, 300, 600, 900, 1800, 3600)
+	// da entrega da ação à partida até a resposta da goroutine da partida, por ação
+	latenciaAcoes = novaFamilia("acao", []string{"jogar_carta", "fim_turno"},
+		0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1)
+	// RTT dos pings UDP confirmados pelos clientes
+	rttPing = novoHistograma(0.001, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.15, 0.25, 0.5, 1)
+)
+
+// escreve as métricas no formato de exposição em texto do Prometheus
+type exposicao struct {
+	b strings.Builder
+}
+
+func (e *exposicao) cabecalho(nome, tipo, ajuda string) {
+	fmt.Fprintf(&e.b, "# HELP %s %s\n# TYPE %s %s\n", nome, ajuda, nome, tipo)
+}
+
+func (e *exposicao) valor(nome, tipo, ajuda string, v float64) {
+	e.cabecalho(nome, tipo, ajuda)
+	fmt.Fprintf(&e.b, "%s %s\n", nome, formatarValor(v))
+}
+
+// uma série por valor do rótulo, em ordem alfabética
+func (e *exposicao) rotulados(nome, tipo, ajuda, rotulo string, valores map[string]float64) {
+	e.cabecalho(nome, tipo, ajuda)
+	chaves := make([]string, 0, len(valores))
+	for k := range valores {
+		chaves = append(chaves, k)
+	}
+	sort.Strings(chaves)
+	for _, k := range chaves {
+		fmt.Fprintf(&e.b, "%s{%s=%q} %s\n", nome, rotulo, k, formatarValor(valores[k]))
+	}
+}
+
+func (e *exposicao) histograma(nome string, rotulos string, h *histograma) {
+	sep := ""
+	if rotulos != "" {
+		sep = ","
+	}
+	var acumulado uint64
+	for i, limite := range h.limites {
+		acumulado += h.contagens[i].Load()
+		fmt.Fprintf(&e.b, "%s_bucket{%s%sle=%q} %d\n", nome, rotulos, sep, formatarValor(limite), acumulado)
+	}
+	acumulado += h.contagens[len(h.limites)].Load()
+	fmt.Fprintf(&e.b, "%s_bucket{%s%sle=\"+Inf\"} %d\n", nome, rotulos, sep, acumulado)
+	if rotulos != "" {
+		rotulos = "{" + rotulos + "}"
+	}
+	fmt.Fprintf(&e.b, "%s_sum%s %s\n", nome, rotulos, formatarValor(math.Float64frombits(h.soma.Load())))
+	fmt.Fprintf(&e.b, "%s_count%s %d\n", nome, rotulos, acumulado)
+}
+
+func (e *exposicao) familia(nome, ajuda string, f *familiaHistogramas) {
+	e.cabecalho(nome, "histogram", ajuda)
+	chaves := make([]string, 0, len(f.series))
+	for k := range f.series {
+		chaves = append(chaves, k)
+	}
+	sort.Strings(chaves)
+	for _, k := range chaves {
+		e.histograma(nome, fmt.Sprintf("%s=%q", f.rotulo, k), f.series[k])
+	}
+}
+
+func formatarValor(v float64) string {
+	return strings.TrimSuffix(fmt.Sprintf("%g", v), ".0")
+}
+
+// coleta todas as métricas do servidor
+func coletarMetricas() string {
+	var e exposicao
+
+	e.valor("lobby_jogadores_conectados", "gauge", "Jogadores conectados ao lobby.", float64(jogadoresPorID.tamanho()))
+	filaMu.Lock()
+	fila := len(filaPartida)
+	filaMu.Unlock()
+	e.valor("lobby_fila_partidas", "gauge", "Jogadores na fila de matchmaking.", float64(fila))
+	e.valor("lobby_partidas_ativas", "gauge", "Partidas em andamento.", float64(partidasAtivas.tamanho()))
+	e.familia("lobby_partida_duracao_segundos", "Duração das partidas encerradas, por motivo do fim.", duracaoPartidas)
+	e.familia("lobby_acao_latencia_segundos", "Tempo até a goroutine da partida tratar uma ação de jogo.", latenciaAcoes)
+
+	estoque := map[string]float64{}
+	boostersMu.Lock()
+	for _, c := range colecoesBooster {
+		estoque[c.Nome] = float64(len(boosters[c.Nome]))
+	}
+	boostersMu.Unlock()
+	e.rotulados("lobby_boosters_estoque", "gauge", "Pacotes booster em estoque, por coleção.", "colecao", estoque)
+
+	e.cabecalho("lobby_ping_rtt_segundos", "histogram", "RTT dos pings UDP confirmados pelos clientes.")
+	e.histograma("lobby_ping_rtt_segundos", "", rttPing)
+
+	e.valor("lobby_saida_descartadas_total", "counter", "Mensagens descartáveis que caíram da fila de saída.", float64(totalDescartadas.Load()))
+	e.valor("lobby_saida_coalescidas_total", "counter", "Atualizações de estado substituídas por uma mais nova.", float64(totalCoalescidas.Load()))
+	e.valor("lobby_saida_lentos_total", "counter", "Clientes desconectados por lentidão.", float64(totalLentos.Load()))
+	e.valor("lobby_linhas_limitadas_total", "counter", "Linhas recusadas pelos limites de taxa.", float64(totalLimitadas.Load()))
+	e.valor("lobby_conexoes_negadas_total", "counter", "Conexões recusadas pelo limite por IP ou por banimento.", float64(totalConexoesNegadas.Load()))
+	e.valor("lobby_ips_banidos_total", "counter", "Banimentos de IPs abusivos.", float64(totalBanimentosIP.Load()))
+	e.valor("lobby_tls_falhas_total", "counter", "Apertos de mão TLS que falharam.", float64(totalFalhasTLS.Load()))
+
+	e.valor("go_goroutines", "gauge", "Goroutines em execução.", float64(runtime.NumGoroutine()))
+	return e.b.String()
+}
+
+// serve /metrics até o processo terminar
+func servirMetricas(endereco string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, coletarMetricas())
+	})
+	srv := &http.Server{Addr: endereco, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	slog.Info("métricas Prometheus disponíveis", "endereco", endereco, "caminho", "/metrics")
+	if err := srv.ListenAndServe(); err != nil {
+		slog.Error("erro no servidor de métricas", "erro", err)
+	}
+}
@@ -0,0 +1,406 @@
+// missoes.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// métricas acompanhadas pelas missões, extraídas dos eventos das partidas
+const (
+	metricaPartidas    = "partidas"
+	metricaVitorias    = "vitorias"
+	metricaDano        = "dano"
+	metricaCartas      = "cartas"
+	metricaCartasRaras = "cartas_raras"
+)
+
+const (
+	missoesPorDia      = 3 // missões diárias sorteadas para cada jogador
+	trocasMissaoPorDia = 1 // missões que podem ser trocadas por dia
+)
+
+// recompensa de uma missão ou conquista: moedas ou um booster da coleção
+type RecompensaMissao struct {
+	Moedas  int
+	Booster string
+}
+
+func (r RecompensaMissao) String() string {
+	if r.Booster != "" {
+		return "booster " + r.Booster
+	}
+	return fmt.Sprintf("%d moedas", r.Moedas)
+}
+
+// modelo de uma missão diária ou de uma conquista
+type ModeloMissao struct {
+	ID         string
+	Descricao  string
+	Metrica    string
+	Meta       int
+	Recompensa RecompensaMissao
+}
+
+// missões que podem ser sorteadas no dia
+var modelosMissao = []ModeloMissao{
+	{ID: "jogar_partidas", Descricao: "Jogue 2 partidas", Metrica: metricaPartidas, Meta: 2, Recompensa: RecompensaMissao{Moedas: 40}},
+	{ID: "vencer_1", Descricao: "Vença 1 partida", Metrica: metricaVitorias, Meta: 1, Recompensa: RecompensaMissao{Moedas: 50}},
+	{ID: "vencer_3", Descricao: "Vença 3 partidas", Metrica: metricaVitorias, Meta: 3, Recompensa: RecompensaMissao{Booster: colecaoBasica}},
+	{ID: "dano_500", Descricao: "Cause 500 de dano", Metrica: metricaDano, Meta: 500, Recompensa: RecompensaMissao{Moedas: 75}},
+	{ID: "dano_1500", Descricao: "Cause 1500 de dano", Metrica: metricaDano, Meta: 1500, Recompensa: RecompensaMissao{Booster: "avancado"}},
+	{ID: "cartas_30", Descricao: "Jogue 30 cartas", Metrica: metricaCartas, Meta: 30, Recompensa: RecompensaMissao{Moedas: 60}},
+	{ID: "raras_10", Descricao: "Jogue 10 cartas Raras", Metrica: metricaCartasRaras, Meta: 10, Recompensa: RecompensaMissao{Moedas: 100}},
+}
+
+// conquistas de longo prazo, obtidas uma única vez
+var modelosConquista = []ModeloMissao{
+	{ID: "primeira_vitoria", Descricao: "Vença sua primeira partida", Metrica: metricaVitorias, Meta: 1, Recompensa: RecompensaMissao{Moedas: 100}},
+	{ID: "veterano", Descricao: "Jogue 50 partidas", Metrica: metricaPartidas, Meta: 50, Recompensa: RecompensaMissao{Moedas: 300}},
+	{ID: "campeao", Descricao: "Vença 100 partidas", Metrica: metricaVitorias, Meta: 100, Recompensa: RecompensaMissao{Booster: "lendario"}},
+	{ID: "destruidor", Descricao: "Cause 10000 de dano", Metrica: metricaDano, Meta: 10000, Recompensa: RecompensaMissao{Moedas: 500}},
+	{ID: "mestre_raras", Descricao: "Jogue 200 cartas Raras", Metrica: metricaCartasRaras, Meta: 200, Recompensa: RecompensaMissao{Booster: "avancado"}},
+}
+
+// missão diária sorteada para um jogador
+type MissaoDiaria struct {
+	ID        string `json:"id"`
+	Progresso int    `json:"progresso"`
+	Concluida bool   `json:"concluida,omitempty"`
+}
+
+// missões e conquistas de um jogador
+type EstadoMissoes struct {
+	Dia        string               `json:"dia"` // dia das missões diárias atuais
+	Diarias    []*MissaoDiaria      `json:"diarias"`
+	Trocas     int                  `json:"trocas"`               // trocas usadas no dia
+	Totais     map[string]int       `json:"totais"`               // métrica -> total acumulado
+	Conquistas map[string]time.Time `json:"conquistas,omitempty"` // conquista -> quando foi obtida
+}
+
+var (
+	missoesMu       sync.Mutex
+	missoes         = map[string]*EstadoMissoes{}
+	salvarMissoesMu sync.Mutex // serializa as gravações do arquivo
+
+	// missoes.json é regravado inteiro: o progresso de cada jogada só marca o arquivo, e a
+	// gravação é imediata apenas antes de pagar uma recompensa
+	gravacaoMissoes = novaGravacaoAdiada("missoes", gravarMissoes)
+)
+
+// recompensa a ser entregue fora do missoesMu
+type premioMissao struct {
+	nome      string
+	modelo    ModeloMissao
+	conquista bool
+}
+
+func buscarModelo(modelos []ModeloMissao, id string) *ModeloMissao {
+	for i := range modelos {
+		if modelos[i].ID == id {
+			return &modelos[i]
+		}
+	}
+	return nil
+}
+
+// estado do jogador, sorteando novas missões na virada do dia; mudou indica que o
+// estado precisa ser gravado (deve ser chamada com missoesMu travado)
+func estadoMissoes(nome string) (e *EstadoMissoes, mudou bool) {
+	e = missoes[nome]
+	if e == nil {
+		e = &EstadoMissoes{Totais: map[string]int{}, Conquistas: map[string]time.Time{}}
+		missoes[nome] = e
+	}
+	if hoje := time.Now().Format("2006-01-02"); e.Dia != hoje {
+		e.Dia = hoje
+		e.Trocas = 0
+		e.Diarias = sortearMissoes(nil, missoesPorDia)
+		mudou = true
+	}
+	return e, mudou
+}
+
+// sorteia n missões diferentes, ignorando as já atribuídas
+func sortearMissoes(atuais []*MissaoDiaria, n int) []*MissaoDiaria {
+	usadas := map[string]bool{}
+	for _, m := range atuais {
+		usadas[m.ID] = true
+	}
+	var sorteadas []*MissaoDiaria
+	for _, i := range rand.Perm(len(modelosMissao)) {
+		if len(sorteadas) == n {
+			break
+		}
+		if !usadas[modelosMissao[i].ID] {
+			sorteadas = append(sorteadas, &MissaoDiaria{ID: modelosMissao[i].ID})
+		}
+	}
+	return sorteadas
+}
+
+// métricas de uma carta jogada: a carta e o dano que ela causou ao oponente
+func metricasJogada(carta int, eventos []motor.Evento) map[string]int {
+	m := map[string]int{metricaCartas: 1}
+	if motor.Raridade(carta) == "Rara" {
+		m[metricaCartasRaras] = 1
+	}
+	for _, ev := range eventos {
+		if ev.Tipo == motor.EventoDano {
+			m[metricaDano] += ev.Valor
+		}
+	}
+	return m
+}
+
+// métricas contadas no fim da partida: a partida jogada e a vitória; cartas e dano vêm das
+// jogadas (somarMetricas)
+func metricasFim(eventos []EventoPartida) map[string]map[string]int {
+	if len(eventos) == 0 || eventos[0].Tipo != "inicio" {
+		return nil
+	}
+	nomes := map[string]string{} // ID -> nome
+	metricas := map[string]map[string]int{}
+	for _, jr := range eventos[0].Jogadores {
+		nomes[jr.ID] = jr.Nome
+		metricas[jr.Nome] = map[string]int{metricaPartidas: 1}
+	}
+	if fim := eventos[len(eventos)-1]; fim.Tipo == "fim" {
+		if nome, ok := nomes[fim.Vencedor]; ok {
+			metricas[nome][metricaVitorias]++
+		}
+	}
+	return metricas
+}
+
+// soma as métricas de origem às de destino e devolve o destino (criado se for nil)
+func somarMetricas(destino, origem map[string]map[string]int) map[string]map[string]int {
+	if destino == nil {
+		destino = map[string]map[string]int{}
+	}
+	for nome, m := range origem {
+		if destino[nome] == nil {
+			destino[nome] = map[string]int{}
+		}
+		for metrica, v := range m {
+			destino[nome][metrica] += v
+		}
+	}
+	return destino
+}
+
+// atualiza missões e conquistas com as métricas de cada jogador (nome -> métrica -> valor)
+func progredirMissoes(metricas map[string]map[string]int) {
+	var premios []premioMissao
+	missoesMu.Lock()
+	for nome, m := range metricas {
+		e, _ := estadoMissoes(nome)
+		for _, d := range e.Diarias {
+			modelo := buscarModelo(modelosMissao, d.ID)
+			if modelo == nil || d.Concluida {
+				continue
+			}
+			d.Progresso = min(d.Progresso+m[modelo.Metrica], modelo.Meta)
+			if d.Progresso == modelo.Meta {
+				d.Concluida = true
+				premios = append(premios, premioMissao{nome: nome, modelo: *modelo})
+			}
+		}
+		for metrica, v := range m {
+			e.Totais[metrica] += v
+		}
+		for _, c := range modelosConquista {
+			if _, ok := e.Conquistas[c.ID]; !ok && e.Totais[c.Metrica] >= c.Meta {
+				e.Conquistas[c.ID] = time.Now()
+				premios = append(premios, premioMissao{nome: nome, modelo: c, conquista: true})
+			}
+		}
+	}
+	missoesMu.Unlock()
+
+	if len(premios) == 0 {
+		salvarMissoes()
+		return
+	}
+	// grava antes de pagar para que uma queda não repita a recompensa
+	gravacaoMissoes.agora()
+	for _, p := range premios {
+		entregarPremio(p)
+	}
+}
+
+// paga a recompensa e avisa o jogador, se estiver conectado
+func entregarPremio(p premioMissao) {
+	titulo := "Missão concluída"
+	referencia := p.modelo.ID
+	if p.conquista {
+		titulo = "Conquista obtida"
+		referencia = "conquista_" + p.modelo.ID
+	}
+	if j := jogadorPorNome(p.nome); j != nil {
+		j.enviarMensagem(fmt.Sprintf("%s: %s (%s)", titulo, p.modelo.Descricao, p.modelo.Recompensa))
+	}
+
+	r := p.modelo.Recompensa
+	if r.Booster == "" {
+		recompensarMissao(p.nome, referencia, r.Moedas)
+		return
+	}
+	pacote, ok := pegarBooster(r.Booster, p.nome)
+	if !ok {
+		// sem estoque: paga o preço do booster em moedas
+		if c := colecaoBooster(r.Booster); c != nil {
+			recompensarMissao(p.nome, referencia, c.Preco)
+		}
+		return
+	}
+	adicionarCartas(p.nome, pacote.Cartas)
+	if j := jogadorPorNome(p.nome); j != nil {
+		j.enviarMensagem(fmt.Sprintf("Você abriu booster %s (%s) -> cartas: %v", pacote.ID, r.Booster, pacote.Cartas))
+	}
+}
+
+// /missoes [trocar <n> | conquistas]
+func tratarMissoes(j *Jogador, args []string) {
+	switch {
+	case len(args) == 0:
+		mostrarMissoes(j)
+	case args[0] == "trocar" && len(args) == 2:
+		trocarMissao(j, args[1])
+	case args[0] == "conquistas" && len(args) == 1:
+		mostrarConquistas(j)
+	default:
+		j.enviarMensagem("Uso: /missoes, /missoes trocar <n>, /missoes conquistas")
+	}
+}
+
+// missões do dia com o progresso de cada uma
+func mostrarMissoes(j *Jogador) {
+	var builder strings.Builder
+	missoesMu.Lock()
+	e, mudou := estadoMissoes(j.Nome)
+	builder.WriteString(fmt.Sprintf("Missões de hoje (trocas disponíveis: %d):\n", trocasMissaoPorDia-e.Trocas))
+	for i, d := range e.Diarias {
+		modelo := buscarModelo(modelosMissao, d.ID)
+		if modelo == nil {
+			continue
+		}
+		situacao := fmt.Sprintf("%d/%d", d.Progresso, modelo.Meta)
+		if d.Concluida {
+			situacao = "concluída"
+		}
+		builder.WriteString(fmt.Sprintf("  %d. %-22s [%s] -> %s\n", i+1, modelo.Descricao, situacao, modelo.Recompensa))
+	}
+	builder.WriteString(fmt.Sprintf("Conquistas: %d/%d (/missoes conquistas)\n", len(e.Conquistas), len(modelosConquista)))
+	missoesMu.Unlock()
+	if mudou {
+		salvarMissoes()
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// troca uma missão do dia ainda não concluída por outra sorteada
+func trocarMissao(j *Jogador, arg string) {
+	n, err := strconv.Atoi(arg)
+	missoesMu.Lock()
+	e, _ := estadoMissoes(j.Nome)
+	var msg string
+	switch {
+	case err != nil || n < 1 || n > len(e.Diarias):
+		msg = "Missão inválida (veja /missoes)"
+	case e.Trocas >= trocasMissaoPorDia:
+		msg = "Você já trocou uma missão hoje"
+	case e.Diarias[n-1].Concluida:
+		msg = "Missões concluídas não podem ser trocadas"
+	default:
+		nova := sortearMissoes(e.Diarias, 1)
+		if len(nova) == 0 {
+			msg = "Não há outras missões disponíveis"
+			break
+		}
+		antiga := buscarModelo(modelosMissao, e.Diarias[n-1].ID)
+		e.Diarias[n-1] = nova[0]
+		e.Trocas++
+		msg = fmt.Sprintf("Missão trocada: %s -> %s", antiga.Descricao, buscarModelo(modelosMissao, nova[0].ID).Descricao)
+	}
+	missoesMu.Unlock()
+	salvarMissoes()
+	j.enviarMensagem(msg)
+}
+
+// conquistas obtidas e progresso das restantes
+func mostrarConquistas(j *Jogador) {
+	var builder strings.Builder
+	builder.WriteString("Conquistas:\n")
+	missoesMu.Lock()
+	e, mudou := estadoMissoes(j.Nome)
+	for _, c := range modelosConquista {
+		situacao := fmt.Sprintf("%d/%d", min(e.Totais[c.Metrica], c.Meta), c.Meta)
+		if quando, ok := e.Conquistas[c.ID]; ok {
+			situacao = "obtida em " + quando.Format("02/01/2006")
+		}
+		builder.WriteString(fmt.Sprintf("  %-26s [%s] -> %s\n", c.Descricao, situacao, c.Recompensa))
+	}
+	missoesMu.Unlock()
+	if mudou {
+		salvarMissoes()
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// agenda a gravação das missões
+func salvarMissoes() {
+	gravacaoMissoes.marcar()
+}
+
+// grava missões e conquistas de todos os jogadores
+func gravarMissoes() {
+	salvarMissoesMu.Lock()
+	defer salvarMissoesMu.Unlock()
+
+	missoesMu.Lock()
+	dados, err := json.Marshal(missoes)
+	missoesMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "missoes.json"), dados)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar missões", "erro", err)
+	}
+}
+
+// carrega missões e conquistas gravadas
+func carregarMissoes() error {
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "missoes.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	missoesMu.Lock()
+	defer missoesMu.Unlock()
+	if err := json.Unmarshal(dados, &missoes); err != nil {
+		return err
+	}
+	for _, e := range missoes {
+		if e.Totais == nil {
+			e.Totais = map[string]int{}
+		}
+		if e.Conquistas == nil {
+			e.Conquistas = map[string]time.Time{}
+		}
+	}
+	return nil
+}
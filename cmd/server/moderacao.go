@@ -0,0 +1,752 @@
+// moderacao.go
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// papéis com poderes de moderação
+const (
+	papelModerador = "moderador"
+	papelAdmin     = "admin"
+)
+
+// códigos de erro enviados ao client
+const (
+	erroSilenciado   = "silenciado"
+	erroBanido       = "banido"
+	erroSemPermissao = "sem_permissao"
+	erroBloqueado    = "bloqueado"
+)
+
+// mensagens de chat recentes guardadas por autor para anexar às denúncias, e por quanto
+// tempo as de um autor que parou de escrever continuam guardadas
+const (
+	tamanhoContextoDenuncia  = 20
+	validadeContextoDenuncia = time.Hour
+)
+
+// jogadores que cada um pode bloquear
+const maxBloqueios = 200
+
+// duração máxima de uma punição (também evita estourar o time.Duration em "/mod banir x 999999d")
+const maxDuracaoPunicao = 10 * 365 * 24 * time.Hour
+
+var (
+	maxFalhasAutenticacao     = 5               // tokens errados seguidos de um IP antes de bloqueá-lo
+	bloqueioFalhaAutenticacao = 5 * time.Minute // tempo que o IP fica sem poder usar /autenticar
+)
+
+var (
+	// tokens que concedem papéis via /autenticar (vazios desativam o papel)
+	tokenAdmin     string
+	tokenModerador string
+
+	// arquivo com as palavras filtradas do chat, uma por linha
+	arquivoFiltro string
+)
+
+// punição aplicada a um jogador (pelo nome)
+type Punicao struct {
+	SilenciadoAte time.Time `json:"silenciado_ate,omitempty"`
+	BanidoAte     time.Time `json:"banido_ate,omitempty"`
+	Motivo        string    `json:"motivo,omitempty"`
+	Autor         string    `json:"autor,omitempty"`
+	IP            string    `json:"ip,omitempty"` // último IP do jogador punido, punido junto
+}
+
+// dados de moderação persistidos
+type dadosModeracao struct {
+	Bloqueios map[string][]string `json:"bloqueios"` // nome -> nomes bloqueados
+	Punicoes  map[string]*Punicao `json:"punicoes"`
+}
+
+// mensagem de chat recente, guardada como contexto para denúncias
+type registroChat struct {
+	Momento time.Time `json:"momento"`
+	Origem  string    `json:"origem"` // "#canal", "partida-..." ou "sussurro"
+	Autor   string    `json:"autor"`
+	Destino string    `json:"destino,omitempty"` // destinatário de sussurros
+	Texto   string    `json:"texto"`
+}
+
+// denúncia gravada para revisão
+type Denuncia struct {
+	Momento    time.Time      `json:"momento"`
+	Autor      string         `json:"autor"`
+	Denunciado string         `json:"denunciado"`
+	Motivo     string         `json:"motivo,omitempty"`
+	Contexto   []registroChat `json:"contexto"`
+}
+
+var (
+	moderacaoMu        sync.Mutex
+	bloqueios          = map[string]map[string]bool{} // nome -> nomes bloqueados
+	punicoes           = map[string]*Punicao{}
+	palavrasProibidas  = map[string]bool{}
+	chatRecente        = map[string][]registroChat{} // autor -> últimas mensagens dele
+	limpezaChatRecente time.Time
+	falhasAutenticacao = map[string]*falhaSenha{} // IP -> tokens errados recentes
+	denunciasMu        sync.Mutex                 // serializa a escrita do arquivo de denúncias
+	salvarModeracaoMu  sync.Mutex                 // serializa as gravações de moderacao.json
+
+	// bloqueios só marcam moderacao.json, gravado no máximo uma vez por intervalo (gravacao.go);
+	// punições gravam na hora
+	gravacaoModeracao = novaGravacaoAdiada("moderacao", gravarModeracao)
+)
+
+// envia um erro com código estável para o client
+func (j *Jogador) enviarErro(codigo, msg string) {
+	j.enviarMensagem(fmt.Sprintf("ERRO %s: %s", codigo, msg))
+}
+
+// carrega bloqueios, punições e o filtro de palavras
+func carregarModeracao() error {
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "moderacao.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var d dadosModeracao
+		if err := json.Unmarshal(dados, &d); err != nil {
+			return err
+		}
+		moderacaoMu.Lock()
+		for nome, lista := range d.Bloqueios {
+			bloqueios[nome] = map[string]bool{}
+			for _, b := range lista {
+				bloqueios[nome][b] = true
+			}
+		}
+		if d.Punicoes != nil {
+			punicoes = d.Punicoes
+		}
+		for _, p := range punicoes {
+			if p.IP != "" {
+				punirIP(p.IP, p.BanidoAte, p.SilenciadoAte)
+			}
+		}
+		moderacaoMu.Unlock()
+	}
+	return carregarFiltro()
+}
+
+// lê o arquivo do filtro de palavras
+func carregarFiltro() error {
+	if arquivoFiltro == "" {
+		arquivoFiltro = filepath.Join(diretorioDados, "palavras_proibidas.txt")
+	}
+	f, err := os.Open(arquivoFiltro)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	palavras := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		palavra := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if palavra != "" && !strings.HasPrefix(palavra, "#") {
+			palavras[palavra] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	moderacaoMu.Lock()
+	palavrasProibidas = palavras
+	moderacaoMu.Unlock()
+	slog.Info("filtro de chat carregado", "palavras", len(palavras))
+	return nil
+}
+
+// agenda a gravação dos bloqueios
+func salvarModeracao() {
+	gravacaoModeracao.marcar()
+}
+
+// grava bloqueios e punições
+func gravarModeracao() {
+	salvarModeracaoMu.Lock()
+	defer salvarModeracaoMu.Unlock()
+
+	moderacaoMu.Lock()
+	d := dadosModeracao{Bloqueios: map[string][]string{}, Punicoes: punicoes}
+	for nome, conjunto := range bloqueios {
+		for b := range conjunto {
+			d.Bloqueios[nome] = append(d.Bloqueios[nome], b)
+		}
+		sort.Strings(d.Bloqueios[nome])
+	}
+	dados, err := json.Marshal(d)
+	moderacaoMu.Unlock()
+	if err == nil {
+		err = gravarArquivo(filepath.Join(diretorioDados, "moderacao.json"), dados)
+	}
+	if err != nil {
+		slog.Error("erro ao salvar dados de moderação", "erro", err)
+	}
+}
+
+// grava o filtro de palavras no arquivo configurado
+func salvarFiltro() {
+	moderacaoMu.Lock()
+	palavras := make([]string, 0, len(palavrasProibidas))
+	for p := range palavrasProibidas {
+		palavras = append(palavras, p)
+	}
+	moderacaoMu.Unlock()
+	sort.Strings(palavras)
+	if err := gravarArquivo(arquivoFiltro, []byte(strings.Join(palavras, "\n")+"\n")); err != nil {
+		slog.Error("erro ao salvar filtro de palavras", "erro", err)
+	}
+}
+
+// substitui por asteriscos as palavras proibidas (comparando palavras inteiras, sem diferenciar maiúsculas)
+func filtrarTexto(texto string) string {
+	moderacaoMu.Lock()
+	defer moderacaoMu.Unlock()
+	if len(palavrasProibidas) == 0 {
+		return texto
+	}
+	runas := []rune(texto)
+	for i := 0; i < len(runas); {
+		if !unicode.IsLetter(runas[i]) && !unicode.IsDigit(runas[i]) {
+			i++
+			continue
+		}
+		fim := i
+		for fim < len(runas) && (unicode.IsLetter(runas[fim]) || unicode.IsDigit(runas[fim])) {
+			fim++
+		}
+		if palavrasProibidas[strings.ToLower(string(runas[i:fim]))] {
+			for k := i; k < fim; k++ {
+				runas[k] = '*'
+			}
+		}
+		i = fim
+	}
+	return string(runas)
+}
+
+// informa se o jogador está silenciado; se estiver, avisa com o código de erro
+func verificarSilencio(j *Jogador) bool {
+	moderacaoMu.Lock()
+	p := punicoes[j.Nome]
+	var ate time.Time
+	var motivo string
+	if p != nil {
+		ate, motivo = p.SilenciadoAte, p.Motivo
+	}
+	moderacaoMu.Unlock()
+	if !time.Now().Before(ate) && !j.temPapel(papelModerador) {
+		// outro nome entrando pelo IP de um jogador silenciado
+		ate, motivo = silencioIP(j.ipAtual()), ""
+	}
+	if !time.Now().Before(ate) {
+		return false
+	}
+	msg := fmt.Sprintf("você está silenciado até %s", ate.Format("02/01 15:04"))
+	if motivo != "" {
+		msg += " (" + motivo + ")"
+	}
+	j.enviarErro(erroSilenciado, msg)
+	return true
+}
+
+// retorna até quando o jogador está banido (zero se não estiver)
+func banidoAte(nome string) time.Time {
+	moderacaoMu.Lock()
+	defer moderacaoMu.Unlock()
+	if p := punicoes[nome]; p != nil && time.Now().Before(p.BanidoAte) {
+		return p.BanidoAte
+	}
+	return time.Time{}
+}
+
+// liga a punição ativa do nome ao IP de onde ele conectou, estendendo-a ao IP
+func vincularIP(nome, ip string) {
+	moderacaoMu.Lock()
+	p := punicoes[nome]
+	agora := time.Now()
+	if p == nil || p.IP == ip || (!agora.Before(p.BanidoAte) && !agora.Before(p.SilenciadoAte)) {
+		moderacaoMu.Unlock()
+		return
+	}
+	p.IP = ip
+	banido, silenciado := p.BanidoAte, p.SilenciadoAte
+	moderacaoMu.Unlock()
+	punirIP(ip, banido, silenciado)
+	salvarModeracao()
+}
+
+// informa se o destinatário bloqueou o autor
+func bloqueou(destinatario, autor string) bool {
+	moderacaoMu.Lock()
+	defer moderacaoMu.Unlock()
+	return bloqueios[destinatario][autor]
+}
+
+// guarda uma mensagem de chat como contexto para futuras denúncias
+func registrarChatRecente(origem, autor, destino, texto string) {
+	moderacaoMu.Lock()
+	defer moderacaoMu.Unlock()
+	agora := time.Now()
+	recentes := append(chatRecente[autor], registroChat{Momento: agora, Origem: origem, Autor: autor, Destino: destino, Texto: texto})
+	if len(recentes) > tamanhoContextoDenuncia {
+		recentes = append(recentes[:0], recentes[len(recentes)-tamanhoContextoDenuncia:]...)
+	}
+	chatRecente[autor] = recentes
+
+	// descarta de tempos em tempos os autores que pararam de escrever
+	if agora.Sub(limpezaChatRecente) < validadeContextoDenuncia {
+		return
+	}
+	limpezaChatRecente = agora
+	for nome, lista := range chatRecente {
+		if agora.Sub(lista[len(lista)-1].Momento) > validadeContextoDenuncia {
+			delete(chatRecente, nome)
+		}
+	}
+}
+
+// /bloquear <nome>
+func bloquearJogador(j *Jogador, nome string) {
+	if nome == "" || nome == j.Nome {
+		j.enviarMensagem("Uso: /bloquear <nome>")
+		return
+	}
+	moderacaoMu.Lock()
+	if bloqueios[j.Nome] == nil {
+		bloqueios[j.Nome] = map[string]bool{}
+	}
+	novo := !bloqueios[j.Nome][nome]
+	cheio := novo && len(bloqueios[j.Nome]) >= maxBloqueios
+	if novo && !cheio {
+		bloqueios[j.Nome][nome] = true
+	}
+	moderacaoMu.Unlock()
+	if cheio {
+		j.enviarMensagem(fmt.Sprintf("Você já bloqueou %d jogadores; use /desbloquear antes de bloquear outro", maxBloqueios))
+		return
+	}
+	if novo {
+		salvarModeracao()
+	}
+	j.enviarMensagem(fmt.Sprintf("Você não verá mais mensagens de %s", nome))
+}
+
+// /desbloquear <nome>
+func desbloquearJogador(j *Jogador, nome string) {
+	moderacaoMu.Lock()
+	existia := bloqueios[j.Nome][nome]
+	delete(bloqueios[j.Nome], nome)
+	moderacaoMu.Unlock()
+	if !existia {
+		j.enviarMensagem(fmt.Sprintf("%s não está bloqueado", nome))
+		return
+	}
+	salvarModeracao()
+	j.enviarMensagem(fmt.Sprintf("%s foi desbloqueado", nome))
+}
+
+// /bloqueados
+func listarBloqueados(j *Jogador) {
+	moderacaoMu.Lock()
+	nomes := make([]string, 0, len(bloqueios[j.Nome]))
+	for nome := range bloqueios[j.Nome] {
+		nomes = append(nomes, nome)
+	}
+	moderacaoMu.Unlock()
+	if len(nomes) == 0 {
+		j.enviarMensagem("Você não bloqueou ninguém")
+		return
+	}
+	sort.Strings(nomes)
+	j.enviarMensagem("Bloqueados: " + strings.Join(nomes, ", "))
+}
+
+// /denunciar <nome> [motivo]: grava a denúncia com as mensagens recentes do denunciado
+func denunciarJogador(j *Jogador, nome, motivo string) {
+	if nome == "" || nome == j.Nome {
+		j.enviarMensagem("Uso: /denunciar <nome> [motivo]")
+		return
+	}
+	if !contaExiste(nome) {
+		j.enviarMensagem(fmt.Sprintf("Jogador %s não encontrado", nome))
+		return
+	}
+	d := Denuncia{Momento: time.Now(), Autor: j.Nome, Denunciado: nome, Motivo: motivo}
+	moderacaoMu.Lock()
+	// mensagens públicas do denunciado e sussurros trocados entre os dois
+	for _, r := range chatRecente[nome] {
+		if r.Origem != "sussurro" || r.Destino == j.Nome {
+			d.Contexto = append(d.Contexto, r)
+		}
+	}
+	for _, r := range chatRecente[j.Nome] {
+		if r.Origem == "sussurro" && r.Destino == nome {
+			d.Contexto = append(d.Contexto, r)
+		}
+	}
+	moderacaoMu.Unlock()
+	sort.SliceStable(d.Contexto, func(a, b int) bool { return d.Contexto[a].Momento.Before(d.Contexto[b].Momento) })
+	if len(d.Contexto) > tamanhoContextoDenuncia {
+		d.Contexto = d.Contexto[len(d.Contexto)-tamanhoContextoDenuncia:]
+	}
+
+	linha, err := json.Marshal(d)
+	if err == nil {
+		err = anexarLinha(filepath.Join(diretorioDados, "denuncias.jsonl"), linha, &denunciasMu)
+	}
+	if err != nil {
+		j.logger().Error("erro ao gravar denúncia", "erro", err)
+		j.enviarMensagem("Não foi possível registrar a denúncia, tente novamente")
+		return
+	}
+	j.logger().Info("denúncia registrada", "denunciado", nome)
+	j.enviarMensagem(fmt.Sprintf("Denúncia contra %s registrada, obrigado", nome))
+	notificarModeradores(fmt.Sprintf("Nova denúncia de %s contra %s: %s", j.Nome, nome, motivo))
+}
+
+// acrescenta uma linha a um arquivo append-only
+func anexarLinha(caminho string, linha []byte, mu *sync.Mutex) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(caminho), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(caminho, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(linha, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// avisa os moderadores e admins conectados
+func notificarModeradores(msg string) {
+	for _, d := range jogadoresConectados() {
+		if d.temPapel(papelModerador) {
+			d.enviarMensagem("[moderação] " + msg)
+		}
+	}
+}
+
+// /autenticar <token>: concede o papel correspondente ao token
+func autenticar(j *Jogador, token string) {
+	ip := j.ipAtual()
+	moderacaoMu.Lock()
+	if f := falhasAutenticacao[ip]; f != nil && time.Now().Before(f.ate) {
+		moderacaoMu.Unlock()
+		j.enviarErro(erroSemPermissao, "muitas tentativas erradas, aguarde")
+		return
+	}
+	moderacaoMu.Unlock()
+
+	papel := ""
+	switch {
+	case token == "":
+	case tokenAdmin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(tokenAdmin)) == 1:
+		papel = papelAdmin
+	case tokenModerador != "" && subtle.ConstantTimeCompare([]byte(token), []byte(tokenModerador)) == 1:
+		papel = papelModerador
+	}
+
+	moderacaoMu.Lock()
+	if papel != "" {
+		delete(falhasAutenticacao, ip)
+		moderacaoMu.Unlock()
+	} else {
+		f := falhasAutenticacao[ip]
+		if f == nil {
+			f = &falhaSenha{}
+			falhasAutenticacao[ip] = f
+		}
+		f.seguidas++
+		bloqueado := f.seguidas >= maxFalhasAutenticacao
+		if bloqueado {
+			f.seguidas = 0
+			f.ate = time.Now().Add(bloqueioFalhaAutenticacao)
+		}
+		moderacaoMu.Unlock()
+		j.logger().Warn("falha de autenticação", "ip", ip, "bloqueado", bloqueado)
+		j.enviarErro(erroSemPermissao, "token inválido")
+		return
+	}
+	j.mu.Lock()
+	j.Papel = papel
+	j.mu.Unlock()
+	j.logger().Info("jogador autenticado", "papel", papel)
+	j.enviarMensagem(fmt.Sprintf("Autenticado como %s", papel))
+}
+
+// informa se o jogador tem o papel (admins têm todos os poderes de moderador)
+func (j *Jogador) temPapel(papel string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.Papel == papelAdmin || (papel == papelModerador && j.Papel == papelModerador)
+}
+
+// posição do papel na hierarquia: jogador 0, moderador 1, admin 2
+func (j *Jogador) nivelPapel() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.Papel {
+	case papelAdmin:
+		return 2
+	case papelModerador:
+		return 1
+	}
+	return 0
+}
+
+// interpreta os subcomandos de /mod
+func tratarModeracao(j *Jogador, args []string) {
+	if !j.temPapel(papelModerador) {
+		j.enviarErro(erroSemPermissao, "comando restrito a moderadores")
+		return
+	}
+	if len(args) == 0 {
+		j.enviarMensagem("Uso: /mod silenciar <nome> <duração> [motivo], /mod banir <nome> <duração> [motivo], /mod liberar <nome>, /mod denuncias [n], /mod filtro lista|adicionar|remover [palavra], /mod ips [liberar <ip>], /mod log [nivel]")
+		return
+	}
+	switch args[0] {
+	case "silenciar", "banir":
+		if len(args) < 3 {
+			j.enviarMensagem(fmt.Sprintf("Uso: /mod %s <nome> <duração> [motivo]", args[0]))
+			return
+		}
+		if args[0] == "banir" && !j.temPapel(papelAdmin) {
+			j.enviarErro(erroSemPermissao, "apenas admins podem banir")
+			return
+		}
+		duracao, err := interpretarDuracao(args[2])
+		if err != nil || duracao <= 0 {
+			j.enviarMensagem("Duração inválida (ex: 10m, 2h, 7d; até 3650d)")
+			return
+		}
+		// papéis só existem na sessão: desconectado, o alvo conta como jogador comum
+		if alvo := jogadorPorNome(args[1]); alvo != nil && alvo.nivelPapel() >= j.nivelPapel() {
+			j.enviarErro(erroSemPermissao, "só é possível punir quem tem papel abaixo do seu")
+			return
+		}
+		punir(j, args[0], args[1], duracao, strings.Join(args[3:], " "))
+	case "ping":
+		listarPings(j, strings.Join(args[1:], " "))
+	case "saida":
+		mostrarSaida(j)
+	case "ips":
+		tratarIPs(j, args[1:])
+	case "liberar":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /mod liberar <nome>")
+			return
+		}
+		moderacaoMu.Lock()
+		p, existia := punicoes[args[1]]
+		delete(punicoes, args[1])
+		moderacaoMu.Unlock()
+		if !existia {
+			j.enviarMensagem(fmt.Sprintf("%s não tem punições", args[1]))
+			return
+		}
+		if p.IP != "" {
+			liberarIP(p.IP)
+		}
+		gravacaoModeracao.agora()
+		j.logger().Info("punições removidas", "alvo", args[1])
+		j.enviarMensagem(fmt.Sprintf("Punições de %s removidas", args[1]))
+		if alvo := jogadorPorNome(args[1]); alvo != nil {
+			alvo.enviarMensagem("Suas punições foram removidas")
+		}
+	case "denuncias":
+		n := 10
+		if len(args) >= 2 {
+			if v, err := strconv.Atoi(args[1]); err == nil && v > 0 {
+				n = v
+			}
+		}
+		listarDenuncias(j, n)
+	case "filtro":
+		tratarFiltro(j, args[1:])
+	case "boosters":
+		tratarBoostersAdmin(j, args[1:])
+	case "moedas":
+		ajustarMoedas(j, args[1:])
+	case "conciliar":
+		conciliarMoedas(j)
+	case "log":
+		tratarNivelLog(j, args[1:])
+	default:
+		j.enviarMensagem("Subcomando de moderação desconhecido")
+	}
+}
+
+// aplica silêncio ou banimento por um período
+func punir(autor *Jogador, tipo, nome string, duracao time.Duration, motivo string) {
+	ate := aplicarPunicao(autor.logger(), autor.Nome, tipo, nome, duracao, motivo)
+	autor.enviarMensagem(fmt.Sprintf("%s punido (%s) até %s", nome, tipo, ate.Format("02/01 15:04")))
+}
+
+// grava a punição e avisa o alvo conectado (banido também é desconectado); usada por /mod e
+// pelo console de admin (admin.go)
+func aplicarPunicao(logger *slog.Logger, autor, tipo, nome string, duracao time.Duration, motivo string) time.Time {
+	ate := time.Now().Add(duracao)
+	// o IP da conexão atual; desconectado, vale o da punição anterior ou o da próxima entrada
+	alvo := jogadorPorNome(nome)
+	ip := ""
+	if alvo != nil {
+		ip = alvo.ipAtual()
+	}
+	moderacaoMu.Lock()
+	p := punicoes[nome]
+	if p == nil {
+		p = &Punicao{}
+		punicoes[nome] = p
+	}
+	if tipo == "banir" {
+		p.BanidoAte = ate
+	} else {
+		p.SilenciadoAte = ate
+	}
+	p.Motivo = motivo
+	p.Autor = autor
+	if ip != "" {
+		p.IP = ip
+	}
+	ip = p.IP
+	banido, silenciado := p.BanidoAte, p.SilenciadoAte
+	moderacaoMu.Unlock()
+	if ip != "" {
+		punirIP(ip, banido, silenciado)
+	}
+	gravacaoModeracao.agora()
+
+	logger.Info("punição aplicada", "tipo", tipo, "alvo", nome, "ate", ate, "motivo", motivo, "ip", ip)
+
+	if alvo == nil {
+		return ate
+	}
+	sufixo := ""
+	if motivo != "" {
+		sufixo = " (" + motivo + ")"
+	}
+	if tipo == "banir" {
+		alvo.enviarErro(erroBanido, fmt.Sprintf("você foi banido até %s%s", ate.Format("02/01 15:04"), sufixo))
+		alvo.derrubarAposAviso()
+		return ate
+	}
+	alvo.enviarErro(erroSilenciado, fmt.Sprintf("você foi silenciado até %s%s", ate.Format("02/01 15:04"), sufixo))
+	return ate
+}
+
+// derruba a conexão depois de dar tempo ao escritor de enviar o último aviso
+func (j *Jogador) derrubarAposAviso() {
+	time.AfterFunc(500*time.Millisecond, func() {
+		j.mu.Lock()
+		conn := j.Conexao
+		j.mu.Unlock()
+		conn.Close()
+	})
+}
+
+// aceita durações do time.ParseDuration e também dias (ex: 7d), até maxDuracaoPunicao
+func interpretarDuracao(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		dias, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		if dias > int(maxDuracaoPunicao/(24*time.Hour)) {
+			return 0, fmt.Errorf("duração maior que %dd", maxDuracaoPunicao/(24*time.Hour))
+		}
+		return time.Duration(dias) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d > maxDuracaoPunicao {
+		return 0, fmt.Errorf("duração maior que %dd", maxDuracaoPunicao/(24*time.Hour))
+	}
+	return d, err
+}
+
+// mostra as últimas denúncias gravadas
+func listarDenuncias(j *Jogador, n int) {
+	denunciasMu.Lock()
+	dados, err := os.ReadFile(filepath.Join(diretorioDados, "denuncias.jsonl"))
+	denunciasMu.Unlock()
+	if errors.Is(err, os.ErrNotExist) || len(dados) == 0 {
+		j.enviarMensagem("Nenhuma denúncia registrada")
+		return
+	}
+	if err != nil {
+		j.enviarMensagem("Erro ao ler denúncias")
+		return
+	}
+	linhas := strings.Split(strings.TrimSpace(string(dados)), "\n")
+	if len(linhas) > n {
+		linhas = linhas[len(linhas)-n:]
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Últimas %d denúncias:\n", len(linhas)))
+	for _, linha := range linhas {
+		var d Denuncia
+		if json.Unmarshal([]byte(linha), &d) != nil {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("  %s %s -> %s: %s\n", d.Momento.Format("02/01 15:04"), d.Autor, d.Denunciado, d.Motivo))
+		for _, r := range d.Contexto {
+			builder.WriteString(fmt.Sprintf("      [%s] [%s] %s: %s\n", r.Momento.Format("15:04"), r.Origem, r.Autor, r.Texto))
+		}
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// /mod filtro lista|adicionar|remover [palavra]
+func tratarFiltro(j *Jogador, args []string) {
+	if len(args) == 0 || args[0] == "lista" {
+		moderacaoMu.Lock()
+		palavras := make([]string, 0, len(palavrasProibidas))
+		for p := range palavrasProibidas {
+			palavras = append(palavras, p)
+		}
+		moderacaoMu.Unlock()
+		sort.Strings(palavras)
+		j.enviarMensagem(fmt.Sprintf("Filtro (%d palavras): %s", len(palavras), strings.Join(palavras, ", ")))
+		return
+	}
+	if len(args) < 2 || (args[0] != "adicionar" && args[0] != "remover") {
+		j.enviarMensagem("Uso: /mod filtro lista|adicionar|remover [palavra]")
+		return
+	}
+	palavra := strings.ToLower(args[1])
+	moderacaoMu.Lock()
+	if args[0] == "adicionar" {
+		palavrasProibidas[palavra] = true
+	} else {
+		delete(palavrasProibidas, palavra)
+	}
+	moderacaoMu.Unlock()
+	salvarFiltro()
+	j.logger().Info("filtro de chat alterado", "operacao", args[0], "palavra", palavra)
+	j.enviarMensagem(fmt.Sprintf("Filtro atualizado: %s %q", args[0], palavra))
+}
@@ -0,0 +1,32 @@
+// moderacao_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// dias grandes demais são recusados em vez de estourar o time.Duration
+func TestInterpretarDuracao(t *testing.T) {
+	casos := []struct {
+		texto string
+		d     time.Duration
+		erro  bool
+	}{
+		{"10m", 10 * time.Minute, false},
+		{"2h", 2 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"3650d", maxDuracaoPunicao, false},
+		{"3651d", 0, true},
+		{"999999d", 0, true},
+		{"9223372036854775807d", 0, true},
+		{"100000h", 0, true},
+		{"xd", 0, true},
+	}
+	for _, c := range casos {
+		d, err := interpretarDuracao(c.texto)
+		if (err != nil) != c.erro || (!c.erro && d != c.d) {
+			t.Errorf("interpretarDuracao(%q) = %v, %v", c.texto, d, err)
+		}
+	}
+}
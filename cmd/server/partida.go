@@ -0,0 +1,298 @@
+// partida.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// Cada partida roda numa goroutine própria (rodar), a única que lê e altera o seu estado:
+// vida, mãos, turno, eventos, chat e espectadores. As regras ficam no pacote internal/motor;
+// aqui as ações viram motor.Acao e os eventos do motor viram registros e mensagens. As outras
+// goroutines entregam mensagens pelo canal de entrada e esperam a partida tratá-las; nenhuma
+// trava protege a partida.
+// As funções marcadas com "(goroutine da partida)" só podem ser chamadas por ela, e ela
+// nunca espera por outra partida nem por si mesma.
+
+// tempo de cada turno; ao fim dele a vez passa para o oponente (0 desativa)
+var duracaoTurno = 60 * time.Second
+
+// mensagem entregue à goroutine da partida
+type mensagemPartida struct {
+	jogador    *Jogador
+	acao       *AcaoJogo        // jogar_carta ou fim_turno
+	desconexao bool             // o jogador saiu do servidor
+	consulta   func(p *Partida) // leitura ou alteração feita pela goroutine da partida
+	feito      chan struct{}    // fechado depois que a mensagem foi tratada
+}
+
+// laço da goroutine da partida: trata as mensagens em ordem até a partida terminar
+func (p *Partida) rodar() {
+	defer close(p.encerrada)
+	p.reiniciarRelogio()
+	p.conferirRemovidos()
+	for !p.terminou {
+		var expirou <-chan time.Time
+		if p.relogio != nil {
+			expirou = p.relogio.C
+		}
+		select {
+		case m := <-p.entrada:
+			p.tratar(m)
+			close(m.feito)
+		case <-expirou:
+			p.relogio = nil
+			p.tempoEsgotado()
+		}
+	}
+	if p.relogio != nil {
+		p.relogio.Stop()
+	}
+}
+
+// entrega a mensagem e espera a partida tratá-la; retorna falso se a partida terminou antes
+func (p *Partida) entregar(m mensagemPartida) bool {
+	m.feito = make(chan struct{})
+	select {
+	case p.entrada <- m:
+	case <-p.encerrada:
+		return false
+	}
+	select {
+	case <-m.feito:
+		return true
+	case <-p.encerrada:
+		// a mensagem pode ter sido a que encerrou a partida
+		select {
+		case <-m.feito:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// executa f na goroutine da partida e espera; retorna falso se a partida já terminou
+func (p *Partida) executar(f func(p *Partida)) bool {
+	return p.entregar(mensagemPartida{consulta: f})
+}
+
+// aplica uma ação de jogo do jogador
+func (p *Partida) agir(j *Jogador, acao AcaoJogo) bool {
+	inicio := time.Now()
+	ok := p.entregar(mensagemPartida{jogador: j, acao: &acao})
+	latenciaAcoes.observar(acao.Acao, time.Since(inicio))
+	return ok
+}
+
+// avisa a partida que o jogador saiu do servidor; o oponente vence
+func (p *Partida) desconectar(j *Jogador) bool {
+	return p.entregar(mensagemPartida{jogador: j, desconexao: true})
+}
+
+// (goroutine da partida)
+func (p *Partida) tratar(m mensagemPartida) {
+	switch {
+	case m.consulta != nil:
+		m.consulta(p)
+	case m.desconexao:
+		p.tratarDesconexao(m.jogador)
+	case m.acao != nil && m.acao.Acao == "jogar_carta":
+		p.jogarCarta(m.jogador, *m.acao)
+	case m.acao != nil && m.acao.Acao == "fim_turno":
+		p.fimTurno(m.jogador, *m.acao)
+	}
+}
+
+// aplica a ação pelo motor e guarda o novo estado; se a ação for recusada, avisa o
+// jogador e retorna falso (goroutine da partida)
+func (p *Partida) aplicar(j *Jogador, acao motor.Acao) ([]motor.Evento, bool) {
+	novo, eventos, err := motor.Aplicar(p.Estado, acao)
+	if err != nil {
+		if depurando() {
+			p.loggerAcao(j, acao).Debug("ação recusada", "erro", err)
+		}
+		if j != nil {
+			j.enviarMensagem(mensagemRecusa(err))
+		}
+		return nil, false
+	}
+	if err := motor.Verificar(novo); err != nil {
+		p.loggerAcao(j, acao).Error("estado inválido após a ação", "erro", err)
+	}
+	if depurando() {
+		p.loggerAcao(j, acao).Debug("ação aplicada", "carta", acao.Carta, "eventos", len(eventos))
+	}
+	p.Estado = novo
+	p.marcarVida()
+	return eventos, true
+}
+
+// texto enviado ao jogador quando o motor recusa a ação
+func mensagemRecusa(err error) string {
+	switch {
+	case errors.Is(err, motor.ErrForaDaVez):
+		return "Não é sua vez"
+	case errors.Is(err, motor.ErrCartaForaDaMao):
+		return "Carta não encontrada na mão"
+	case errors.Is(err, motor.ErrPartidaEncerrada):
+		return "A partida já terminou"
+	}
+	return "Ação recusada: " + err.Error()
+}
+
+// (goroutine da partida)
+func (p *Partida) jogarCarta(j *Jogador, acao AcaoJogo) {
+	eventos, ok := p.aplicar(j, motor.Acao{Tipo: motor.JogarCarta, Jogador: j.ID, Carta: acao.CartaID})
+	if !ok {
+		return
+	}
+	p.registrar(EventoPartida{Tipo: "acao", Jogador: j.ID, Acao: &acao})
+	// cartas e dano de cada jogada se somam às partidas e vitórias quando a partida termina
+	somarMetricas(p.missoes, map[string]map[string]int{j.Nome: metricasJogada(acao.CartaID, eventos)})
+
+	for _, ev := range eventos {
+		switch ev.Tipo {
+		case motor.EventoDano:
+			p.registrar(EventoPartida{Tipo: "dano", Jogador: ev.Jogador, Valor: ev.Valor, Vida: ev.Vida})
+			msg := fmt.Sprintf("\n%s jogou a carta [%d] %s causando %d de dano!\nVida de %s: %d | Vida de %s: %d\n",
+				j.Nome, ev.Carta, nomeCarta(ev.Carta), ev.Valor,
+				j.Nome, ev.Vida[j.ID], "Oponente", ev.Vida[ev.Jogador],
+			)
+			p.publicar(msg)
+		case motor.EventoTurno:
+			p.passarVez(ev)
+			p.publicar(fmt.Sprintf("\n============================\nVez trocada! %s passou a vez\n============================", j.Nome))
+		case motor.EventoFim:
+			p.publicar(fmt.Sprintf("\n============================\n%s venceu a partida!\n============================", j.Nome))
+			p.encerrar(ev.Vencedor, ev.Motivo)
+		}
+	}
+}
+
+// (goroutine da partida)
+func (p *Partida) fimTurno(j *Jogador, acao AcaoJogo) {
+	eventos, ok := p.aplicar(j, motor.Acao{Tipo: motor.FimTurno, Jogador: j.ID})
+	if !ok {
+		return
+	}
+	p.registrar(EventoPartida{Tipo: "acao", Jogador: j.ID, Acao: &acao})
+	for _, ev := range eventos {
+		if ev.Tipo == motor.EventoTurno {
+			p.passarVez(ev)
+			p.publicar(fmt.Sprintf("\n============================\nVez trocada! Agora: %s\n============================", p.Turno))
+		}
+	}
+}
+
+// fim do prazo do turno: a vez passa para o oponente (goroutine da partida)
+func (p *Partida) tempoEsgotado() {
+	if p.conferirRemovidos() {
+		return
+	}
+	nome := p.A.Nome
+	if p.Turno == p.B.ID {
+		nome = p.B.Nome
+	}
+	eventos, ok := p.aplicar(nil, motor.Acao{Tipo: motor.Tempo, Jogador: p.Turno})
+	if !ok {
+		return
+	}
+	for _, ev := range eventos {
+		switch ev.Tipo {
+		case motor.EventoTempo:
+			p.registrar(EventoPartida{Tipo: "tempo", Jogador: ev.Jogador})
+		case motor.EventoTurno:
+			p.passarVez(ev)
+		}
+	}
+	p.publicar(fmt.Sprintf("\n============================\nTempo esgotado! %s perdeu a vez\n============================", nome))
+}
+
+// registra a troca de vez feita pelo motor e reinicia o prazo do turno (goroutine da partida)
+func (p *Partida) passarVez(ev motor.Evento) {
+	p.registrar(EventoPartida{Tipo: "turno", Turno: ev.Turno})
+	p.reiniciarRelogio()
+}
+
+// (goroutine da partida)
+func (p *Partida) reiniciarRelogio() {
+	if p.relogio != nil {
+		p.relogio.Stop()
+		p.relogio = nil
+	}
+	if duracaoTurno > 0 {
+		p.relogio = time.NewTimer(duracaoTurno)
+	}
+}
+
+// encerra a partida se um dos jogadores saiu do servidor sem que ela recebesse a
+// desconexão (goroutine da partida)
+func (p *Partida) conferirRemovidos() bool {
+	if j := jogadorRemovido(p.A, p.B); j != nil {
+		p.tratarDesconexao(j)
+		return true
+	}
+	return false
+}
+
+// o jogador saiu do servidor: a partida termina com a vitória do oponente (goroutine da partida)
+func (p *Partida) tratarDesconexao(j *Jogador) {
+	eventos, ok := p.aplicar(nil, motor.Acao{Tipo: motor.Desconexao, Jogador: j.ID})
+	if !ok {
+		return
+	}
+	for _, ev := range eventos {
+		switch ev.Tipo {
+		case motor.EventoDesconexao:
+			if p.Oponente(ev.Jogador) == p.A.ID {
+				p.A.enviarMensagem("Oponente desconectou, partida encerrada")
+			} else {
+				p.B.enviarMensagem("Oponente desconectou, partida encerrada")
+			}
+			p.registrar(EventoPartida{Tipo: "desconexao", Jogador: ev.Jogador})
+			p.transmitirEspectadores(fmt.Sprintf("%s desconectou, partida encerrada", j.Nome))
+		case motor.EventoFim:
+			p.encerrar(ev.Vencedor, ev.Motivo)
+		}
+	}
+}
+
+// um admin encerra a partida; vencedorID vazio encerra sem vencedor (goroutine da partida)
+func (p *Partida) encerrarPorAdmin(vencedorID string) error {
+	novo, ev, err := motor.Encerrar(p.Estado, vencedorID)
+	if err != nil {
+		return err
+	}
+	p.Estado = novo
+	p.registrar(EventoPartida{Tipo: "encerramento_admin", Vencedor: vencedorID})
+	aviso := "sem vencedor"
+	for _, jog := range []*Jogador{p.A, p.B} {
+		if jog.ID == vencedorID {
+			aviso = "vencedor: " + jog.Nome
+		}
+	}
+	p.publicar(fmt.Sprintf("\n============================\nPartida encerrada por um administrador (%s)\n============================", aviso))
+	p.encerrar(ev.Vencedor, ev.Motivo)
+	return nil
+}
+
+// retira a partida dos registros, registra o fim, libera jogadores e espectadores e
+// encerra a goroutine da partida (goroutine da partida)
+func (p *Partida) encerrar(vencedorID, motivo string) {
+	removerPartida(p)
+	duracaoPartidas.observar(motivo, time.Since(p.Criada))
+	p.logger(nil).Info("partida encerrada", "vencedor", vencedorID, "motivo", motivo, "duracao_segundos", time.Since(p.Criada).Seconds())
+	p.registrarFim(vencedorID, motivo)
+	p.encerrarEspectadores()
+	for _, jog := range []*Jogador{p.A, p.B} {
+		jog.mu.Lock()
+		jog.EmPartida = false
+		jog.mu.Unlock()
+		notificarPresenca(jog.Nome)
+	}
+	p.terminou = true
+}
@@ -0,0 +1,225 @@
+// ping.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocolo de ping (texto, um pacote por datagrama, momentos em nanossegundos Unix):
+//
+//	cliente -> servidor: PING <id> <seq> <momento_cliente>
+//	servidor -> cliente: PONG <seq> <momento_cliente> <momento_servidor>
+//	cliente -> servidor: ACK <id> <seq>
+//
+// O ID de ping (conexao.go) é enviado ao jogador ao conectar e identifica o Jogador. Ele não é o
+// token de sessão: os datagramas viajam em claro mesmo com TLS no lobby, e o token de sessão é a
+// credencial de "retomar <token>". O cliente mede o RTT entre o PING e o PONG; o servidor, entre
+// o PONG e o ACK. O payload antigo "ping" continua recebendo "pong".
+
+// endereço do respondedor UDP
+var enderecoUDP = ":4001"
+
+const (
+	limitePendentesPing = 64               // PONGs aguardando ACK por jogador
+	prazoAckPing        = 10 * time.Second // ACKs mais antigos que isso são descartados
+	saltoMaximoPing     = 10000            // saltos de sequência maiores são ignorados
+	validadePing        = 30 * time.Second // medições mais antigas não contam no matchmaking
+)
+
+// estatísticas de ping de um jogador (protegidas por j.mu; o último RTT fica em j.UltimoPing)
+type EstatisticasPing struct {
+	Recebidos int64         // PINGs recebidos
+	Esperados int64         // PINGs esperados pela faixa de sequências recebida
+	Medicoes  int64         // RTTs medidos pelos ACKs
+	RTTMedio  time.Duration // média móvel do RTT
+	Jitter    time.Duration // variação média entre RTTs consecutivos (RFC 3550)
+	Ultimo    time.Time     // último pacote recebido
+	maiorSeq  uint64
+	pendentes map[uint64]time.Time // seq -> envio do PONG
+}
+
+// perda de pacotes no sentido cliente -> servidor (0 a 1)
+func (e *EstatisticasPing) perda() float64 {
+	if e.Esperados == 0 {
+		return 0
+	}
+	return 1 - float64(e.Recebidos)/float64(e.Esperados)
+}
+
+// latência usada no matchmaking: RTT médio, mais duas vezes o jitter, mais 10 ms por ponto
+// percentual de perda; falso se não há medição recente
+func (e *EstatisticasPing) latenciaEfetiva() (time.Duration, bool) {
+	if e.Medicoes == 0 || time.Since(e.Ultimo) > validadePing {
+		return 0, false
+	}
+	return e.RTTMedio + 2*e.Jitter + time.Duration(e.perda()*100)*10*time.Millisecond, true
+}
+
+// inicia o servidor UDP de ping
+func iniciarRespondedorUDP(endereco string) {
+	pc, err := net.ListenPacket("udp", endereco)
+	if err != nil {
+		fatal("erro ao iniciar UDP", "erro", err)
+	}
+	defer pc.Close()
+	slog.Info("respondedor UDP ouvindo", "endereco", endereco)
+	buf := make([]byte, 1024)
+	for {
+		n, raddr, err := pc.ReadFrom(buf)
+		if err != nil {
+			slog.Error("erro ao ler UDP", "erro", err)
+			continue
+		}
+		if resposta := tratarPacotePing(strings.Fields(string(buf[:n])), raddr, time.Now()); resposta != "" {
+			_, _ = pc.WriteTo([]byte(resposta+"\n"), raddr)
+		}
+	}
+}
+
+// interpreta um pacote e devolve a resposta (vazia se não houver)
+func tratarPacotePing(campos []string, raddr net.Addr, agora time.Time) string {
+	if len(campos) == 1 && campos[0] == "ping" {
+		return "pong"
+	}
+	if len(campos) < 3 {
+		return ""
+	}
+	j := jogadorPorIDPing(campos[1])
+	seq, err := strconv.ParseUint(campos[2], 10, 64)
+	if j == nil || err != nil || seq == 0 {
+		return ""
+	}
+
+	switch {
+	case campos[0] == "PING" && len(campos) == 4:
+		j.mu.Lock()
+		j.EnderecoUDP = raddr.String()
+		registrado := j.Ping.registrarPing(seq, agora)
+		j.mu.Unlock()
+		if !registrado {
+			return ""
+		}
+		return fmt.Sprintf("PONG %d %s %d", seq, campos[3], agora.UnixNano())
+	case campos[0] == "ACK" && len(campos) == 3:
+		j.mu.Lock()
+		if rtt, ok := j.Ping.registrarAck(seq, agora, j.UltimoPing); ok {
+			j.UltimoPing = rtt
+			rttPing.observar(rtt)
+		}
+		j.mu.Unlock()
+	}
+	return ""
+}
+
+// contabiliza um PING e guarda o envio do PONG para medir o RTT no ACK
+func (e *EstatisticasPing) registrarPing(seq uint64, agora time.Time) bool {
+	switch {
+	case e.Recebidos == 0:
+		e.Esperados = 1
+		e.maiorSeq = seq
+	case seq > e.maiorSeq:
+		if seq-e.maiorSeq > saltoMaximoPing {
+			return false
+		}
+		e.Esperados += int64(seq - e.maiorSeq)
+		e.maiorSeq = seq
+	case e.maiorSeq-seq > saltoMaximoPing:
+		return false
+	}
+	e.Recebidos = min(e.Recebidos+1, e.Esperados) // duplicados não reduzem a perda abaixo de zero
+	e.Ultimo = agora
+
+	if e.pendentes == nil {
+		e.pendentes = map[uint64]time.Time{}
+	}
+	if len(e.pendentes) >= limitePendentesPing {
+		for s, envio := range e.pendentes {
+			if agora.Sub(envio) > prazoAckPing || s+limitePendentesPing < e.maiorSeq {
+				delete(e.pendentes, s)
+			}
+		}
+	}
+	if len(e.pendentes) < limitePendentesPing {
+		e.pendentes[seq] = agora
+	}
+	return true
+}
+
+// mede o RTT de um PONG confirmado e atualiza média e jitter
+func (e *EstatisticasPing) registrarAck(seq uint64, agora time.Time, anterior time.Duration) (time.Duration, bool) {
+	envio, ok := e.pendentes[seq]
+	if !ok {
+		return 0, false
+	}
+	delete(e.pendentes, seq)
+	rtt := agora.Sub(envio)
+	if rtt > prazoAckPing {
+		return 0, false
+	}
+	if e.Medicoes == 0 {
+		e.RTTMedio = rtt
+	} else {
+		e.RTTMedio += (rtt - e.RTTMedio) / 8
+		variacao := rtt - anterior
+		if variacao < 0 {
+			variacao = -variacao
+		}
+		e.Jitter += (variacao - e.Jitter) / 16
+	}
+	e.Medicoes++
+	e.Ultimo = agora
+	return rtt, true
+}
+
+// resumo das estatísticas (deve ser chamada com j.mu travado)
+func (j *Jogador) resumoPing() string {
+	e := &j.Ping
+	if e.Medicoes == 0 {
+		return "sem medições (o cliente precisa enviar pings UDP com o ID de ping recebido ao conectar)"
+	}
+	return fmt.Sprintf("RTT %d ms, média %d ms, jitter %d ms, perda %.1f%% (%d/%d pacotes), último pacote há %s",
+		j.UltimoPing.Milliseconds(), e.RTTMedio.Milliseconds(), e.Jitter.Milliseconds(),
+		e.perda()*100, e.Recebidos, e.Esperados, time.Since(e.Ultimo).Truncate(time.Second))
+}
+
+// /ping: estatísticas medidas pelo servidor
+func mostrarPing(j *Jogador) {
+	j.mu.Lock()
+	resumo := j.resumoPing()
+	j.mu.Unlock()
+	j.enviarMensagem("Ping (servidor): " + resumo)
+}
+
+// /mod ping [nome]: estatísticas de ping dos jogadores conectados
+func listarPings(j *Jogador, nome string) {
+	var lista []*Jogador
+	if nome == "" {
+		lista = jogadoresConectados()
+	} else if outro := jogadorPorNome(nome); outro != nil {
+		lista = append(lista, outro)
+	}
+	if len(lista) == 0 {
+		j.enviarMensagem(fmt.Sprintf("%s não está conectado", nome))
+		return
+	}
+	sort.Slice(lista, func(a, b int) bool { return lista[a].Nome < lista[b].Nome })
+
+	var builder strings.Builder
+	builder.WriteString("Ping dos jogadores:\n")
+	for _, outro := range lista {
+		outro.mu.Lock()
+		linha := fmt.Sprintf("  %-16s %s", outro.Nome, outro.resumoPing())
+		if outro.EnderecoUDP != "" {
+			linha += " [" + outro.EnderecoUDP + "]"
+		}
+		outro.mu.Unlock()
+		builder.WriteString(linha + "\n")
+	}
+	j.enviarMensagem(builder.String())
+}
@@ -0,0 +1,193 @@
+// registro.go
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Registros globais de jogadores, partidas e sessões. Cada registro é um mapa dividido em
+// fragmentos com travas próprias, e nenhuma outra trava é adquirida com a trava de um
+// fragmento: ela é sempre a última da hierarquia, e quem precisa percorrer os jogadores
+// copia a lista primeiro e trava cada j.mu depois.
+//
+// Hierarquia de travas (quem está à esquerda pode adquirir quem está à direita):
+//
+//	torneiosMu -> partida -> presencaMu -> j.mu -> f.mu (fila de saída)
+//	partida -> estatisticasMu, moderacaoMu
+//	presencaMu -> amigosMu
+//	filaMu -> j.mu
+//	trocasMu -> salvarColecoesMu -> colecoesMu -> economiaMu -> transacoesMu
+//	salvarSaldosMu -> economiaMu
+//	contasMu -> arquivoContasMu
+//	entrega atrasada aos espectadores (filaAtrasada.entregaMu) -> j.mu -> f.mu
+//	fragmentos dos registros, tokens de sessão, missoesMu e ipsMu são folhas
+//
+// "partida" é esperar a goroutine de uma partida (partida.go): quem espera pode estar com
+// torneiosMu, e a goroutine da partida nunca adquire torneiosMu nem espera outra partida.
+// Dois jogadores nunca são travados ao mesmo tempo.
+
+// quantidade de fragmentos de cada registro
+const numFragmentos = 64
+
+// mapa de chaves de texto dividido em fragmentos
+type mapaFragmentado[V comparable] struct {
+	fragmentos [numFragmentos]fragmento[V]
+}
+
+type fragmento[V comparable] struct {
+	mu    sync.Mutex
+	itens map[string]V
+}
+
+func novoMapaFragmentado[V comparable]() *mapaFragmentado[V] {
+	m := &mapaFragmentado[V]{}
+	for i := range m.fragmentos {
+		m.fragmentos[i].itens = map[string]V{}
+	}
+	return m
+}
+
+func (m *mapaFragmentado[V]) fragmento(chave string) *fragmento[V] {
+	h := fnv.New32a()
+	h.Write([]byte(chave))
+	return &m.fragmentos[h.Sum32()%numFragmentos]
+}
+
+func (m *mapaFragmentado[V]) obter(chave string) (V, bool) {
+	f := m.fragmento(chave)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.itens[chave]
+	return v, ok
+}
+
+func (m *mapaFragmentado[V]) definir(chave string, v V) {
+	f := m.fragmento(chave)
+	f.mu.Lock()
+	f.itens[chave] = v
+	f.mu.Unlock()
+}
+
+// insere apenas se a chave estiver livre; retorna falso se já existia
+func (m *mapaFragmentado[V]) inserirSeAusente(chave string, v V) bool {
+	f := m.fragmento(chave)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, existe := f.itens[chave]; existe {
+		return false
+	}
+	f.itens[chave] = v
+	return true
+}
+
+// remove a chave apenas se ela ainda aponta para v; retorna se removeu
+func (m *mapaFragmentado[V]) removerSe(chave string, v V) bool {
+	f := m.fragmento(chave)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if atual, ok := f.itens[chave]; !ok || atual != v {
+		return false
+	}
+	delete(f.itens, chave)
+	return true
+}
+
+func (m *mapaFragmentado[V]) remover(chave string) {
+	f := m.fragmento(chave)
+	f.mu.Lock()
+	delete(f.itens, chave)
+	f.mu.Unlock()
+}
+
+// cópia dos valores, fragmento por fragmento (não é um retrato atômico do mapa inteiro)
+func (m *mapaFragmentado[V]) valores() []V {
+	var lista []V
+	for i := range m.fragmentos {
+		f := &m.fragmentos[i]
+		f.mu.Lock()
+		for _, v := range f.itens {
+			lista = append(lista, v)
+		}
+		f.mu.Unlock()
+	}
+	return lista
+}
+
+func (m *mapaFragmentado[V]) tamanho() int {
+	n := 0
+	for i := range m.fragmentos {
+		f := &m.fragmentos[i]
+		f.mu.Lock()
+		n += len(f.itens)
+		f.mu.Unlock()
+	}
+	return n
+}
+
+var (
+	jogadoresPorID    = novoMapaFragmentado[*Jogador]() // ID -> jogador conectado
+	jogadoresPorNome  = novoMapaFragmentado[*Jogador]() // nome -> jogador conectado
+	partidasAtivas    = novoMapaFragmentado[*Partida]() // ID -> partida em andamento
+	partidaPorJogador = novoMapaFragmentado[*Partida]() // ID do jogador -> partida em andamento
+)
+
+// registra o jogador conectado; retorna falso se o nome já está em uso
+func registrarJogador(j *Jogador) bool {
+	if !jogadoresPorNome.inserirSeAusente(j.Nome, j) {
+		return false
+	}
+	jogadoresPorID.definir(j.ID, j)
+	return true
+}
+
+// retira o jogador dos registros
+func removerRegistroJogador(j *Jogador) {
+	jogadoresPorID.removerSe(j.ID, j)
+	jogadoresPorNome.removerSe(j.Nome, j)
+}
+
+// retorna o jogador conectado com o nome informado
+func jogadorPorNome(nome string) *Jogador {
+	j, _ := jogadoresPorNome.obter(nome)
+	return j
+}
+
+// cópia da lista de jogadores conectados
+func jogadoresConectados() []*Jogador {
+	return jogadoresPorID.valores()
+}
+
+// registra a partida em andamento e indexa os dois jogadores
+func registrarPartida(p *Partida) {
+	partidasAtivas.definir(p.ID, p)
+	partidaPorJogador.definir(p.A.ID, p)
+	partidaPorJogador.definir(p.B.ID, p)
+}
+
+// retira a partida dos registros; apenas a primeira chamada retorna verdadeiro, o que
+// decide quem encerra a partida quando a vitória e uma desconexão acontecem juntas
+func removerPartida(p *Partida) bool {
+	if !partidasAtivas.removerSe(p.ID, p) {
+		return false
+	}
+	partidaPorJogador.removerSe(p.A.ID, p)
+	partidaPorJogador.removerSe(p.B.ID, p)
+	return true
+}
+
+func partidaPorID(id string) *Partida {
+	p, _ := partidasAtivas.obter(id)
+	return p
+}
+
+// retorna a partida em que o jogador está
+func encontrarPartidaPorJogador(jogadorID string) *Partida {
+	p, _ := partidaPorJogador.obter(jogadorID)
+	return p
+}
+
+// cópia da lista de partidas em andamento
+func partidasEmAndamento() []*Partida {
+	return partidasAtivas.valores()
+}
@@ -0,0 +1,95 @@
+// registro_test.go
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// quantidades de jogadores conectados medidas nos benchmarks
+var escalasRegistro = []int{1000, 5000, 10000}
+
+// recria os registros com n jogadores conectados, pareados em n/2 partidas; os registros
+// anteriores voltam ao fim do benchmark, para não vazar para os testes seguintes
+func popularRegistros(b *testing.B, n int) []*Jogador {
+	porID, porNome, ativas, porJogador := jogadoresPorID, jogadoresPorNome, partidasAtivas, partidaPorJogador
+	b.Cleanup(func() {
+		jogadoresPorID, jogadoresPorNome, partidasAtivas, partidaPorJogador = porID, porNome, ativas, porJogador
+	})
+	jogadoresPorID = novoMapaFragmentado[*Jogador]()
+	jogadoresPorNome = novoMapaFragmentado[*Jogador]()
+	partidasAtivas = novoMapaFragmentado[*Partida]()
+	partidaPorJogador = novoMapaFragmentado[*Partida]()
+	jogadores := make([]*Jogador, n)
+	for i := range jogadores {
+		jogadores[i] = &Jogador{ID: strconv.Itoa(i), Nome: fmt.Sprintf("jogador-%d", i)}
+		registrarJogador(jogadores[i])
+	}
+	for i := 0; i+1 < n; i += 2 {
+		registrarPartida(&Partida{ID: fmt.Sprintf("partida-%d", i), A: jogadores[i], B: jogadores[i+1]})
+	}
+	return jogadores
+}
+
+// conexão e saída de jogadores com o servidor já cheio
+func BenchmarkRegistrarJogador(b *testing.B) {
+	for _, n := range escalasRegistro {
+		b.Run(fmt.Sprintf("jogadores=%d", n), func(b *testing.B) {
+			popularRegistros(b, n)
+			var seq atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := seq.Add(1)
+					j := &Jogador{ID: "novo-" + strconv.FormatInt(i, 10), Nome: "novo-" + strconv.FormatInt(i, 10)}
+					if !registrarJogador(j) {
+						b.Error("nome livre recusado")
+					}
+					removerRegistroJogador(j)
+				}
+			})
+		})
+	}
+}
+
+// a consulta feita a cada ação de jogo; "varredura" é a busca linear por todas as partidas
+// que o índice jogador -> partida substituiu
+func BenchmarkEncontrarPartidaPorJogador(b *testing.B) {
+	for _, n := range escalasRegistro {
+		b.Run(fmt.Sprintf("jogadores=%d/indice", n), func(b *testing.B) {
+			jogadores := popularRegistros(b, n)
+			var seq atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					j := jogadores[int(seq.Add(1))%n]
+					if encontrarPartidaPorJogador(j.ID) == nil {
+						b.Error("partida não encontrada")
+					}
+				}
+			})
+		})
+		b.Run(fmt.Sprintf("jogadores=%d/varredura", n), func(b *testing.B) {
+			jogadores := popularRegistros(b, n)
+			var seq atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					j := jogadores[int(seq.Add(1))%n]
+					var achada *Partida
+					for _, p := range partidasEmAndamento() {
+						if p.A.ID == j.ID || p.B.ID == j.ID {
+							achada = p
+							break
+						}
+					}
+					if achada == nil {
+						b.Error("partida não encontrada")
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// replay.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/motor"
+)
+
+// diretório onde os arquivos de replay são gravados
+var diretorioReplays = "replays"
+
+// evento registrado no log de uma partida (uma linha do arquivo de replay)
+type EventoPartida struct {
+	Seq     int       `json:"seq"`
+	Momento time.Time `json:"momento"`
+	Tipo    string    `json:"tipo"`              // inicio, compra, acao, dano, turno, tempo, desconexao, fim
+	Jogador string    `json:"jogador,omitempty"` // ID do jogador envolvido
+
+	Acao     *AcaoJogo        `json:"acao,omitempty"`     // ação aplicada (tipo "acao")
+	Cartas   []int            `json:"cartas,omitempty"`   // cartas compradas (tipo "compra")
+	Valor    int              `json:"valor,omitempty"`    // dano causado (tipo "dano")
+	Vida     map[string]int   `json:"vida,omitempty"`     // vida após o evento
+	Mao      map[string][]int `json:"mao,omitempty"`      // mãos finais (tipo "fim")
+	Turno    string           `json:"turno,omitempty"`    // jogador com a vez após o evento
+	Vencedor string           `json:"vencedor,omitempty"` // ID do vencedor (tipo "fim")
+	Motivo   string           `json:"motivo,omitempty"`   // motivo do encerramento (tipo "fim")
+
+	// presentes apenas no evento "inicio"
+	Semente   int64           `json:"semente,omitempty"`
+	Jogadores []JogadorReplay `json:"jogadores,omitempty"`
+}
+
+// identifica um jogador no arquivo de replay
+type JogadorReplay struct {
+	ID   string `json:"id"`
+	Nome string `json:"nome"`
+}
+
+// acrescenta um evento ao log da partida (goroutine da partida)
+func (p *Partida) registrar(ev EventoPartida) {
+	ev.Seq = len(p.Eventos) + 1
+	ev.Momento = time.Now()
+	p.Eventos = append(p.Eventos, ev)
+}
+
+// registra o início da partida e as cartas compradas por cada jogador
+// (antes de a goroutine da partida começar)
+func (p *Partida) registrarInicio() {
+	p.registrar(EventoPartida{
+		Tipo:      "inicio",
+		Semente:   p.Semente,
+		Jogadores: []JogadorReplay{{ID: p.A.ID, Nome: p.A.Nome}, {ID: p.B.ID, Nome: p.B.Nome}},
+		Vida:      copiarVida(p.Vida),
+		Turno:     p.Turno,
+	})
+	for _, jog := range []*Jogador{p.A, p.B} {
+		p.registrar(EventoPartida{Tipo: "compra", Jogador: jog.ID, Cartas: append([]int(nil), p.Mao[jog.ID]...)})
+	}
+}
+
+// registra o estado final da partida, grava o arquivo de replay e repassa o resultado
+// às estatísticas, recompensas, missões e torneios (goroutine da partida)
+func (p *Partida) registrarFim(vencedorID, motivo string) {
+	p.registrar(EventoPartida{
+		Tipo:     "fim",
+		Vida:     copiarVida(p.Vida),
+		Mao:      copiarMaos(p.Mao),
+		Turno:    p.Turno,
+		Vencedor: vencedorID,
+		Motivo:   motivo,
+	})
+	eventos := append([]EventoPartida(nil), p.Eventos...)
+	resultado := p.resultado(vencedorID, motivo)
+	missoes := somarMetricas(metricasFim(eventos), p.missoes)
+	go func() {
+		if err := salvarReplay(p.ID, eventos); err != nil {
+			p.logger(nil).Error("erro ao salvar replay", "erro", err)
+		}
+		registrarEstatisticas(resultado)
+		recompensarPartida(resultado)
+		progredirMissoes(missoes)
+		registrarResultadoTorneio(resultado)
+	}()
+}
+
+// grava os eventos da partida em um arquivo JSON Lines
+func salvarReplay(idPartida string, eventos []EventoPartida) error {
+	if err := os.MkdirAll(diretorioReplays, 0o755); err != nil {
+		return err
+	}
+	caminho := filepath.Join(diretorioReplays, idPartida+".jsonl")
+	f, err := os.Create(caminho)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ev := range eventos {
+		if err := enc.Encode(ev); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	slog.Debug("replay salvo", "match_id", idPartida, "arquivo", caminho)
+	return f.Close()
+}
+
+// lê os eventos de um arquivo de replay
+func carregarReplay(caminho string) ([]EventoPartida, error) {
+	f, err := os.Open(caminho)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var eventos []EventoPartida
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev EventoPartida
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("linha %d: %w", len(eventos)+1, err)
+		}
+		eventos = append(eventos, ev)
+	}
+	return eventos, scanner.Err()
+}
+
+// subcomando "replay": re-simula o arquivo pelo motor do jogo e confere o estado final
+func executarReplay(caminho string) error {
+	eventos, err := carregarReplay(caminho)
+	if err != nil {
+		return err
+	}
+	if err := inicializarCartas(); err != nil {
+		return err
+	}
+
+	final, err := simularReplay(eventos)
+	if err != nil {
+		return err
+	}
+	esperado := eventos[len(eventos)-1]
+	if esperado.Tipo != "fim" {
+		return fmt.Errorf("replay incompleto: último evento é %q", esperado.Tipo)
+	}
+
+	divergencias := compararFim(esperado, final)
+	for _, d := range divergencias {
+		fmt.Println("Divergência:", d)
+	}
+	if len(divergencias) > 0 {
+		return fmt.Errorf("estado final diverge do replay (%d divergências)", len(divergencias))
+	}
+	fmt.Printf("Replay verificado: %d eventos, vencedor %s (%s)\n", len(eventos), final.Vencedor, final.Motivo)
+	return nil
+}
+
+// reconstrói a partida pelo motor: sorteia as mãos iniciais com a semente registrada e
+// aplica as ações do log em ordem sobre o estado inicial. É uma função pura: não cria
+// partidas, jogadores nem goroutines. Retorna o estado final como um evento "fim"
+func simularReplay(eventos []EventoPartida) (EventoPartida, error) {
+	if len(eventos) == 0 || eventos[0].Tipo != "inicio" || len(eventos[0].Jogadores) != 2 {
+		return EventoPartida{}, fmt.Errorf("replay sem evento de início válido")
+	}
+	inicio := eventos[0]
+	a, b := inicio.Jogadores[0].ID, inicio.Jogadores[1].ID
+
+	// mesma ordem de sorteio de novaPartida
+	rng := rand.New(rand.NewSource(inicio.Semente))
+	maos := map[string][]int{a: gerarMaoAleatoria(rng, 5), b: gerarMaoAleatoria(rng, 5)}
+	estado := motor.Novo(a, b, maos[a], maos[b])
+
+	for _, ev := range eventos[1:] {
+		var err error
+		switch ev.Tipo {
+		case "compra":
+			if !reflect.DeepEqual(maos[ev.Jogador], ev.Cartas) {
+				return EventoPartida{}, fmt.Errorf("evento %d: semente %d gerou as cartas %v, replay registra %v", ev.Seq, inicio.Semente, maos[ev.Jogador], ev.Cartas)
+			}
+		case "acao":
+			if ev.Acao == nil {
+				return EventoPartida{}, fmt.Errorf("evento %d: ação inválida", ev.Seq)
+			}
+			// "jogar_carta" e "fim_turno" são os mesmos tipos de ação do motor
+			estado, _, err = motor.Aplicar(estado, motor.Acao{Tipo: ev.Acao.Acao, Jogador: ev.Jogador, Carta: ev.Acao.CartaID})
+		case "tempo":
+			estado, _, err = motor.Aplicar(estado, motor.Acao{Tipo: motor.Tempo, Jogador: ev.Jogador})
+		case "desconexao":
+			estado, _, err = motor.Aplicar(estado, motor.Acao{Tipo: motor.Desconexao, Jogador: ev.Jogador})
+		case "encerramento_admin":
+			estado, _, err = motor.Encerrar(estado, ev.Vencedor)
+		}
+		if err != nil {
+			return EventoPartida{}, fmt.Errorf("evento %d (%s): %w", ev.Seq, ev.Tipo, err)
+		}
+	}
+	if !estado.Encerrada {
+		return EventoPartida{}, fmt.Errorf("a simulação não encerrou a partida")
+	}
+	return EventoPartida{
+		Tipo:     "fim",
+		Vida:     copiarVida(estado.Vida),
+		Mao:      copiarMaos(estado.Mao),
+		Turno:    estado.Turno,
+		Vencedor: estado.Vencedor,
+		Motivo:   estado.Motivo,
+	}, nil
+}
+
+// compara o estado final registrado com o obtido pela simulação
+func compararFim(esperado, obtido EventoPartida) []string {
+	var divergencias []string
+	if esperado.Vencedor != obtido.Vencedor {
+		divergencias = append(divergencias, fmt.Sprintf("vencedor %q, simulação %q", esperado.Vencedor, obtido.Vencedor))
+	}
+	if esperado.Motivo != obtido.Motivo {
+		divergencias = append(divergencias, fmt.Sprintf("motivo %q, simulação %q", esperado.Motivo, obtido.Motivo))
+	}
+	if esperado.Turno != obtido.Turno {
+		divergencias = append(divergencias, fmt.Sprintf("turno %q, simulação %q", esperado.Turno, obtido.Turno))
+	}
+	if !reflect.DeepEqual(esperado.Vida, obtido.Vida) {
+		divergencias = append(divergencias, fmt.Sprintf("vida %v, simulação %v", esperado.Vida, obtido.Vida))
+	}
+	if !reflect.DeepEqual(esperado.Mao, obtido.Mao) {
+		divergencias = append(divergencias, fmt.Sprintf("mãos %v, simulação %v", esperado.Mao, obtido.Mao))
+	}
+	return divergencias
+}
+
+// retorna o ID do outro jogador da partida
+func outroJogador(p *Partida, jogadorID string) string {
+	if p.A.ID == jogadorID {
+		return p.B.ID
+	}
+	return p.A.ID
+}
+
+func copiarVida(vida map[string]int) map[string]int {
+	c := make(map[string]int, len(vida))
+	for k, v := range vida {
+		c[k] = v
+	}
+	return c
+}
+
+func copiarMaos(maos map[string][]int) map[string][]int {
+	c := make(map[string][]int, len(maos))
+	for k, v := range maos {
+		c[k] = append([]int{}, v...)
+	}
+	return c
+}
@@ -0,0 +1,250 @@
+// saida.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// prioridade de uma mensagem na fila de saída
+type prioridade int
+
+const (
+	msgCritica     prioridade = iota // estado da partida, respostas, erros: nunca descartada
+	msgDescartavel                   // chat e avisos: descartada quando o cliente não acompanha
+)
+
+// limites da fila de saída de cada conexão
+var (
+	limiteDescartaveis = 64               // descartáveis pendentes; acima disso as mais antigas caem
+	limiteCriticas     = 1024             // críticas pendentes; acima disso o cliente é desconectado
+	prazoLentidao      = 15 * time.Second // crítica pendente há mais tempo desconecta o cliente
+)
+
+// contadores globais da saída (expostos em /mod saida)
+var (
+	totalDescartadas atomic.Int64 // mensagens descartáveis que caíram
+	totalCoalescidas atomic.Int64 // atualizações de estado substituídas por uma mais nova
+	totalLentos      atomic.Int64 // clientes desconectados por lentidão
+)
+
+// mensagem aguardando o escritor
+type entradaSaida struct {
+	texto       string
+	prioridade  prioridade
+	chave       string // atualizações de estado com a mesma chave substituem as pendentes
+	enfileirada time.Time
+	removida    bool
+}
+
+// fila de saída de uma conexão: os remetentes nunca bloqueiam e a fila nunca é fechada
+// com alguém enviando (fechar apenas marca a fila, e envios posteriores são ignorados)
+type FilaSaida struct {
+	mu           sync.Mutex
+	sinal        chan struct{} // acorda o escritor (capacidade 1)
+	entradas     []*entradaSaida
+	chaves       map[string]*entradaSaida
+	criticas     int
+	descartaveis int
+	fechada      bool
+
+	Descartadas int64 // descartáveis que caíram nesta conexão
+	Coalescidas int64 // atualizações substituídas nesta conexão
+}
+
+func novaFilaSaida() *FilaSaida {
+	return &FilaSaida{sinal: make(chan struct{}, 1), chaves: map[string]*entradaSaida{}}
+}
+
+// enfileira a mensagem; retorna falso se o cliente ficou lento demais e deve ser desconectado
+func (f *FilaSaida) adicionar(texto string, prio prioridade, chave string) bool {
+	agora := time.Now()
+	f.mu.Lock()
+	if f.fechada {
+		f.mu.Unlock()
+		return true
+	}
+	if chave != "" {
+		if e := f.chaves[chave]; e != nil {
+			// atualização de estado: substitui a pendente, mantendo a posição
+			e.texto = texto
+			f.Coalescidas++
+			f.mu.Unlock()
+			totalCoalescidas.Add(1)
+			return true
+		}
+	}
+	e := &entradaSaida{texto: texto, prioridade: prio, chave: chave, enfileirada: agora}
+	f.entradas = append(f.entradas, e)
+	if chave != "" {
+		f.chaves[chave] = e
+	}
+	if prio == msgCritica {
+		f.criticas++
+	} else {
+		f.descartaveis++
+		if f.descartaveis > limiteDescartaveis {
+			f.descartarMaisAntiga()
+		}
+	}
+	lento := f.criticas > limiteCriticas || agora.Sub(f.criticaMaisAntiga(agora)) > prazoLentidao
+	f.mu.Unlock()
+
+	select {
+	case f.sinal <- struct{}{}:
+	default:
+	}
+	return !lento
+}
+
+// remove a descartável pendente mais antiga (deve ser chamada com f.mu travado)
+func (f *FilaSaida) descartarMaisAntiga() {
+	for _, e := range f.entradas {
+		if !e.removida && e.prioridade == msgDescartavel {
+			e.removida = true
+			if e.chave != "" {
+				delete(f.chaves, e.chave)
+			}
+			f.descartaveis--
+			f.Descartadas++
+			totalDescartadas.Add(1)
+			return
+		}
+	}
+}
+
+// momento da crítica pendente mais antiga, ou agora se não houver
+// (deve ser chamada com f.mu travado)
+func (f *FilaSaida) criticaMaisAntiga(agora time.Time) time.Time {
+	for _, e := range f.entradas {
+		if !e.removida && e.prioridade == msgCritica {
+			return e.enfileirada
+		}
+	}
+	return agora
+}
+
+// retira todas as mensagens pendentes, na ordem de chegada
+func (f *FilaSaida) retirar() (mensagens []string, fechada bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.entradas {
+		if !e.removida {
+			mensagens = append(mensagens, e.texto)
+		}
+	}
+	f.entradas = f.entradas[:0]
+	clear(f.chaves)
+	f.criticas, f.descartaveis = 0, 0
+	return mensagens, f.fechada
+}
+
+// marca a fila como fechada e acorda o escritor para que termine
+func (f *FilaSaida) fechar() {
+	f.mu.Lock()
+	f.fechada = true
+	f.mu.Unlock()
+	select {
+	case f.sinal <- struct{}{}:
+	default:
+	}
+}
+
+// tamanho atual da fila (críticas e descartáveis pendentes)
+func (f *FilaSaida) tamanho() (criticas, descartaveis int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.criticas, f.descartaveis
+}
+
+// envia uma mensagem que nunca é descartada (resultado de comandos, estado da partida)
+func (j *Jogador) enviarMensagem(msg string) {
+	j.enviar(msg, msgCritica, "")
+}
+
+// envia uma mensagem que pode cair se o cliente não acompanhar (chat, avisos)
+func (j *Jogador) enviarDescartavel(msg string) {
+	j.enviar(msg, msgDescartavel, "")
+}
+
+// envia uma atualização de estado: descartável, e uma nova com a mesma chave substitui
+// a pendente em vez de se acumular
+func (j *Jogador) enviarEstado(chave, msg string) {
+	j.enviar(msg, msgDescartavel, chave)
+}
+
+func (j *Jogador) enviar(msg string, prio prioridade, chave string) {
+	j.mu.Lock()
+	fila := j.Saida
+	j.mu.Unlock()
+	if fila != nil && !fila.adicionar(msg, prio, chave) {
+		j.desconectarLento(fila)
+	}
+}
+
+// derruba a conexão de um cliente que não acompanha as mensagens críticas; a leitura
+// falha em seguida e o jogador segue o fluxo normal de queda (conexao.go)
+func (j *Jogador) desconectarLento(fila *FilaSaida) {
+	j.mu.Lock()
+	if j.Saida != fila || j.desconectado {
+		j.mu.Unlock()
+		return // já tratado
+	}
+	j.fecharSaida()
+	conn := j.Conexao
+	j.mu.Unlock()
+
+	totalLentos.Add(1)
+	criticas, _ := fila.tamanho()
+	j.logger().Warn("jogador desconectado por lentidão", "criticas_pendentes", criticas)
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// /mod saida: contadores da fila de saída e jogadores com mais descartes
+func mostrarSaida(j *Jogador) {
+	type linhaSaida struct {
+		nome                     string
+		criticas, descartaveis   int
+		descartadas, coalescidas int64
+	}
+	lista := jogadoresConectados()
+	linhas := make([]linhaSaida, 0, len(lista))
+	for _, outro := range lista {
+		outro.mu.Lock()
+		fila := outro.Saida
+		outro.mu.Unlock()
+		if fila == nil {
+			continue
+		}
+		l := linhaSaida{nome: outro.Nome}
+		fila.mu.Lock()
+		l.criticas, l.descartaveis = fila.criticas, fila.descartaveis
+		l.descartadas, l.coalescidas = fila.Descartadas, fila.Coalescidas
+		fila.mu.Unlock()
+		linhas = append(linhas, l)
+	}
+	sort.Slice(linhas, func(a, b int) bool {
+		if linhas[a].descartadas != linhas[b].descartadas {
+			return linhas[a].descartadas > linhas[b].descartadas
+		}
+		return linhas[a].nome < linhas[b].nome
+	})
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Saída: %d descartadas, %d coalescidas, %d clientes desconectados por lentidão\n",
+		totalDescartadas.Load(), totalCoalescidas.Load(), totalLentos.Load()))
+	for i, l := range linhas {
+		if i == 10 {
+			break
+		}
+		builder.WriteString(fmt.Sprintf("  %-16s pendentes %d/%d, descartadas %d, coalescidas %d\n",
+			l.nome, l.criticas, l.descartaveis, l.descartadas, l.coalescidas))
+	}
+	j.enviarMensagem(builder.String())
+}
@@ -0,0 +1,91 @@
+// tls.go
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/certificado"
+)
+
+// arquivos PEM do TLS do lobby (-tls-cert, -tls-chave); sem eles o lobby fala TCP puro.
+// Com -tls-ca, o cliente precisa apresentar um certificado assinado por essa CA (TLS mútuo)
+var (
+	certificadoTLS string
+	chaveTLS       string
+	caClientesTLS  string
+)
+
+// apertos de mão TLS que falharam (certificado recusado, cliente que não fala TLS, prazo)
+var totalFalhasTLS atomic.Int64
+
+// abre o listener do lobby, com TLS quando configurado
+func escutarLobby(endereco string) (net.Listener, string, error) {
+	if certificadoTLS == "" && chaveTLS == "" {
+		if caClientesTLS != "" {
+			return nil, "", errors.New("-tls-ca exige -tls-cert e -tls-chave")
+		}
+		ln, err := net.Listen("tcp", endereco)
+		return ln, "TCP", err
+	}
+	if certificadoTLS == "" || chaveTLS == "" {
+		return nil, "", errors.New("-tls-cert e -tls-chave devem ser informados juntos")
+	}
+	cfg, err := certificado.Servidor(certificadoTLS, chaveTLS, caClientesTLS)
+	if err != nil {
+		return nil, "", err
+	}
+	ln, err := tls.Listen("tcp", endereco, cfg)
+	modo := "TLS"
+	if caClientesTLS != "" {
+		modo = "TLS mútuo"
+	}
+	return ln, modo, err
+}
+
+// conclui o aperto de mão de uma conexão TLS dentro do prazo do nome; conexões TCP puras
+// passam direto
+func concluirTLS(conn net.Conn) error {
+	c, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	c.SetDeadline(time.Now().Add(prazoNome))
+	defer c.SetDeadline(time.Time{})
+	if err := c.Handshake(); err != nil {
+		totalFalhasTLS.Add(1)
+		return err
+	}
+	return nil
+}
+
+// subcomando gerar-certificado [diretório] [hosts separados por vírgula]
+func gerarCertificado(dir, hosts string) error {
+	if dir == "" {
+		dir = "certs"
+	}
+	var lista []string
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			lista = append(lista, h)
+		}
+	}
+	arquivos, err := certificado.Gerar(dir, lista)
+	if err != nil {
+		return err
+	}
+	if len(lista) == 0 {
+		lista = certificado.HostsPadrao
+	}
+	fmt.Printf("Certificados de desenvolvimento gerados (válidos para %s):\n", strings.Join(lista, ", "))
+	for _, a := range arquivos {
+		fmt.Println("  " + a)
+	}
+	fmt.Printf("Use: servidor -tls-cert %s -tls-chave %s [-tls-ca %s]\n", arquivos[2], arquivos[3], arquivos[0])
+	return nil
+}
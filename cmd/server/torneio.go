@@ -0,0 +1,719 @@
+// torneio.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempo que um jogador tem para aparecer antes de perder o confronto por W.O.
+var prazoWO = 2 * time.Minute
+
+// torneios não encerrados que cada jogador e o servidor inteiro podem ter
+const (
+	maxTorneiosPorCriador = 3
+	maxTorneios           = 100
+)
+
+// formatos de torneio suportados
+const (
+	formatoEliminatoria = "eliminatoria" // eliminação simples
+	formatoDupla        = "dupla"        // eliminação dupla
+	formatoSuico        = "suico"        // sistema suíço
+)
+
+// estados de um torneio
+const (
+	torneioInscricoes = "inscricoes"
+	torneioAndamento  = "andamento"
+	torneioEncerrado  = "encerrado"
+)
+
+// representa um torneio organizado no servidor
+type Torneio struct {
+	ID       string
+	Nome     string
+	Formato  string
+	MelhorDe int    // número máximo de partidas por confronto (ímpar)
+	Criador  string // nome de quem criou e pode iniciar o torneio
+	Estado   string
+
+	Participantes map[string]*Participante
+	Ordem         []string // nomes na ordem de chaveamento (sorteada ao iniciar)
+	Rodada        int
+	TotalRodadas  int // apenas no sistema suíço
+	Confrontos    []*Confronto
+	Campeao       string
+	rng           *rand.Rand
+}
+
+// situação de um jogador dentro do torneio
+type Participante struct {
+	Nome      string
+	Vitorias  int // confrontos vencidos (byes incluídos)
+	Derrotas  int // confrontos perdidos
+	Byes      int
+	Oponentes map[string]bool
+}
+
+// confronto de uma rodada, disputado em melhor de N partidas
+type Confronto struct {
+	A, B         string // nomes dos jogadores; B vazio indica bye
+	Chave        string // "vencedores", "perdedores" ou "final" na eliminação dupla
+	VitoriasA    int
+	VitoriasB    int
+	Vencedor     string
+	Decidido     bool
+	WO           bool      // decidido por ausência
+	PartidaAtual string    // ID da partida em andamento
+	Prazo        time.Time // limite para a próxima partida começar
+}
+
+var (
+	torneiosMu       sync.Mutex
+	torneios         = map[string]*Torneio{}
+	proximoTorneio   int
+	partidasTorneios = map[string]*Confronto{} // ID da partida -> confronto
+	confrontoTorneio = map[*Confronto]*Torneio{}
+)
+
+// interpreta os subcomandos de /torneio
+func tratarTorneio(j *Jogador, args []string) {
+	if len(args) == 0 {
+		j.enviarMensagem("Uso: /torneio criar <nome> [eliminatoria|dupla|suico] [melhor_de], /torneio inscrever <id>, /torneio sair <id>, /torneio iniciar <id>, /torneio lista, /torneio ver <id>")
+		return
+	}
+	switch args[0] {
+	case "criar":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /torneio criar <nome> [eliminatoria|dupla|suico] [melhor_de]")
+			return
+		}
+		formato := formatoEliminatoria
+		if len(args) >= 3 {
+			formato = args[2]
+		}
+		melhorDe := 1
+		if len(args) >= 4 {
+			n, err := strconv.Atoi(args[3])
+			if err != nil || n < 1 || n%2 == 0 {
+				j.enviarMensagem("melhor_de deve ser um número ímpar (1, 3, 5...)")
+				return
+			}
+			melhorDe = n
+		}
+		criarTorneio(j, args[1], formato, melhorDe)
+	case "inscrever", "sair", "iniciar", "ver":
+		if len(args) < 2 {
+			j.enviarMensagem(fmt.Sprintf("Uso: /torneio %s <id>", args[0]))
+			return
+		}
+		torneiosMu.Lock()
+		defer torneiosMu.Unlock()
+		t := torneios[args[1]]
+		if t == nil {
+			j.enviarMensagem("Torneio não encontrado")
+			return
+		}
+		switch args[0] {
+		case "inscrever":
+			t.inscrever(j)
+		case "sair":
+			t.desinscrever(j)
+		case "iniciar":
+			t.iniciar(j)
+		case "ver":
+			j.enviarMensagem(t.classificacao())
+		}
+	case "lista":
+		listarTorneios(j)
+	default:
+		j.enviarMensagem("Subcomando de torneio desconhecido")
+	}
+}
+
+// cria um torneio com inscrições abertas
+func criarTorneio(j *Jogador, nome, formato string, melhorDe int) {
+	if formato != formatoEliminatoria && formato != formatoDupla && formato != formatoSuico {
+		j.enviarMensagem("Formato inválido: use eliminatoria, dupla ou suico")
+		return
+	}
+	torneiosMu.Lock()
+	abertos, doCriador := 0, 0
+	for _, t := range torneios {
+		if t.Estado != torneioEncerrado {
+			abertos++
+			if t.Criador == j.Nome {
+				doCriador++
+			}
+		}
+	}
+	if doCriador >= maxTorneiosPorCriador || abertos >= maxTorneios {
+		torneiosMu.Unlock()
+		if doCriador >= maxTorneiosPorCriador {
+			j.enviarMensagem(fmt.Sprintf("Você já tem %d torneios não encerrados", maxTorneiosPorCriador))
+		} else {
+			j.enviarMensagem("O servidor já tem torneios demais em andamento, tente mais tarde")
+		}
+		return
+	}
+	proximoTorneio++
+	t := &Torneio{
+		ID:            fmt.Sprintf("torneio-%d", proximoTorneio),
+		Nome:          nome,
+		Formato:       formato,
+		MelhorDe:      melhorDe,
+		Criador:       j.Nome,
+		Estado:        torneioInscricoes,
+		Participantes: map[string]*Participante{},
+		rng:           rand.New(rand.NewSource(rand.Int63())),
+	}
+	torneios[t.ID] = t
+	torneiosMu.Unlock()
+
+	j.logger().Info("torneio criado", "torneio", t.ID, "nome", nome, "formato", formato, "melhor_de", melhorDe)
+	j.enviarMensagem(fmt.Sprintf("Torneio %s criado (%s, melhor de %d). Inscrições: /torneio inscrever %s | Para começar: /torneio iniciar %s",
+		t.ID, formato, melhorDe, t.ID, t.ID))
+}
+
+// lista os torneios existentes
+func listarTorneios(j *Jogador) {
+	torneiosMu.Lock()
+	defer torneiosMu.Unlock()
+	if len(torneios) == 0 {
+		j.enviarMensagem("Nenhum torneio criado")
+		return
+	}
+	ids := make([]string, 0, len(torneios))
+	for id := range torneios {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return torneios[ids[a]].criadoAntes(torneios[ids[b]]) })
+
+	var builder strings.Builder
+	builder.WriteString("Torneios:\n")
+	for _, id := range ids {
+		t := torneios[id]
+		builder.WriteString(fmt.Sprintf("  %s: %s (%s, melhor de %d) | %s | %d inscritos\n",
+			t.ID, t.Nome, t.Formato, t.MelhorDe, t.Estado, len(t.Participantes)))
+	}
+	j.enviarMensagem(builder.String())
+}
+
+// ordena os torneios pelo número sequencial do ID
+func (t *Torneio) criadoAntes(outro *Torneio) bool {
+	a, _ := strconv.Atoi(strings.TrimPrefix(t.ID, "torneio-"))
+	b, _ := strconv.Atoi(strings.TrimPrefix(outro.ID, "torneio-"))
+	return a < b
+}
+
+// inscreve o jogador (deve ser chamada com torneiosMu travado)
+func (t *Torneio) inscrever(j *Jogador) {
+	if t.Estado != torneioInscricoes {
+		j.enviarMensagem("As inscrições deste torneio estão encerradas")
+		return
+	}
+	if t.Participantes[j.Nome] != nil {
+		j.enviarMensagem("Você já está inscrito neste torneio")
+		return
+	}
+	t.Participantes[j.Nome] = &Participante{Nome: j.Nome, Oponentes: map[string]bool{}}
+	j.enviarMensagem(fmt.Sprintf("Inscrito no torneio %s (%d inscritos)", t.Nome, len(t.Participantes)))
+}
+
+// cancela a inscrição do jogador (deve ser chamada com torneiosMu travado)
+func (t *Torneio) desinscrever(j *Jogador) {
+	if t.Estado != torneioInscricoes {
+		j.enviarMensagem("O torneio já começou")
+		return
+	}
+	if t.Participantes[j.Nome] == nil {
+		j.enviarMensagem("Você não está inscrito neste torneio")
+		return
+	}
+	delete(t.Participantes, j.Nome)
+	j.enviarMensagem(fmt.Sprintf("Inscrição no torneio %s cancelada", t.Nome))
+}
+
+// encerra as inscrições e cria a primeira rodada (deve ser chamada com torneiosMu travado)
+func (t *Torneio) iniciar(j *Jogador) {
+	if j.Nome != t.Criador {
+		j.enviarMensagem("Apenas quem criou o torneio pode iniciá-lo")
+		return
+	}
+	if t.Estado != torneioInscricoes {
+		j.enviarMensagem("O torneio já começou")
+		return
+	}
+	if len(t.Participantes) < 2 {
+		j.enviarMensagem("São necessários pelo menos 2 inscritos")
+		return
+	}
+
+	t.Ordem = make([]string, 0, len(t.Participantes))
+	for nome := range t.Participantes {
+		t.Ordem = append(t.Ordem, nome)
+	}
+	sort.Strings(t.Ordem)
+	t.rng.Shuffle(len(t.Ordem), func(a, b int) { t.Ordem[a], t.Ordem[b] = t.Ordem[b], t.Ordem[a] })
+	if t.Formato == formatoSuico {
+		t.TotalRodadas = int(math.Ceil(math.Log2(float64(len(t.Ordem)))))
+	}
+	t.Estado = torneioAndamento
+	slog.Info("torneio iniciado", "torneio", t.ID, "participantes", len(t.Ordem))
+	t.avisar(fmt.Sprintf("Torneio %s começou com %d participantes!", t.Nome, len(t.Ordem)))
+	t.proximaRodada()
+}
+
+// envia uma mensagem a todos os participantes conectados (deve ser chamada com torneiosMu travado)
+func (t *Torneio) avisar(msg string) {
+	for _, nome := range t.Ordem {
+		if j := jogadorPorNome(nome); j != nil {
+			j.enviarMensagem(msg)
+		}
+	}
+}
+
+// gera os confrontos da próxima rodada ou encerra o torneio
+// (deve ser chamada com torneiosMu travado)
+func (t *Torneio) proximaRodada() {
+	var confrontos []*Confronto
+	var aguardando []string // eliminação dupla: sozinhos numa chave, esperando a outra
+	switch t.Formato {
+	case formatoEliminatoria:
+		vivos := t.filtrar(func(p *Participante) bool { return p.Derrotas == 0 })
+		if len(vivos) <= 1 {
+			t.encerrar(vivos)
+			return
+		}
+		confrontos = t.parear(vivos, "")
+	case formatoDupla:
+		vencedores := t.filtrar(func(p *Participante) bool { return p.Derrotas == 0 })
+		perdedores := t.filtrar(func(p *Participante) bool { return p.Derrotas == 1 })
+		switch {
+		case len(vencedores)+len(perdedores) <= 1:
+			t.encerrar(append(vencedores, perdedores...))
+			return
+		case len(vencedores) == 1 && len(perdedores) == 1:
+			confrontos = []*Confronto{{A: vencedores[0], B: perdedores[0], Chave: "final"}}
+		default:
+			// uma chave com um só jogador fica parada até a outra chegar a um, em vez de
+			// dar a ele byes seguidos contados como vitórias
+			if len(vencedores) > 1 {
+				confrontos = t.parear(vencedores, "vencedores")
+			} else {
+				aguardando = vencedores
+			}
+			if len(perdedores) > 1 {
+				confrontos = append(confrontos, t.parear(perdedores, "perdedores")...)
+			} else {
+				aguardando = append(aguardando, perdedores...)
+			}
+		}
+	case formatoSuico:
+		if t.Rodada >= t.TotalRodadas {
+			t.encerrar(t.classificados()[:1])
+			return
+		}
+		confrontos = t.parearSuico()
+	}
+
+	t.Rodada++
+	t.Confrontos = confrontos
+	prazo := time.Now().Add(prazoWO)
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\n============================\nTorneio %s - rodada %d\n", t.Nome, t.Rodada))
+	for _, c := range confrontos {
+		c.Prazo = prazo
+		confrontoTorneio[c] = t
+		if c.B == "" {
+			t.decidir(c, c.A, false)
+			builder.WriteString(fmt.Sprintf("  %s avança (bye)\n", c.A))
+			continue
+		}
+		t.Participantes[c.A].Oponentes[c.B] = true
+		t.Participantes[c.B].Oponentes[c.A] = true
+		builder.WriteString(fmt.Sprintf("  %s x %s%s\n", c.A, c.B, rotuloChave(c.Chave)))
+	}
+	for _, nome := range aguardando {
+		builder.WriteString(fmt.Sprintf("  %s aguarda a outra chave\n", nome))
+	}
+	builder.WriteString(fmt.Sprintf("Quem não estiver disponível até %s perde por W.O.\n============================", prazo.Format("15:04:05")))
+	t.avisar(builder.String())
+	t.avancar()
+}
+
+func rotuloChave(chave string) string {
+	if chave == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (chave %s)", chave)
+}
+
+// retorna os participantes que satisfazem o filtro, na ordem do chaveamento
+func (t *Torneio) filtrar(filtro func(*Participante) bool) []string {
+	var nomes []string
+	for _, nome := range t.Ordem {
+		if filtro(t.Participantes[nome]) {
+			nomes = append(nomes, nome)
+		}
+	}
+	return nomes
+}
+
+// pareia os jogadores em sequência; com número ímpar, quem teve menos byes folga
+func (t *Torneio) parear(nomes []string, chave string) []*Confronto {
+	if len(nomes) == 0 {
+		return nil
+	}
+	nomes = append([]string(nil), nomes...)
+	var confrontos []*Confronto
+	if len(nomes)%2 == 1 {
+		folga := len(nomes) - 1
+		for i := len(nomes) - 1; i >= 0; i-- {
+			if t.Participantes[nomes[i]].Byes < t.Participantes[nomes[folga]].Byes {
+				folga = i
+			}
+		}
+		confrontos = append(confrontos, &Confronto{A: nomes[folga], Chave: chave})
+		nomes = append(nomes[:folga], nomes[folga+1:]...)
+	}
+	for i := 0; i+1 < len(nomes); i += 2 {
+		confrontos = append(confrontos, &Confronto{A: nomes[i], B: nomes[i+1], Chave: chave})
+	}
+	return confrontos
+}
+
+// pareamento suíço: jogadores com pontuação próxima, evitando revanches
+func (t *Torneio) parearSuico() []*Confronto {
+	nomes := t.classificados()
+	var confrontos []*Confronto
+	if len(nomes)%2 == 1 {
+		// o último colocado que ainda não folgou recebe o bye
+		folga := len(nomes) - 1
+		for i := len(nomes) - 1; i >= 0; i-- {
+			if t.Participantes[nomes[i]].Byes == 0 {
+				folga = i
+				break
+			}
+		}
+		confrontos = append(confrontos, &Confronto{A: nomes[folga]})
+		nomes = append(nomes[:folga], nomes[folga+1:]...)
+	}
+	pareado := map[string]bool{}
+	for i, nome := range nomes {
+		if pareado[nome] {
+			continue
+		}
+		oponente := ""
+		for _, outro := range nomes[i+1:] {
+			if pareado[outro] {
+				continue
+			}
+			if oponente == "" {
+				oponente = outro // aceita revanche se não houver alternativa
+			}
+			if !t.Participantes[nome].Oponentes[outro] {
+				oponente = outro
+				break
+			}
+		}
+		if oponente == "" {
+			continue
+		}
+		pareado[nome], pareado[oponente] = true, true
+		confrontos = append(confrontos, &Confronto{A: nome, B: oponente})
+	}
+	return confrontos
+}
+
+// participantes ordenados pela classificação: vitórias, derrotas, força dos oponentes (Buchholz)
+func (t *Torneio) classificados() []string {
+	nomes := append([]string(nil), t.Ordem...)
+	sort.SliceStable(nomes, func(a, b int) bool {
+		pa, pb := t.Participantes[nomes[a]], t.Participantes[nomes[b]]
+		if pa.Vitorias != pb.Vitorias {
+			return pa.Vitorias > pb.Vitorias
+		}
+		if pa.Derrotas != pb.Derrotas {
+			return pa.Derrotas < pb.Derrotas
+		}
+		return t.buchholz(pa) > t.buchholz(pb)
+	})
+	return nomes
+}
+
+// soma das vitórias dos oponentes enfrentados
+func (t *Torneio) buchholz(p *Participante) int {
+	soma := 0
+	for oponente := range p.Oponentes {
+		soma += t.Participantes[oponente].Vitorias
+	}
+	return soma
+}
+
+// encerra o torneio com o campeão (se houver) e avisa os participantes
+// (deve ser chamada com torneiosMu travado)
+func (t *Torneio) encerrar(restantes []string) {
+	t.Estado = torneioEncerrado
+	t.Confrontos = nil
+	msg := fmt.Sprintf("\n============================\nTorneio %s encerrado sem campeão\n============================", t.Nome)
+	if len(restantes) > 0 {
+		t.Campeao = restantes[0]
+		msg = fmt.Sprintf("\n============================\n%s é o campeão do torneio %s!\n============================", t.Campeao, t.Nome)
+	}
+	slog.Info("torneio encerrado", "torneio", t.ID, "campeao", t.Campeao)
+	t.avisar(msg)
+}
+
+// marca o confronto como decidido e atualiza os participantes
+// (deve ser chamada com torneiosMu travado)
+func (t *Torneio) decidir(c *Confronto, vencedor string, wo bool) {
+	c.Decidido = true
+	c.Vencedor = vencedor
+	c.WO = wo
+	c.PartidaAtual = ""
+	delete(confrontoTorneio, c)
+
+	if c.B == "" {
+		t.Participantes[c.A].Vitorias++
+		t.Participantes[c.A].Byes++
+		return
+	}
+	for _, nome := range []string{c.A, c.B} {
+		if nome == vencedor {
+			t.Participantes[nome].Vitorias++
+		} else {
+			t.Participantes[nome].Derrotas++
+		}
+	}
+}
+
+// inicia as partidas pendentes, aplica W.O. vencido o prazo e avança a rodada
+// quando todos os confrontos estiverem decididos (deve ser chamada com torneiosMu travado)
+func (t *Torneio) avancar() {
+	if t.Estado != torneioAndamento {
+		return
+	}
+	agora := time.Now()
+	for _, c := range t.Confrontos {
+		if c.Decidido || c.PartidaAtual != "" {
+			continue
+		}
+		if t.iniciarPartida(c) {
+			continue
+		}
+		if agora.After(c.Prazo) {
+			t.aplicarWO(c)
+		}
+	}
+	for _, c := range t.Confrontos {
+		if !c.Decidido {
+			return
+		}
+	}
+	t.proximaRodada()
+}
+
+// tenta criar a próxima partida do confronto; retorna false se algum jogador não está disponível
+// (deve ser chamada com torneiosMu travado)
+func (t *Torneio) iniciarPartida(c *Confronto) bool {
+	a, b := jogadorPorNome(c.A), jogadorPorNome(c.B)
+	if a == nil || b == nil || !reservarParaPartida(a) {
+		return false
+	}
+	if !reservarParaPartida(b) {
+		a.mu.Lock()
+		a.EmPartida = false
+		a.mu.Unlock()
+		return false
+	}
+	pararDeAssistir(a)
+	pararDeAssistir(b)
+	p := criarPartida(a, b, false)
+	if p == nil {
+		return false // um dos dois saiu durante a criação; o W.O. decide no prazo
+	}
+	c.PartidaAtual = p.ID
+	partidasTorneios[p.ID] = c
+
+	jogo := c.VitoriasA + c.VitoriasB + 1
+	msg := fmt.Sprintf("Torneio %s: partida %d do confronto %s x %s (placar %d x %d, melhor de %d)",
+		t.Nome, jogo, c.A, c.B, c.VitoriasA, c.VitoriasB, t.MelhorDe)
+	a.enviarMensagem(msg)
+	b.enviarMensagem(msg)
+	return true
+}
+
+// decide o confronto contra quem não apareceu (deve ser chamada com torneiosMu travado)
+func (t *Torneio) aplicarWO(c *Confronto) {
+	presenteA := jogadorPorNome(c.A) != nil
+	presenteB := jogadorPorNome(c.B) != nil
+	switch {
+	case presenteA && !presenteB:
+		t.decidir(c, c.A, true)
+	case presenteB && !presenteA:
+		t.decidir(c, c.B, true)
+	case !presenteA && !presenteB:
+		// ninguém apareceu: os dois perdem o confronto
+		t.decidir(c, "", true)
+	default:
+		// os dois estão conectados mas ocupados: quem estiver livre vence
+		a, b := jogadorPorNome(c.A), jogadorPorNome(c.B)
+		a.mu.Lock()
+		ocupadoA := a.EmPartida
+		a.mu.Unlock()
+		b.mu.Lock()
+		ocupadoB := b.EmPartida
+		b.mu.Unlock()
+		switch {
+		case ocupadoA && !ocupadoB:
+			t.decidir(c, c.B, true)
+		case ocupadoB && !ocupadoA:
+			t.decidir(c, c.A, true)
+		default:
+			t.decidir(c, "", true)
+		}
+	}
+	t.avisar(fmt.Sprintf("Torneio %s: confronto %s x %s decidido por W.O. (vencedor: %s)", t.Nome, c.A, c.B, nomeOuNinguem(c.Vencedor)))
+}
+
+func nomeOuNinguem(nome string) string {
+	if nome == "" {
+		return "ninguém"
+	}
+	return nome
+}
+
+// contabiliza o resultado de uma partida de torneio encerrada
+func registrarResultadoTorneio(r ResultadoPartida) {
+	torneiosMu.Lock()
+	defer torneiosMu.Unlock()
+	c := partidasTorneios[r.ID]
+	if c == nil {
+		return
+	}
+	delete(partidasTorneios, r.ID)
+	t := confrontoTorneio[c]
+	if t == nil {
+		return
+	}
+
+	c.PartidaAtual = ""
+	c.Prazo = time.Now().Add(prazoWO)
+	switch r.Vencedor {
+	case c.A:
+		c.VitoriasA++
+	case c.B:
+		c.VitoriasB++
+	}
+	necessarias := t.MelhorDe/2 + 1
+	switch {
+	case c.VitoriasA >= necessarias:
+		t.decidir(c, c.A, false)
+	case c.VitoriasB >= necessarias:
+		t.decidir(c, c.B, false)
+	}
+	if c.Decidido {
+		t.avisar(fmt.Sprintf("Torneio %s: %s venceu o confronto contra %s (%d x %d)",
+			t.Nome, c.Vencedor, outroDoConfronto(c, c.Vencedor), max(c.VitoriasA, c.VitoriasB), min(c.VitoriasA, c.VitoriasB)))
+	}
+	t.avancar()
+}
+
+func outroDoConfronto(c *Confronto, nome string) string {
+	if c.A == nome {
+		return c.B
+	}
+	return c.A
+}
+
+// verifica periodicamente os confrontos pendentes (jogadores ocupados, W.O., próximas partidas)
+func loopTorneios() {
+	for {
+		time.Sleep(2 * time.Second)
+		torneiosMu.Lock()
+		for _, t := range torneios {
+			t.avancar()
+		}
+		torneiosMu.Unlock()
+	}
+}
+
+// monta a classificação e os confrontos da rodada atual (deve ser chamada com torneiosMu travado)
+func (t *Torneio) classificacao() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Torneio %s (%s) - %s, melhor de %d - %s\n", t.Nome, t.ID, t.Formato, t.MelhorDe, t.Estado))
+	if t.Estado == torneioInscricoes {
+		nomes := make([]string, 0, len(t.Participantes))
+		for nome := range t.Participantes {
+			nomes = append(nomes, nome)
+		}
+		sort.Strings(nomes)
+		builder.WriteString(fmt.Sprintf("Inscritos (%d): %s", len(nomes), strings.Join(nomes, ", ")))
+		return builder.String()
+	}
+	if t.Campeao != "" {
+		builder.WriteString(fmt.Sprintf("Campeão: %s\n", t.Campeao))
+	}
+	if len(t.Confrontos) > 0 {
+		rodada := fmt.Sprintf("Rodada %d", t.Rodada)
+		if t.TotalRodadas > 0 {
+			rodada += fmt.Sprintf(" de %d", t.TotalRodadas)
+		}
+		builder.WriteString(rodada + ":\n")
+		for _, c := range t.Confrontos {
+			builder.WriteString("  " + c.descrever() + "\n")
+		}
+	}
+	builder.WriteString("Classificação:\n")
+	for i, nome := range t.classificados() {
+		p := t.Participantes[nome]
+		situacao := ""
+		if (t.Formato == formatoEliminatoria && p.Derrotas >= 1) || (t.Formato == formatoDupla && p.Derrotas >= 2) {
+			situacao = " (eliminado)"
+		}
+		if t.Formato == formatoSuico {
+			situacao = fmt.Sprintf(" (Buchholz %d)", t.buchholz(p))
+		}
+		builder.WriteString(fmt.Sprintf("  %2d. %s - %d V / %d D%s\n", i+1, nome, p.Vitorias, p.Derrotas, situacao))
+	}
+	return builder.String()
+}
+
+// descreve o andamento de um confronto
+func (c *Confronto) descrever() string {
+	if c.B == "" {
+		return fmt.Sprintf("%s (bye)", c.A)
+	}
+	s := fmt.Sprintf("%s %d x %d %s%s", c.A, c.VitoriasA, c.VitoriasB, c.B, rotuloChave(c.Chave))
+	switch {
+	case c.Decidido && c.WO:
+		return s + fmt.Sprintf(" - W.O., vencedor: %s", nomeOuNinguem(c.Vencedor))
+	case c.Decidido:
+		return s + fmt.Sprintf(" - vencedor: %s", c.Vencedor)
+	case c.PartidaAtual != "":
+		return s + fmt.Sprintf(" - em andamento (%s)", c.PartidaAtual)
+	default:
+		return s + " - aguardando jogadores"
+	}
+}
+
+// marca o jogador como em partida; retorna false se ele já estiver em uma ou tiver saído
+// do servidor
+func reservarParaPartida(j *Jogador) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.EmPartida || j.removido {
+		return false
+	}
+	j.EmPartida = true
+	return true
+}
@@ -0,0 +1,400 @@
+// trocas.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempo máximo de uma sessão de troca
+var prazoTroca = 3 * time.Minute
+
+// tipo de transação das moedas trocadas
+const tipoTroca = "troca"
+
+// o que um lado coloca na troca
+type Oferta struct {
+	Cartas map[string]int `json:"cartas,omitempty"` // carta -> quantidade
+	Moedas int            `json:"moedas,omitempty"`
+}
+
+// sessão de troca entre dois jogadores
+type Troca struct {
+	ID          string             `json:"id"`
+	A           string             `json:"a"` // quem propôs
+	B           string             `json:"b"` // quem foi convidado
+	Ofertas     map[string]*Oferta `json:"ofertas"`
+	Confirmados map[string]bool    `json:"-"`
+	Criada      time.Time          `json:"criada"`
+	Concluida   time.Time          `json:"concluida"`
+	expiracao   *time.Timer
+}
+
+var (
+	trocasMu         sync.Mutex
+	trocasPorJogador = map[string]*Troca{} // nome -> sessão em andamento
+	arquivoTrocasMu  sync.Mutex            // serializa a escrita do log de trocas
+)
+
+// interpreta os subcomandos de /troca
+func tratarTroca(j *Jogador, args []string) {
+	if len(args) == 0 {
+		j.enviarMensagem("Uso: /troca propor <nome>, /troca adicionar <carta> [qtd], /troca remover <carta> [qtd], /troca moedas <valor>, /troca ver, /troca confirmar, /troca cancelar")
+		return
+	}
+	switch args[0] {
+	case "propor":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /troca propor <nome>")
+			return
+		}
+		proporTroca(j, args[1])
+	case "adicionar", "remover":
+		if len(args) < 2 {
+			j.enviarMensagem(fmt.Sprintf("Uso: /troca %s <carta> [qtd]", args[0]))
+			return
+		}
+		qtd := 1
+		if len(args) >= 3 {
+			v, err := strconv.Atoi(args[2])
+			if err != nil || v <= 0 {
+				j.enviarMensagem("Quantidade inválida")
+				return
+			}
+			qtd = v
+		}
+		if args[0] == "remover" {
+			qtd = -qtd
+		}
+		alterarOferta(j, args[1], qtd, -1)
+	case "moedas":
+		if len(args) < 2 {
+			j.enviarMensagem("Uso: /troca moedas <valor>")
+			return
+		}
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			j.enviarMensagem("Valor inválido")
+			return
+		}
+		alterarOferta(j, "", 0, v)
+	case "ver":
+		trocasMu.Lock()
+		t := trocasPorJogador[j.Nome]
+		resumo := ""
+		if t != nil {
+			resumo = t.resumo()
+		}
+		trocasMu.Unlock()
+		if t == nil {
+			j.enviarMensagem("Você não está em uma troca")
+			return
+		}
+		j.enviarMensagem(resumo)
+	case "confirmar":
+		confirmarTroca(j)
+	case "cancelar":
+		cancelarTroca(j.Nome, fmt.Sprintf("%s cancelou a troca", j.Nome))
+	default:
+		j.enviarMensagem("Subcomando de troca desconhecido")
+	}
+}
+
+// abre uma sessão de troca com outro jogador conectado
+func proporTroca(j *Jogador, nome string) {
+	if nome == j.Nome {
+		j.enviarMensagem("Você não pode trocar consigo mesmo")
+		return
+	}
+	d := jogadorPorNome(nome)
+	if d == nil {
+		j.enviarMensagem(fmt.Sprintf("%s não está conectado", nome))
+		return
+	}
+	if bloqueou(nome, j.Nome) {
+		j.enviarErro(erroBloqueado, fmt.Sprintf("%s não aceita trocas suas", nome))
+		return
+	}
+
+	trocasMu.Lock()
+	if trocasPorJogador[j.Nome] != nil {
+		trocasMu.Unlock()
+		j.enviarMensagem("Você já está em uma troca (use /troca cancelar)")
+		return
+	}
+	if trocasPorJogador[nome] != nil {
+		trocasMu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("%s já está em uma troca", nome))
+		return
+	}
+	t := &Troca{
+		ID:          fmt.Sprintf("troca-%d", time.Now().UnixNano()),
+		A:           j.Nome,
+		B:           nome,
+		Ofertas:     map[string]*Oferta{j.Nome: {Cartas: map[string]int{}}, nome: {Cartas: map[string]int{}}},
+		Confirmados: map[string]bool{},
+		Criada:      time.Now(),
+	}
+	trocasPorJogador[j.Nome] = t
+	trocasPorJogador[nome] = t
+	t.expiracao = time.AfterFunc(prazoTroca, func() {
+		expirarTroca(t)
+	})
+	trocasMu.Unlock()
+
+	j.enviarMensagem(fmt.Sprintf("Troca aberta com %s (expira em %s). Use /troca adicionar, /troca moedas e /troca confirmar", nome, prazoTroca))
+	d.enviarMensagem(fmt.Sprintf("[troca] %s abriu uma troca com você. Use /troca adicionar <carta>, /troca moedas <valor>, /troca confirmar ou /troca cancelar", j.Nome))
+}
+
+// altera a oferta do jogador: cartas (delta de quantidade) ou moedas (valor >= 0);
+// qualquer alteração desfaz as confirmações
+func alterarOferta(j *Jogador, carta string, delta, moedas int) {
+	trocasMu.Lock()
+	t := trocasPorJogador[j.Nome]
+	if t == nil {
+		trocasMu.Unlock()
+		j.enviarMensagem("Você não está em uma troca (use /troca propor <nome>)")
+		return
+	}
+	oferta := t.Ofertas[j.Nome]
+
+	// confere a posse agora para avisar cedo; a conferência definitiva é feita ao concluir
+	if carta != "" {
+		novo := oferta.Cartas[carta] + delta
+		colecoesMu.Lock()
+		possui := copiasDe(j.Nome, carta)
+		colecoesMu.Unlock()
+		if novo > possui {
+			trocasMu.Unlock()
+			j.enviarMensagem(fmt.Sprintf("Você tem apenas %d cópia(s) de %s", possui, carta))
+			return
+		}
+		if novo <= 0 {
+			delete(oferta.Cartas, carta)
+		} else {
+			oferta.Cartas[carta] = novo
+		}
+	} else {
+		if saldo := saldoDe(j.Nome); moedas > saldo {
+			trocasMu.Unlock()
+			j.enviarErro(erroSaldoInsuficiente, fmt.Sprintf("você tem apenas %d moedas", saldo))
+			return
+		}
+		oferta.Moedas = moedas
+	}
+	t.Confirmados = map[string]bool{}
+	resumo := t.resumo()
+	outro := t.outro(j.Nome)
+	trocasMu.Unlock()
+
+	j.enviarMensagem(resumo)
+	if d := jogadorPorNome(outro); d != nil {
+		d.enviarMensagem(fmt.Sprintf("[troca] %s alterou a oferta\n%s", j.Nome, resumo))
+	}
+}
+
+// confirma a troca; quando os dois confirmam, ela é executada
+func confirmarTroca(j *Jogador) {
+	trocasMu.Lock()
+	t := trocasPorJogador[j.Nome]
+	if t == nil {
+		trocasMu.Unlock()
+		j.enviarMensagem("Você não está em uma troca")
+		return
+	}
+	t.Confirmados[j.Nome] = true
+	outro := t.outro(j.Nome)
+	if !t.Confirmados[outro] {
+		trocasMu.Unlock()
+		j.enviarMensagem(fmt.Sprintf("Troca confirmada, aguardando %s", outro))
+		if d := jogadorPorNome(outro); d != nil {
+			d.enviarMensagem(fmt.Sprintf("[troca] %s confirmou. Use /troca confirmar para concluir", j.Nome))
+		}
+		return
+	}
+	err := t.executar()
+	t.encerrar()
+	trocasMu.Unlock()
+
+	msg := "[troca] Troca concluída!"
+	if err != nil {
+		msg = "[troca] Troca cancelada: " + err.Error()
+	} else {
+		registrarTroca(t)
+	}
+	for _, nome := range []string{t.A, t.B} {
+		if d := jogadorPorNome(nome); d != nil {
+			d.enviarMensagem(msg)
+		}
+	}
+}
+
+// transfere cartas e moedas de forma atômica: tudo é conferido com as coleções e os saldos
+// travados antes de qualquer alteração, e as travas só são soltas depois que as moedas estão
+// no arquivo de transações e as cartas em colecoes.json (deve ser chamada com trocasMu travado)
+func (t *Troca) executar() error {
+	salvarColecoesMu.Lock()
+	defer salvarColecoesMu.Unlock()
+	colecoesMu.Lock()
+	defer colecoesMu.Unlock()
+	economiaMu.Lock()
+	defer economiaMu.Unlock()
+
+	for nome, oferta := range t.Ofertas {
+		for carta, qtd := range oferta.Cartas {
+			if copiasDe(nome, carta) < qtd {
+				return fmt.Errorf("%s não tem mais %d cópia(s) de %s", nome, qtd, carta)
+			}
+		}
+		if saldos[nome] < oferta.Moedas {
+			return fmt.Errorf("%s não tem mais %d moedas", nome, oferta.Moedas)
+		}
+	}
+
+	// moedas primeiro: são gravadas no arquivo de transações e podem falhar
+	var feitas []Transacao
+	for _, nome := range []string{t.A, t.B} {
+		valor := t.Ofertas[nome].Moedas
+		if valor == 0 {
+			continue
+		}
+		for _, mov := range []struct {
+			nome  string
+			valor int
+		}{{nome, -valor}, {t.outro(nome), valor}} {
+			tr, err := movimentarTravado(mov.nome, tipoTroca, mov.valor, t.ID, "")
+			if err != nil {
+				t.estornar(feitas)
+				return fmt.Errorf("erro ao registrar as moedas")
+			}
+			feitas = append(feitas, tr)
+		}
+	}
+
+	t.moverCartas(1)
+	if err := gravarColecoesTravado(); err != nil {
+		slog.Error("erro ao gravar as coleções da troca", "troca", t.ID, "erro", err)
+		t.moverCartas(-1)
+		t.estornar(feitas)
+		return fmt.Errorf("erro ao registrar as cartas")
+	}
+	t.Concluida = time.Now()
+	salvarSaldos()
+	return nil
+}
+
+// passa as cartas oferecidas para o outro lado; sentido -1 devolve
+// (deve ser chamada com colecoesMu travado)
+func (t *Troca) moverCartas(sentido int) {
+	for nome, oferta := range t.Ofertas {
+		for carta, qtd := range oferta.Cartas {
+			alterarCopias(nome, carta, -qtd*sentido)
+			alterarCopias(t.outro(nome), carta, qtd*sentido)
+		}
+	}
+}
+
+// desfaz as transações de moedas já gravadas, da última para a primeira. Um estorno que
+// falha deixa o saldo divergente do combinado e fica no log para correção manual
+// (deve ser chamada com economiaMu travado)
+func (t *Troca) estornar(feitas []Transacao) {
+	for i := len(feitas) - 1; i >= 0; i-- {
+		if _, err := movimentarTravado(feitas[i].Jogador, tipoEstorno, -feitas[i].Valor, t.ID, ""); err != nil {
+			slog.Error("erro ao estornar moedas da troca", "troca", t.ID, "jogador", feitas[i].Jogador, "valor", -feitas[i].Valor, "erro", err)
+		}
+	}
+}
+
+// cancela a troca do jogador, avisando os dois lados
+func cancelarTroca(nome, motivo string) {
+	trocasMu.Lock()
+	t := trocasPorJogador[nome]
+	if t == nil {
+		trocasMu.Unlock()
+		return
+	}
+	t.encerrar()
+	trocasMu.Unlock()
+	for _, n := range []string{t.A, t.B} {
+		if d := jogadorPorNome(n); d != nil {
+			d.enviarMensagem("[troca] Troca cancelada: " + motivo)
+		}
+	}
+}
+
+// encerra a troca por tempo, se ela ainda estiver aberta
+func expirarTroca(t *Troca) {
+	trocasMu.Lock()
+	atual := trocasPorJogador[t.A] == t
+	trocasMu.Unlock()
+	if atual {
+		cancelarTroca(t.A, "tempo esgotado")
+	}
+}
+
+// remove a sessão dos dois jogadores (deve ser chamada com trocasMu travado)
+func (t *Troca) encerrar() {
+	t.expiracao.Stop()
+	if trocasPorJogador[t.A] == t {
+		delete(trocasPorJogador, t.A)
+	}
+	if trocasPorJogador[t.B] == t {
+		delete(trocasPorJogador, t.B)
+	}
+}
+
+func (t *Troca) outro(nome string) string {
+	if nome == t.A {
+		return t.B
+	}
+	return t.A
+}
+
+// descreve as ofertas e confirmações (deve ser chamada com trocasMu travado)
+func (t *Troca) resumo() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Troca %s x %s:\n", t.A, t.B))
+	for _, nome := range []string{t.A, t.B} {
+		oferta := t.Ofertas[nome]
+		cartas := make([]string, 0, len(oferta.Cartas))
+		for c, n := range oferta.Cartas {
+			cartas = append(cartas, fmt.Sprintf("%s x%d", c, n))
+		}
+		sort.Strings(cartas)
+		itens := strings.Join(cartas, ", ")
+		if oferta.Moedas > 0 {
+			if itens != "" {
+				itens += ", "
+			}
+			itens += fmt.Sprintf("%d moedas", oferta.Moedas)
+		}
+		if itens == "" {
+			itens = "nada"
+		}
+		marca := ""
+		if t.Confirmados[nome] {
+			marca = " (confirmou)"
+		}
+		builder.WriteString(fmt.Sprintf("  %s oferece: %s%s\n", nome, itens, marca))
+	}
+	return builder.String()
+}
+
+// acrescenta a troca concluída ao log de trocas
+func registrarTroca(t *Troca) {
+	linha, err := json.Marshal(t)
+	if err == nil {
+		err = anexarLinha(filepath.Join(diretorioDados, "trocas.jsonl"), linha, &arquivoTrocasMu)
+	}
+	if err != nil {
+		slog.Error("erro ao registrar troca", "troca", t.ID, "erro", err)
+	}
+	slog.Info("troca concluída", "troca", t.ID, "jogador_a", t.A, "jogador_b", t.B)
+}
@@ -1,24 +1,50 @@
-
 package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"math/rand"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/maatheusantanadev/go-card-game/internal/certificado"
 )
 
 var (
 	serverAddr = flag.String("addr", "localhost:4000", "endereço do servidor TCP")
-	clients    = flag.Int("clients",300, "número de clientes simultâneos")
+	clients    = flag.Int("clients", 300, "número de clientes simultâneos")
 	duration   = flag.Duration("duration", 60*time.Second, "duração do teste")
+	escalas    = flag.String("escalas", "", "lista de quantidades de clientes para rodar em sequência (ex: 1000,5000,10000); gera uma tabela de vazão")
+	rampa      = flag.Duration("rampa", 0, "tempo para abrir todas as conexões (0 = todas de uma vez)")
+	fuzz       = flag.Bool("fuzz", false, "envia linhas malformadas em vez de jogar e confere se o servidor continua respondendo")
+	usarTLS    = flag.Bool("tls", false, "conecta via TLS (-tls-ca, -tls-cert e -tls-inseguro também ligam o TLS)")
+	caTLS      = flag.String("tls-ca", "", "CA PEM que assina o certificado do servidor")
+	certTLS    = flag.String("tls-cert", "", "certificado PEM dos clientes, para servidores com TLS mútuo")
+	chaveTLS   = flag.String("tls-chave", "", "chave privada PEM do certificado dos clientes")
+	inseguro   = flag.Bool("tls-inseguro", false, "não verifica o certificado do servidor")
+	configTLS  *tls.Config
 )
 
+// senha enviada pelos bots após o nome; a primeira rodada cria as contas e as seguintes
+// entram nelas
+const senhaBots = "loadbot"
+
+// abre uma conexão com o servidor, com TLS quando configurado (0 = sem prazo)
+func dial(prazo time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: prazo}
+	if configTLS == nil {
+		return d.Dial("tcp", *serverAddr)
+	}
+	return tls.DialWithDialer(d, "tcp", *serverAddr, configTLS)
+}
+
 type stats struct {
 	successConns int64
 	failConns    int64
@@ -26,24 +52,80 @@ type stats struct {
 	actionsErr   int64
 	latencies    []time.Duration
 	winCount     int64
+	elapsed      time.Duration
 }
 
 func main() {
 	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 
+	if *usarTLS || *caTLS != "" || *certTLS != "" || *inseguro {
+		cfg, err := certificado.Cliente(*caTLS, *certTLS, *chaveTLS, *serverAddr, *inseguro)
+		if err != nil {
+			fmt.Println("Erro na configuração TLS:", err)
+			return
+		}
+		configTLS = cfg
+	}
+
+	if *fuzz {
+		runFuzz(*clients)
+		return
+	}
+
+	if *escalas == "" {
+		st := runLoad(*clients, 0)
+		printStats(*clients, st)
+		return
+	}
+
+	// modo benchmark: uma rodada por escala, com nomes distintos entre as rodadas
+	var results []*stats
+	var counts []int
+	offset := 0
+	for _, campo := range strings.Split(*escalas, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(campo))
+		if err != nil || n <= 0 {
+			fmt.Println("Escala inválida:", campo)
+			return
+		}
+		fmt.Printf("--- rodada com %d clientes ---\n", n)
+		st := runLoad(n, offset)
+		printStats(n, st)
+		offset += n
+		results = append(results, st)
+		counts = append(counts, n)
+		time.Sleep(2 * time.Second) // deixa o servidor encerrar as conexões da rodada
+	}
+
+	fmt.Println("=== Vazão por escala ===")
+	fmt.Printf("%8s %8s %10s %10s %10s %10s\n", "clientes", "conexões", "ações/s", "erros", "p50", "p99")
+	for i, st := range results {
+		sortDur(st.latencies)
+		fmt.Printf("%8d %8d %10.0f %10d %10s %10s\n", counts[i], st.successConns,
+			float64(st.actionsSent)/st.elapsed.Seconds(), st.actionsErr,
+			percentile(st.latencies, 50), percentile(st.latencies, 99))
+	}
+}
+
+// roda uma rodada do teste com n clientes (os nomes começam em offset)
+func runLoad(n, offset int) *stats {
 	var wg sync.WaitGroup
 	st := &stats{}
 	var latMu sync.Mutex
 
-	stopAt := time.Now().Add(*duration)
+	start := time.Now()
+	stopAt := start.Add(*duration)
 
-	for i := 0; i < *clients; i++ {
+	for i := 0; i < n; i++ {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			nome := fmt.Sprintf("LoadBot-%d", id)
-			conn, err := net.Dial("tcp", *serverAddr)
+			if *rampa > 0 {
+				time.Sleep(time.Duration(int64(*rampa) * int64(id) / int64(n)))
+			}
+			nome := fmt.Sprintf("LoadBot-%d", offset+id)
+			conn, err := dial(0)
 			if err != nil {
 				atomic.AddInt64(&st.failConns, 1)
 				return
@@ -53,8 +135,8 @@ func main() {
 
 			// leitor que captura mensagens do servidor
 			reader := bufio.NewReader(conn)
-			// envia nome
-			_, _ = conn.Write([]byte(nome + "\n"))
+			// envia nome e senha
+			_, _ = conn.Write([]byte(nome + "\n" + senhaBots + "\n"))
 
 			// start goroutine de leitura para detectar "vencedor" e medir latência via eco de mensagens
 			msgCh := make(chan string, 100)
@@ -65,7 +147,13 @@ func main() {
 						close(msgCh)
 						return
 					}
-					msgCh <- strings.TrimSpace(line)
+					line = strings.TrimSpace(line)
+					// responde aos heartbeats para não ser desconectado
+					if strings.HasPrefix(line, "HEARTBEAT ") {
+						_, _ = conn.Write([]byte("/heartbeat " + strings.TrimPrefix(line, "HEARTBEAT ") + "\n"))
+						continue
+					}
+					msgCh <- line
 				}
 			}()
 
@@ -87,6 +175,19 @@ func main() {
 					cmd = fmt.Sprintf("/jogar %d", card)
 				}
 
+				// descarta mensagens anteriores para medir a resposta desta ação
+			drain:
+				for {
+					select {
+					case _, ok := <-msgCh:
+						if !ok {
+							return
+						}
+					default:
+						break drain
+					}
+				}
+
 				start := time.Now()
 				_, err := conn.Write([]byte(cmd + "\n"))
 				atomic.AddInt64(&st.actionsSent, 1)
@@ -118,48 +219,186 @@ func main() {
 
 	// esperar goroutines terminarem
 	wg.Wait()
+	st.elapsed = time.Since(start)
+	return st
+}
+
+// ordena as latências
+func sortDur(a []time.Duration) {
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+}
+
+// percentil de uma lista ordenada
+func percentile(lat []time.Duration, p float64) time.Duration {
+	if len(lat) == 0 {
+		return 0
+	}
+	idx := int(float64(len(lat)) * p / 100.0)
+	if idx >= len(lat) {
+		idx = len(lat) - 1
+	}
+	return lat[idx]
+}
 
+// imprime o resultado de uma rodada
+func printStats(n int, st *stats) {
 	// compila estatísticas
 	fmt.Println("=== Resultado do Load Test ===")
-	fmt.Printf("Clientes requisitados: %d\n", *clients)
+	fmt.Printf("Clientes requisitados: %d\n", n)
 	fmt.Printf("Conexões bem-sucedidas: %d\n", st.successConns)
 	fmt.Printf("Conexões falhas: %d\n", st.failConns)
 	fmt.Printf("Ações enviadas: %d (erros: %d)\n", st.actionsSent, st.actionsErr)
 	fmt.Printf("Vitórias detectadas: %d\n", st.winCount)
 
+	fmt.Printf("Vazão: %.0f ações/s em %s\n", float64(st.actionsSent)/st.elapsed.Seconds(), st.elapsed.Truncate(time.Millisecond))
+
 	// latências
-	latMu.Lock()
 	lat := st.latencies
-	latMu.Unlock()
 	if len(lat) == 0 {
 		fmt.Println("Nenhuma latência registrada")
 		return
 	}
 	// calcula p50 p90 p99
-	sortDur := func(a []time.Duration) {
-		for i := 1; i < len(a); i++ {
-			key := a[i]
-			j := i - 1
-			for j >= 0 && a[j] > key {
-				a[j+1] = a[j]
-				j--
-			}
-			a[j+1] = key
-		}
-	}
 	sortDur(lat)
-	getPct := func(p float64) time.Duration {
-		idx := int(float64(len(lat)) * p / 100.0)
-		if idx >= len(lat) {
-			idx = len(lat) - 1
-		}
-		return lat[idx]
-	}
+	getPct := func(p float64) time.Duration { return percentile(lat, p) }
 	total := time.Duration(0)
-	for _, d := range lat { total += d }
+	for _, d := range lat {
+		total += d
+	}
 	avg := total / time.Duration(len(lat))
 
 	fmt.Printf("Latências registradas: %d\n", len(lat))
 	fmt.Printf("avg: %s, p50: %s, p90: %s, p99: %s\n",
 		avg, getPct(50), getPct(90), getPct(99))
 }
+
+// modo fuzz: cada cliente envia linhas geradas por gerarLinhaFuzz (comandos mutados, JSON
+// quebrado, bytes aleatórios, linhas maiores que o limite) até o fim do teste; depois uma
+// conexão nova confere se o servidor ainda responde
+func runFuzz(n int) {
+	var wg sync.WaitGroup
+	var enviadas, recusadas, derrubadas int64
+	stopAt := time.Now().Add(*duration)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			conn, err := dial(0)
+			if err != nil {
+				atomic.AddInt64(&derrubadas, 1)
+				return
+			}
+			defer conn.Close()
+			_, _ = conn.Write([]byte(fmt.Sprintf("FuzzBot-%d\n%s\n", id, senhaBots)))
+
+			fechou := make(chan struct{})
+			go func() {
+				defer close(fechou)
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.HasPrefix(line, "HEARTBEAT ") {
+						_, _ = conn.Write([]byte("/heartbeat " + strings.TrimSpace(strings.TrimPrefix(line, "HEARTBEAT ")) + "\n"))
+					}
+					if strings.HasPrefix(line, "ERRO comando_invalido") || strings.HasPrefix(line, "ERRO linha_longa") {
+						atomic.AddInt64(&recusadas, 1)
+					}
+				}
+			}()
+
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(id)))
+			for time.Now().Before(stopAt) {
+				select {
+				case <-fechou:
+					atomic.AddInt64(&derrubadas, 1)
+					return
+				case <-time.After(time.Duration(5+rng.Intn(20)) * time.Millisecond):
+				}
+				if _, err := conn.Write([]byte(gerarLinhaFuzz(rng) + "\n")); err != nil {
+					atomic.AddInt64(&derrubadas, 1)
+					return
+				}
+				atomic.AddInt64(&enviadas, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Println("=== Resultado do Fuzz ===")
+	fmt.Printf("Linhas enviadas: %d (recusadas pelo servidor: %d)\n", enviadas, recusadas)
+	fmt.Printf("Conexões derrubadas pelo servidor: %d de %d\n", derrubadas, n)
+
+	// sonda: o servidor precisa continuar atendendo
+	conn, err := dial(5 * time.Second)
+	if err != nil {
+		fmt.Println("FALHA: servidor não aceita conexões após o fuzz:", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, _ = conn.Write([]byte(fmt.Sprintf("FuzzSonda-%d\n%s\n/cartas\n", time.Now().UnixNano(), senhaBots)))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("FALHA: servidor não respondeu após o fuzz:", err)
+			return
+		}
+		if strings.HasPrefix(line, "Cartas do jogo") {
+			fmt.Println("Servidor respondeu normalmente após o fuzz")
+			return
+		}
+	}
+}
+
+// linhas válidas usadas como ponto de partida das mutações do modo fuzz
+var linhasFuzz = []string{
+	"/entrar", "/sair", "/mao", "/jogar 3", "/jogar -1", "/perfil ana", "/ranking 2024-07 2",
+	`/sussurrar bia "oi, tudo bem?"`, `/denunciar caio "spam no chat" de novo`, `/torneio criar "Copa" suico 3`,
+	"/canal entrar geral", "/amigo convidar bia", "/loja comprar basico", "/missoes trocar 2", "/troca moedas 150",
+	`/perfil "\"aspas\" e \\barras"`, `{"acao":"jogar_carta","carta_id":3}`, `{"acao":"fim_turno"}`,
+	`{"acao":"chat","canal":"geral","texto":"oi"}`, `{"acao":"sussurrar","destino":"bia","texto":"psiu"}`,
+}
+
+// trechos que costumam quebrar analisadores
+var trechosFuzz = []string{
+	`"`, `\`, `\"`, " ", "\t", "\r", "\x00", "\xff", "\xc3", "é", "🃏", "{", "}", ":", ",", "/", "-",
+	"99999999999999999999", "1e9", "null", `"acao"`, `"carta_id":`, strings.Repeat("a", 300),
+}
+
+// gera uma linha arbitrária: uma linha válida com mutações, duas linhas emendadas, bytes
+// aleatórios ou uma linha maior que o limite do servidor
+func gerarLinhaFuzz(rng *rand.Rand) string {
+	switch rng.Intn(10) {
+	case 0:
+		b := make([]byte, rng.Intn(64))
+		rng.Read(b)
+		return strings.ReplaceAll(string(b), "\n", "")
+	case 1:
+		return "/" + strings.Repeat("x ", 2048+rng.Intn(8))
+	case 2:
+		return linhasFuzz[rng.Intn(len(linhasFuzz))] + linhasFuzz[rng.Intn(len(linhasFuzz))]
+	}
+	linha := []byte(linhasFuzz[rng.Intn(len(linhasFuzz))])
+	for n := 1 + rng.Intn(4); n > 0; n-- {
+		pos := rng.Intn(len(linha) + 1)
+		switch rng.Intn(4) {
+		case 0: // insere um trecho
+			linha = append(linha[:pos], append([]byte(trechosFuzz[rng.Intn(len(trechosFuzz))]), linha[pos:]...)...)
+		case 1: // apaga um pedaço
+			fim := min(len(linha), pos+rng.Intn(6))
+			linha = append(linha[:pos], linha[fim:]...)
+		case 2: // troca um byte
+			if pos < len(linha) && rng.Intn(256) != '\n' {
+				linha[pos] = byte(rng.Intn(256))
+			}
+		case 3: // corta a linha
+			linha = linha[:pos]
+		}
+	}
+	return string(linha)
+}
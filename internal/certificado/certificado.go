@@ -0,0 +1,200 @@
+// certificado.go
+
+// Package certificado monta as configurações TLS do servidor, do cliente e do load tester
+// e gera certificados autoassinados para desenvolvimento: uma CA local, um certificado de
+// servidor e um de cliente (usado no TLS mútuo), todos assinados por ela.
+package certificado
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// nomes dos arquivos gravados por Gerar
+const (
+	ArquivoCA            = "ca.pem"
+	ArquivoChaveCA       = "ca-chave.pem"
+	ArquivoServidor      = "servidor.pem"
+	ArquivoChaveServidor = "servidor-chave.pem"
+	ArquivoCliente       = "cliente.pem"
+	ArquivoChaveCliente  = "cliente-chave.pem"
+)
+
+// hosts incluídos no certificado do servidor quando nenhum é informado
+var HostsPadrao = []string{"localhost", "127.0.0.1", "::1", "lobby"}
+
+// validade dos certificados gerados
+const Validade = 365 * 24 * time.Hour
+
+var ErrSemCertificados = errors.New("nenhum certificado encontrado no arquivo")
+
+// configuração do lado servidor; com ca != "", exige e verifica o certificado do cliente
+// (TLS mútuo)
+func Servidor(cert, chave, ca string) (*tls.Config, error) {
+	par, err := tls.LoadX509KeyPair(cert, chave)
+	if err != nil {
+		return nil, fmt.Errorf("carregando certificado do servidor: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{par}, MinVersion: tls.VersionTLS12}
+	if ca != "" {
+		pool, err := carregarCA(ca)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// configuração do lado cliente; ca vazio usa as CAs do sistema, cert/chave são o
+// certificado apresentado no TLS mútuo e inseguro desliga a verificação do servidor
+func Cliente(ca, cert, chave, servidor string, inseguro bool) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(servidor)
+	if err != nil {
+		host = servidor
+	}
+	cfg := &tls.Config{ServerName: host, InsecureSkipVerify: inseguro, MinVersion: tls.VersionTLS12}
+	if ca != "" {
+		if cfg.RootCAs, err = carregarCA(ca); err != nil {
+			return nil, err
+		}
+	}
+	if cert != "" || chave != "" {
+		par, err := tls.LoadX509KeyPair(cert, chave)
+		if err != nil {
+			return nil, fmt.Errorf("carregando certificado do cliente: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{par}
+	}
+	return cfg, nil
+}
+
+func carregarCA(arquivo string) (*x509.CertPool, error) {
+	dados, err := os.ReadFile(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("lendo CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(dados) {
+		return nil, fmt.Errorf("%s: %w", arquivo, ErrSemCertificados)
+	}
+	return pool, nil
+}
+
+// grava em dir uma CA e os certificados de servidor (válido para hosts) e de cliente
+// assinados por ela; devolve os caminhos gravados. Arquivos existentes são sobrescritos.
+func Gerar(dir string, hosts []string) ([]string, error) {
+	if len(hosts) == 0 {
+		hosts = HostsPadrao
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	agora := time.Now()
+	caModelo := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "go-card-game CA de desenvolvimento"},
+		NotBefore:             agora.Add(-time.Hour),
+		NotAfter:              agora.Add(Validade),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caChave, caCert, caDER, err := emitir(caModelo, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	servidor := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: hosts[0]},
+		NotBefore:   agora.Add(-time.Hour),
+		NotAfter:    agora.Add(Validade),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			servidor.IPAddresses = append(servidor.IPAddresses, ip)
+		} else {
+			servidor.DNSNames = append(servidor.DNSNames, h)
+		}
+	}
+	servidorChave, _, servidorDER, err := emitir(servidor, caCert, caChave)
+	if err != nil {
+		return nil, err
+	}
+
+	cliente := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "cliente-dev"},
+		NotBefore:   agora.Add(-time.Hour),
+		NotAfter:    agora.Add(Validade),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clienteChave, _, clienteDER, err := emitir(cliente, caCert, caChave)
+	if err != nil {
+		return nil, err
+	}
+
+	arquivos := []struct {
+		nome  string
+		der   []byte
+		chave *ecdsa.PrivateKey
+	}{
+		{ArquivoCA, caDER, nil}, {ArquivoChaveCA, nil, caChave},
+		{ArquivoServidor, servidorDER, nil}, {ArquivoChaveServidor, nil, servidorChave},
+		{ArquivoCliente, clienteDER, nil}, {ArquivoChaveCliente, nil, clienteChave},
+	}
+	var gravados []string
+	for _, a := range arquivos {
+		caminho := filepath.Join(dir, a.nome)
+		bloco, modo := &pem.Block{Type: "CERTIFICATE", Bytes: a.der}, os.FileMode(0o644)
+		if a.chave != nil {
+			der, err := x509.MarshalECPrivateKey(a.chave)
+			if err != nil {
+				return gravados, err
+			}
+			bloco, modo = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, 0o600
+		}
+		if err := os.WriteFile(caminho, pem.EncodeToMemory(bloco), modo); err != nil {
+			return gravados, err
+		}
+		gravados = append(gravados, caminho)
+	}
+	return gravados, nil
+}
+
+// cria uma chave e assina o modelo com a CA (ou com a própria chave, se ca for nil)
+func emitir(modelo, ca *x509.Certificate, caChave *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate, []byte, error) {
+	chave, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	modelo.SerialNumber = serial
+	if ca == nil {
+		ca, caChave = modelo, chave
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modelo, ca, &chave.PublicKey, caChave)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	return chave, cert, der, err
+}
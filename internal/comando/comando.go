@@ -0,0 +1,360 @@
+// comando.go
+
+// Package comando interpreta as linhas enviadas pelos jogadores: comandos de texto
+// ("/nome arg \"arg com espaços\" ..."), validados pela gramática de cada comando, e ações
+// em JSON. Nenhuma função do pacote entra em pânico com entradas arbitrárias e nenhuma
+// aceita linhas maiores que o limite informado.
+package comando
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tamanho máximo padrão de uma linha, em bytes, sem contar o fim de linha
+const TamanhoMaximo = 4096
+
+var (
+	ErrLinhaLonga          = errors.New("linha muito longa")
+	ErrCaractereInvalido   = errors.New("caractere inválido")
+	ErrNaoComando          = errors.New("comandos começam com /")
+	ErrComandoDesconhecido = errors.New("comando desconhecido")
+	ErrSubcomando          = errors.New("subcomando desconhecido")
+	ErrAspas               = errors.New("aspas não fechadas")
+	ErrArgumentos          = errors.New("quantidade de argumentos inválida")
+	ErrNumero              = errors.New("número inválido")
+	ErrJSON                = errors.New("JSON incorreto")
+	ErrAcaoDesconhecida    = errors.New("ação desconhecida")
+)
+
+// comando de texto já separado em argumentos
+type Comando struct {
+	Nome    string   // sem a barra, em minúsculas
+	Args    []string // argumentos, sem as aspas
+	linha   string
+	inicios []int // posição de cada argumento na linha
+	aspas   []bool
+}
+
+// regra da gramática de um comando
+type Regra struct {
+	Min, Max    int              // quantidade de argumentos; Max < 0 aceita qualquer quantidade
+	Uso         string           // mostrado quando os argumentos não conferem
+	Inteiros    []int            // posições (em Args) dos argumentos que devem ser números inteiros
+	Subcomandos map[string]Regra // o primeiro argumento escolhe o subcomando; nele só Inteiros é conferido
+}
+
+const semLimite = -1
+
+var (
+	semArgs = Regra{}
+	livre   = Regra{Max: semLimite}
+)
+
+// regra de um comando com exatamente um argumento
+func umNome(uso string) Regra {
+	return Regra{Min: 1, Max: 1, Uso: uso}
+}
+
+// gramática dos comandos; a quantidade de argumentos dos subcomandos fica a cargo de quem os trata
+var gramatica = map[string]Regra{
+	"entrar":      semArgs,
+	"sair":        semArgs,
+	"heartbeat":   {Max: 1},
+	"ping":        semArgs,
+	"partidas":    semArgs,
+	"assistir":    umNome("/assistir <id_partida>"),
+	"mao":         semArgs,
+	"cartas":      semArgs,
+	"jogar":       {Min: 1, Max: 1, Uso: "/jogar <id_carta>", Inteiros: []int{0}},
+	"fim":         semArgs,
+	"perfil":      {Max: 1, Uso: "/perfil [nome]"},
+	"ranking":     {Max: 2, Uso: "/ranking [temporada] [pagina]"},
+	"sussurrar":   {Min: 2, Max: semLimite, Uso: "/sussurrar <nome> <mensagem>"},
+	"autenticar":  umNome("/autenticar <token>"),
+	"bloquear":    umNome("/bloquear <nome>"),
+	"desbloquear": umNome("/desbloquear <nome>"),
+	"bloqueados":  semArgs,
+	"denunciar":   {Min: 1, Max: semLimite, Uso: "/denunciar <nome> [motivo]"},
+	"booster":     semArgs,
+	"saldo":       semArgs,
+	"extrato":     semArgs,
+	"colecao":     semArgs,
+	"desencantar": {Min: 1, Max: 2, Uso: "/desencantar <carta> [qtd]", Inteiros: []int{1}},
+	"criar":       umNome("/criar <carta>"),
+	"torneio": {Max: semLimite, Uso: "/torneio criar|inscrever|sair|iniciar|lista|ver", Subcomandos: map[string]Regra{
+		"criar": {Inteiros: []int{3}}, "inscrever": livre, "sair": livre, "iniciar": livre, "lista": livre, "ver": livre,
+	}},
+	"canal": {Max: semLimite, Uso: "/canal entrar|sair|usar|lista|historico", Subcomandos: map[string]Regra{
+		"entrar": livre, "sair": livre, "usar": livre, "lista": livre, "historico": livre,
+	}},
+	"amigo": {Max: semLimite, Uso: "/amigo adicionar|aceitar|recusar|remover|lista|pedidos|convidar|jogar", Subcomandos: map[string]Regra{
+		"adicionar": livre, "aceitar": livre, "recusar": livre, "remover": livre,
+		"lista": livre, "pedidos": livre, "convidar": livre, "jogar": livre,
+	}},
+	"mod": {Max: semLimite, Uso: "/mod silenciar|banir|liberar|denuncias|filtro|ping|saida|ips|boosters|moedas|conciliar|log", Subcomandos: map[string]Regra{
+		"silenciar": livre, "banir": livre, "liberar": livre, "denuncias": {Inteiros: []int{1}}, "filtro": livre,
+		"ping": livre, "saida": livre, "ips": livre, "boosters": livre, "moedas": livre, "conciliar": livre,
+		"log": livre,
+	}},
+	"admin": {Max: semLimite, Uso: "/admin jogadores|partidas|fila|estado|expulsar|banir|encerrar|repor|anunciar|recarregar-cartas", Subcomandos: map[string]Regra{
+		"jogadores": livre, "partidas": livre, "fila": livre, "estado": livre, "expulsar": livre, "banir": livre,
+		"encerrar": livre, "repor": {Inteiros: []int{2}}, "anunciar": livre, "recarregar-cartas": livre,
+	}},
+	"loja": {Max: semLimite, Uso: "/loja, /loja comprar <colecao>", Subcomandos: map[string]Regra{"comprar": livre}},
+	"missoes": {Max: 2, Uso: "/missoes, /missoes trocar <n>, /missoes conquistas", Subcomandos: map[string]Regra{
+		"trocar": {Inteiros: []int{1}}, "conquistas": livre,
+	}},
+	"troca": {Max: semLimite, Uso: "/troca propor|adicionar|remover|moedas|ver|confirmar|cancelar", Subcomandos: map[string]Regra{
+		"propor": livre, "adicionar": {Inteiros: []int{2}}, "remover": {Inteiros: []int{2}},
+		"moedas": {Inteiros: []int{1}}, "ver": livre, "confirmar": livre, "cancelar": livre,
+	}},
+}
+
+// separa a linha em comando e argumentos e confere a gramática
+func Analisar(linha string) (Comando, error) {
+	if err := conferirTexto(linha); err != nil {
+		return Comando{}, err
+	}
+	if !strings.HasPrefix(linha, "/") {
+		return Comando{}, ErrNaoComando
+	}
+	c := Comando{linha: linha}
+	fim := strings.IndexAny(linha, " \t")
+	if fim < 0 {
+		fim = len(linha)
+	}
+	c.Nome = strings.ToLower(linha[1:fim])
+	regra, ok := gramatica[c.Nome]
+	if !ok {
+		return Comando{}, fmt.Errorf("%w: /%s", ErrComandoDesconhecido, c.Nome)
+	}
+	if err := c.separar(fim); err != nil {
+		return Comando{}, err
+	}
+	if regra.Uso == "" {
+		regra.Uso = "/" + c.Nome
+	}
+	if len(c.Args) < regra.Min || (regra.Max >= 0 && len(c.Args) > regra.Max) {
+		return Comando{}, fmt.Errorf("%w; uso: %s", ErrArgumentos, regra.Uso)
+	}
+	if err := c.conferirInteiros(regra.Inteiros, regra.Uso); err != nil {
+		return Comando{}, err
+	}
+	if regra.Subcomandos != nil && len(c.Args) > 0 {
+		sub, ok := regra.Subcomandos[c.Args[0]]
+		if !ok {
+			return Comando{}, fmt.Errorf("%w %q; uso: %s", ErrSubcomando, c.Args[0], regra.Uso)
+		}
+		if err := c.conferirInteiros(sub.Inteiros, regra.Uso); err != nil {
+			return Comando{}, err
+		}
+	}
+	return c, nil
+}
+
+// separa os argumentos a partir da posição inicial; entre aspas valem \" e \\
+func (c *Comando) separar(pos int) error {
+	linha := c.linha
+	for pos < len(linha) {
+		if linha[pos] == ' ' || linha[pos] == '\t' {
+			pos++
+			continue
+		}
+		inicio := pos
+		if linha[pos] != '"' {
+			for pos < len(linha) && linha[pos] != ' ' && linha[pos] != '\t' {
+				pos++
+			}
+			c.adicionar(linha[inicio:pos], inicio, false)
+			continue
+		}
+		var arg strings.Builder
+		pos++
+		fechou := false
+		for pos < len(linha) && !fechou {
+			switch {
+			case linha[pos] == '\\' && pos+1 < len(linha) && (linha[pos+1] == '"' || linha[pos+1] == '\\'):
+				arg.WriteByte(linha[pos+1])
+				pos += 2
+			case linha[pos] == '"':
+				fechou = true
+				pos++
+			default:
+				arg.WriteByte(linha[pos])
+				pos++
+			}
+		}
+		if !fechou {
+			return ErrAspas
+		}
+		if pos < len(linha) && linha[pos] != ' ' && linha[pos] != '\t' {
+			return fmt.Errorf("%w: texto logo após as aspas", ErrAspas)
+		}
+		c.adicionar(arg.String(), inicio, true)
+	}
+	return nil
+}
+
+func (c *Comando) adicionar(arg string, inicio int, aspas bool) {
+	c.Args = append(c.Args, arg)
+	c.inicios = append(c.inicios, inicio)
+	c.aspas = append(c.aspas, aspas)
+}
+
+func (c Comando) conferirInteiros(posicoes []int, uso string) error {
+	for _, i := range posicoes {
+		if i < len(c.Args) {
+			if _, ok := inteiro(c.Args[i]); !ok {
+				return fmt.Errorf("%w: %q; uso: %s", ErrNumero, c.Args[i], uso)
+			}
+		}
+	}
+	return nil
+}
+
+// número inteiro de até 9 dígitos, com sinal opcional
+func inteiro(s string) (int, bool) {
+	digitos := strings.TrimPrefix(s, "-")
+	if digitos == "" || len(digitos) > 9 || strings.Trim(digitos, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// argumento i como inteiro (0 se ausente; a gramática já validou as posições numéricas)
+func (c Comando) Inteiro(i int) int {
+	if i < 0 || i >= len(c.Args) {
+		return 0
+	}
+	n, _ := inteiro(c.Args[i])
+	return n
+}
+
+// argumento i (vazio se ausente)
+func (c Comando) Arg(i int) string {
+	if i < 0 || i >= len(c.Args) {
+		return ""
+	}
+	return c.Args[i]
+}
+
+// texto da linha a partir do argumento i, como foi digitado (para mensagens e motivos);
+// um único argumento entre aspas é devolvido sem elas
+func (c Comando) Resto(i int) string {
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(c.Args) {
+		return ""
+	}
+	if i == len(c.Args)-1 && c.aspas[i] {
+		return c.Args[i]
+	}
+	return strings.TrimSpace(c.linha[c.inicios[i]:])
+}
+
+// monta a linha de um comando, pondo entre aspas os argumentos que precisam
+func Formatar(nome string, args ...string) string {
+	var b strings.Builder
+	b.WriteString("/" + nome)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		if arg != "" && !strings.ContainsAny(arg, " \t\"\\") {
+			b.WriteString(arg)
+			continue
+		}
+		b.WriteByte('"')
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// confere tamanho, UTF-8 e caracteres de controle
+func conferirTexto(linha string) error {
+	if len(linha) > TamanhoMaximo {
+		return ErrLinhaLonga
+	}
+	if !utf8.ValidString(linha) {
+		return fmt.Errorf("%w: UTF-8 inválido", ErrCaractereInvalido)
+	}
+	for _, r := range linha {
+		if r != '\t' && unicode.IsControl(r) {
+			return fmt.Errorf("%w: %U", ErrCaractereInvalido, r)
+		}
+	}
+	return nil
+}
+
+// ação enviada pelo jogador em JSON
+type Acao struct {
+	Acao    string `json:"acao"`               // tipo da ação, ex: "jogar_carta", "fim_turno", "chat", "sussurrar"
+	CartaID int    `json:"carta_id,omitempty"` // id da carta, se aplicável
+	Canal   string `json:"canal,omitempty"`    // canal de chat ("partida" para o chat da partida)
+	Destino string `json:"destino,omitempty"`  // nome do destinatário de um sussurro
+	Texto   string `json:"texto,omitempty"`    // texto da mensagem de chat
+}
+
+// ações aceitas em JSON
+var acoes = map[string]bool{
+	"jogar_carta": true, "fim_turno": true, "chat": true, "sussurrar": true, "canal_entrar": true, "canal_sair": true,
+}
+
+// decodifica uma ação JSON: um único objeto, sem campos desconhecidos, de um tipo aceito
+func DecodificarAcao(linha string) (Acao, error) {
+	if len(linha) > TamanhoMaximo {
+		return Acao{}, ErrLinhaLonga
+	}
+	dec := json.NewDecoder(strings.NewReader(linha))
+	dec.DisallowUnknownFields()
+	var a Acao
+	if err := dec.Decode(&a); err != nil {
+		return Acao{}, fmt.Errorf("%w: %v", ErrJSON, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return Acao{}, fmt.Errorf("%w: dados após o objeto", ErrJSON)
+	}
+	if !acoes[a.Acao] {
+		return Acao{}, fmt.Errorf("%w: %q", ErrAcaoDesconhecida, a.Acao)
+	}
+	if a.Acao == "jogar_carta" && a.CartaID <= 0 {
+		return Acao{}, fmt.Errorf("%w: carta_id", ErrNumero)
+	}
+	return a, nil
+}
+
+// lê uma linha de no máximo max bytes (sem o fim de linha). Uma linha maior é descartada
+// até o próximo \n sem ser guardada e retorna ErrLinhaLonga; os outros erros são os da leitura
+func LerLinha(r *bufio.Reader, max int) (string, error) {
+	var linha []byte
+	for {
+		parte, err := r.ReadSlice('\n')
+		if len(linha)+len(parte) > max+2 { // \r\n
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = r.ReadSlice('\n')
+			}
+			if err != nil {
+				return "", err
+			}
+			return "", ErrLinhaLonga
+		}
+		linha = append(linha, parte...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		texto := strings.TrimRight(string(linha), "\r\n")
+		if err == nil && len(texto) > max {
+			return "", ErrLinhaLonga
+		}
+		return texto, err
+	}
+}
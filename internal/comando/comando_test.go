@@ -0,0 +1,153 @@
+// comando_test.go
+package comando
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// linhas válidas usadas como sementes dos alvos de fuzz
+var corpus = []string{
+	"/entrar", "/sair", "/mao", "/jogar 3", "/jogar -1", "/heartbeat 12", "/perfil ana", "/ranking 2024-07 2",
+	`/sussurrar bia "oi, tudo bem?"`, "/sussurrar bia oi tudo bem", `/denunciar caio "spam no chat" de novo`,
+	`/torneio criar "Copa de Verão" suico 3`, "/torneio inscrever torneio-1", "/canal entrar geral",
+	"/amigo convidar bia", "/mod silenciar caio 10m flood", "/mod denuncias 5", "/loja comprar basica",
+	"/missoes trocar 2", "/troca adicionar C001-R 2", "/troca moedas 150", "/desencantar C010-U 3",
+	`/assistir "partida-123"`, `/perfil "\"aspas\" e \\barras"`, "/jogar\t7", "/admin repor basico 3",
+	`{"acao":"jogar_carta","carta_id":3}`, `{"acao":"fim_turno"}`, `{"acao":"chat","canal":"geral","texto":"oi"}`,
+	`{"acao":"sussurrar","destino":"bia","texto":"psiu"}`, `{"acao":"canal_entrar","canal":"trocas"}`,
+}
+
+// sementes que costumam quebrar analisadores
+var sementesQuebradas = []string{
+	`/sussurrar bia "sem fim`, `/perfil "a\`, "/jogar 99999999999999999999", "/jogar\x00", "/\xff\xc3",
+	`{"acao":"jogar_carta","carta_id":1e9}`, `{"acao":"fim_turno"}{}`, `{"acao":null}`, "/" + strings.Repeat("x ", 3000),
+}
+
+// erros que o pacote pode devolver
+var conhecidos = []error{
+	ErrLinhaLonga, ErrCaractereInvalido, ErrNaoComando, ErrComandoDesconhecido, ErrSubcomando,
+	ErrAspas, ErrArgumentos, ErrNumero, ErrJSON, ErrAcaoDesconhecida,
+}
+
+func conhecido(err error) bool {
+	for _, e := range conhecidos {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func semear(f *testing.F) {
+	for _, linha := range append(append([]string{}, corpus...), sementesQuebradas...) {
+		f.Add(linha)
+	}
+}
+
+func TestCorpusAceito(t *testing.T) {
+	for _, linha := range corpus {
+		if !strings.HasPrefix(linha, "/") {
+			if _, err := DecodificarAcao(linha); err != nil {
+				t.Errorf("ação do corpus recusada %q: %v", linha, err)
+			}
+			continue
+		}
+		if _, err := Analisar(linha); err != nil {
+			t.Errorf("linha do corpus recusada %q: %v", linha, err)
+		}
+	}
+}
+
+// todo comando aceito respeita a gramática e volta igual depois de formatado e analisado de novo
+func FuzzAnalisar(f *testing.F) {
+	semear(f)
+	f.Fuzz(func(t *testing.T, linha string) {
+		c, err := Analisar(linha)
+		if err != nil {
+			if !conhecido(err) {
+				t.Fatalf("erro fora dos previstos para %q: %v", linha, err)
+			}
+			return
+		}
+		regra, ok := gramatica[c.Nome]
+		switch {
+		case !ok:
+			t.Fatalf("comando fora da gramática aceito: %q", c.Nome)
+		case len(c.Args) < regra.Min || (regra.Max >= 0 && len(c.Args) > regra.Max):
+			t.Fatalf("%d argumentos aceitos para /%s", len(c.Args), c.Nome)
+		case len(linha) > TamanhoMaximo:
+			t.Fatalf("linha de %d bytes aceita", len(linha))
+		}
+		for _, i := range regra.Inteiros {
+			if _, ok := inteiro(c.Arg(i)); i < len(c.Args) && !ok {
+				t.Fatalf("argumento %d de /%s aceito sem ser número: %q", i, c.Nome, c.Args[i])
+			}
+		}
+		for i := -1; i <= len(c.Args); i++ {
+			c.Resto(i)
+			c.Inteiro(i)
+		}
+		formatada := Formatar(c.Nome, c.Args...)
+		if len(formatada) > TamanhoMaximo {
+			return
+		}
+		d, err := Analisar(formatada)
+		if err != nil || d.Nome != c.Nome || !reflect.DeepEqual(d.Args, c.Args) {
+			t.Fatalf("comando não sobrevive à ida e volta por %q: %v %q", formatada, err, d.Args)
+		}
+	})
+}
+
+// toda ação aceita sobrevive à ida e volta pelo JSON
+func FuzzDecodificarAcao(f *testing.F) {
+	semear(f)
+	f.Fuzz(func(t *testing.T, linha string) {
+		a, err := DecodificarAcao(linha)
+		if err != nil {
+			if !conhecido(err) {
+				t.Fatalf("erro de ação fora dos previstos para %q: %v", linha, err)
+			}
+			return
+		}
+		dados, _ := json.Marshal(a)
+		if b, err := DecodificarAcao(string(dados)); err != nil || b != a {
+			t.Fatalf("ação não sobrevive à ida e volta: %+v, %+v, %v", a, b, err)
+		}
+	})
+}
+
+// a leitura nunca devolve uma linha acima do limite, nem com leituras parciais
+func FuzzLerLinha(f *testing.F) {
+	for i, linha := range append(append([]string{}, corpus...), sementesQuebradas...) {
+		f.Add(linha, uint8(16+i*7%64))
+	}
+	f.Fuzz(func(t *testing.T, linha string, limite uint8) {
+		max := 16 + int(limite)
+		// a mesma linha duas vezes, com um buffer mínimo para forçar leituras parciais
+		entrada := linha + "\n" + linha + "\r\n"
+		r := bufio.NewReaderSize(strings.NewReader(entrada), 16)
+		for _, esperada := range strings.SplitAfter(entrada, "\n") {
+			lida, err := LerLinha(r, max)
+			texto := strings.TrimRight(esperada, "\r\n")
+			longa := len(esperada) > max+2 || len(texto) > max
+			switch {
+			case esperada == "":
+				if err != io.EOF || lida != "" {
+					t.Fatalf("LerLinha leu além do fim: %q, %v", lida, err)
+				}
+			case len(lida) > max:
+				t.Fatalf("LerLinha devolveu %d bytes com limite %d", len(lida), max)
+			case longa && !errors.Is(err, ErrLinhaLonga):
+				t.Fatalf("LerLinha aceitou uma linha de %d bytes com limite %d: %v", len(texto), max, err)
+			case !longa && (err != nil || lida != texto):
+				t.Fatalf("LerLinha devolveu %q, %v; esperado %q", lida, err, texto)
+			}
+		}
+	})
+}
@@ -0,0 +1,217 @@
+// motor.go
+
+// Package motor contém as regras de uma partida: dano das cartas, troca de turno e fim de
+// jogo. Aplicar é pura: não faz I/O, não altera o estado recebido e sempre produz o mesmo
+// resultado para a mesma entrada. O servidor traduz comandos em ações e eventos em mensagens.
+package motor
+
+import (
+	"errors"
+	"slices"
+)
+
+// vida de cada jogador no início da partida
+const VidaInicial = 100
+
+// cartas do catálogo das partidas (IDs 1 a TotalCartas)
+const TotalCartas = 20
+
+// tipos de ação
+const (
+	JogarCarta = "jogar_carta"
+	FimTurno   = "fim_turno"
+	Tempo      = "tempo"      // o prazo do turno do jogador acabou
+	Desconexao = "desconexao" // o jogador saiu da partida
+)
+
+// tipos de evento
+const (
+	EventoDano       = "dano"
+	EventoTurno      = "turno"
+	EventoTempo      = "tempo"
+	EventoDesconexao = "desconexao"
+	EventoFim        = "fim"
+)
+
+// motivos de fim de partida
+const (
+	MotivoVitoria    = "vitoria"
+	MotivoDesconexao = "desconexao"
+	MotivoAdmin      = "admin" // encerrada por um admin, com ou sem vencedor
+)
+
+var (
+	ErrPartidaEncerrada    = errors.New("a partida já terminou")
+	ErrJogadorDesconhecido = errors.New("jogador não participa da partida")
+	ErrForaDaVez           = errors.New("não é sua vez")
+	ErrCartaForaDaMao      = errors.New("carta não encontrada na mão")
+	ErrAcaoDesconhecida    = errors.New("ação desconhecida")
+)
+
+// estado de uma partida
+type Estado struct {
+	Jogadores [2]string        // IDs dos jogadores; o primeiro começa
+	Vida      map[string]int   // ID -> vida
+	Mao       map[string][]int // ID -> cartas na mão
+	Turno     string           // ID do jogador com a vez
+	Encerrada bool
+	Vencedor  string // ID do vencedor, quando encerrada
+	Motivo    string // vitoria, desconexao ou admin, quando encerrada
+}
+
+// ação de um jogador (ou do relógio, no caso de Tempo)
+type Acao struct {
+	Tipo    string
+	Jogador string
+	Carta   int // apenas JogarCarta
+}
+
+// consequência de uma ação, na ordem em que acontece
+type Evento struct {
+	Tipo     string
+	Jogador  string         // alvo do dano, quem perdeu a vez ou quem desconectou
+	Carta    int            // carta que causou o dano
+	Valor    int            // dano causado
+	Vida     map[string]int // vida após o dano
+	Turno    string         // jogador com a vez após a troca
+	Vencedor string
+	Motivo   string
+}
+
+// estado inicial de uma partida entre a e b com as mãos já sorteadas
+func Novo(a, b string, maoA, maoB []int) Estado {
+	return Estado{
+		Jogadores: [2]string{a, b},
+		Vida:      map[string]int{a: VidaInicial, b: VidaInicial},
+		Mao:       map[string][]int{a: slices.Clone(maoA), b: slices.Clone(maoB)},
+		Turno:     a,
+	}
+}
+
+// raridade de uma carta do catálogo das partidas
+func Raridade(carta int) string {
+	switch {
+	case carta >= 1 && carta <= 5:
+		return "Rara"
+	case carta >= 6 && carta <= 10:
+		return "Incomum"
+	}
+	return "Comum"
+}
+
+// dano causado por uma carta, pela raridade
+func Dano(carta int) int {
+	switch Raridade(carta) {
+	case "Rara":
+		return 30
+	case "Incomum":
+		return 20
+	}
+	return 10
+}
+
+// oponente do jogador (vazio se ele não participa)
+func (e Estado) Oponente(jogador string) string {
+	switch jogador {
+	case e.Jogadores[0]:
+		return e.Jogadores[1]
+	case e.Jogadores[1]:
+		return e.Jogadores[0]
+	}
+	return ""
+}
+
+// aplica a ação e retorna o novo estado e os eventos; em caso de erro o estado retornado é
+// o recebido e nenhum evento acontece
+func Aplicar(e Estado, a Acao) (Estado, []Evento, error) {
+	if e.Encerrada {
+		return e, nil, ErrPartidaEncerrada
+	}
+	oponente := e.Oponente(a.Jogador)
+	if a.Jogador == "" || oponente == "" {
+		return e, nil, ErrJogadorDesconhecido
+	}
+
+	switch a.Tipo {
+	case JogarCarta:
+		if e.Turno != a.Jogador {
+			return e, nil, ErrForaDaVez
+		}
+		pos := slices.Index(e.Mao[a.Jogador], a.Carta)
+		if pos < 0 {
+			return e, nil, ErrCartaForaDaMao
+		}
+		n := e.copiar()
+		n.Mao[a.Jogador] = slices.Delete(n.Mao[a.Jogador], pos, pos+1)
+		dano := Dano(a.Carta)
+		n.Vida[oponente] = max(n.Vida[oponente]-dano, 0)
+		eventos := []Evento{{Tipo: EventoDano, Jogador: oponente, Carta: a.Carta, Valor: dano, Vida: copiarVida(n.Vida)}}
+		if n.Vida[oponente] == 0 {
+			return n, append(eventos, n.encerrar(a.Jogador, MotivoVitoria)), nil
+		}
+		return n, append(eventos, n.trocarVez()), nil
+
+	case FimTurno:
+		if e.Turno != a.Jogador {
+			return e, nil, ErrForaDaVez
+		}
+		n := e.copiar()
+		return n, []Evento{n.trocarVez()}, nil
+
+	case Tempo:
+		if e.Turno != a.Jogador {
+			return e, nil, ErrForaDaVez
+		}
+		n := e.copiar()
+		return n, []Evento{{Tipo: EventoTempo, Jogador: a.Jogador}, n.trocarVez()}, nil
+
+	case Desconexao:
+		n := e.copiar()
+		return n, []Evento{{Tipo: EventoDesconexao, Jogador: a.Jogador}, n.encerrar(oponente, MotivoDesconexao)}, nil
+	}
+	return e, nil, ErrAcaoDesconhecida
+}
+
+// encerra a partida por decisão de um admin; vencedor vazio encerra sem vencedor. Como
+// Aplicar, não altera o estado recebido
+func Encerrar(e Estado, vencedor string) (Estado, Evento, error) {
+	if e.Encerrada {
+		return e, Evento{}, ErrPartidaEncerrada
+	}
+	if vencedor != "" && e.Oponente(vencedor) == "" {
+		return e, Evento{}, ErrJogadorDesconhecido
+	}
+	n := e.copiar()
+	return n, n.encerrar(vencedor, MotivoAdmin), nil
+}
+
+// passa a vez (apenas em cópias)
+func (e *Estado) trocarVez() Evento {
+	e.Turno = e.Oponente(e.Turno)
+	return Evento{Tipo: EventoTurno, Turno: e.Turno}
+}
+
+// encerra a partida (apenas em cópias)
+func (e *Estado) encerrar(vencedor, motivo string) Evento {
+	e.Encerrada, e.Vencedor, e.Motivo = true, vencedor, motivo
+	return Evento{Tipo: EventoFim, Vencedor: vencedor, Motivo: motivo}
+}
+
+// cópia profunda do estado, para que Aplicar nunca altere a entrada
+func (e Estado) copiar() Estado {
+	n := e
+	n.Vida = copiarVida(e.Vida)
+	n.Mao = make(map[string][]int, len(e.Mao))
+	for id, mao := range e.Mao {
+		n.Mao[id] = slices.Clone(mao)
+	}
+	return n
+}
+
+func copiarVida(vida map[string]int) map[string]int {
+	c := make(map[string]int, len(vida))
+	for id, v := range vida {
+		c[id] = v
+	}
+	return c
+}
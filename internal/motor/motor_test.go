@@ -0,0 +1,316 @@
+// motor_test.go
+package motor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// caso da tabela de regras
+type caso struct {
+	nome    string
+	estado  Estado
+	acao    Acao
+	erro    error
+	confere func(n Estado, ev []Evento) string // descrição do problema, ou vazio
+}
+
+// estado de exemplo: "a" com a vez, vidas informadas
+func exemplo(vidaA, vidaB int, maoA, maoB []int) Estado {
+	e := Novo("a", "b", maoA, maoB)
+	e.Vida["a"], e.Vida["b"] = vidaA, vidaB
+	return e
+}
+
+func tiposDe(ev []Evento) string {
+	tipos := make([]string, len(ev))
+	for i, e := range ev {
+		tipos[i] = e.Tipo
+	}
+	return strings.Join(tipos, ",")
+}
+
+var casos = []caso{
+	{nome: "carta rara causa 30", estado: exemplo(100, 100, []int{1}, []int{11}), acao: Acao{JogarCarta, "a", 1},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Vida["b"] != 70 || n.Turno != "b" || len(n.Mao["a"]) != 0 || tiposDe(ev) != "dano,turno" {
+				return fmt.Sprintf("vida %v, turno %s, mão %v, eventos %s", n.Vida, n.Turno, n.Mao["a"], tiposDe(ev))
+			}
+			return ""
+		}},
+	{nome: "carta incomum causa 20", estado: exemplo(100, 100, []int{7}, nil), acao: Acao{JogarCarta, "a", 7},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Vida["b"] != 80 || ev[0].Valor != 20 {
+				return fmt.Sprintf("vida %v, dano %d", n.Vida, ev[0].Valor)
+			}
+			return ""
+		}},
+	{nome: "carta comum causa 10", estado: exemplo(100, 100, []int{15}, nil), acao: Acao{JogarCarta, "a", 15},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Vida["b"] != 90 {
+				return fmt.Sprintf("vida %v", n.Vida)
+			}
+			return ""
+		}},
+	{nome: "dano letal zera a vida e encerra", estado: exemplo(100, 20, []int{1}, nil), acao: Acao{JogarCarta, "a", 1},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Vida["b"] != 0 || !n.Encerrada || n.Vencedor != "a" || n.Motivo != MotivoVitoria || tiposDe(ev) != "dano,fim" {
+				return fmt.Sprintf("vida %v, encerrada %v, vencedor %q, eventos %s", n.Vida, n.Encerrada, n.Vencedor, tiposDe(ev))
+			}
+			return ""
+		}},
+	{nome: "jogada fora da vez", estado: exemplo(100, 100, nil, []int{1}), acao: Acao{JogarCarta, "b", 1}, erro: ErrForaDaVez},
+	{nome: "carta fora da mão", estado: exemplo(100, 100, []int{2}, nil), acao: Acao{JogarCarta, "a", 3}, erro: ErrCartaForaDaMao},
+	{nome: "fim de turno passa a vez", estado: exemplo(100, 100, nil, nil), acao: Acao{FimTurno, "a", 0},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Turno != "b" || tiposDe(ev) != "turno" {
+				return fmt.Sprintf("turno %s, eventos %s", n.Turno, tiposDe(ev))
+			}
+			return ""
+		}},
+	{nome: "fim de turno fora da vez", estado: exemplo(100, 100, nil, nil), acao: Acao{FimTurno, "b", 0}, erro: ErrForaDaVez},
+	{nome: "tempo esgotado passa a vez", estado: exemplo(100, 100, nil, nil), acao: Acao{Tempo, "a", 0},
+		confere: func(n Estado, ev []Evento) string {
+			if n.Turno != "b" || tiposDe(ev) != "tempo,turno" {
+				return fmt.Sprintf("turno %s, eventos %s", n.Turno, tiposDe(ev))
+			}
+			return ""
+		}},
+	{nome: "desconexão fora da vez dá a vitória ao oponente", estado: exemplo(100, 100, nil, nil), acao: Acao{Desconexao, "b", 0},
+		confere: func(n Estado, ev []Evento) string {
+			if !n.Encerrada || n.Vencedor != "a" || n.Motivo != MotivoDesconexao || tiposDe(ev) != "desconexao,fim" {
+				return fmt.Sprintf("encerrada %v, vencedor %q, motivo %q, eventos %s", n.Encerrada, n.Vencedor, n.Motivo, tiposDe(ev))
+			}
+			return ""
+		}},
+	{nome: "partida encerrada não aceita ações", estado: func() Estado {
+		e := exemplo(100, 0, []int{1}, nil)
+		e.Encerrada, e.Vencedor, e.Motivo = true, "a", MotivoVitoria
+		return e
+	}(), acao: Acao{JogarCarta, "a", 1}, erro: ErrPartidaEncerrada},
+	{nome: "jogador de fora", estado: exemplo(100, 100, []int{1}, nil), acao: Acao{JogarCarta, "c", 1}, erro: ErrJogadorDesconhecido},
+	{nome: "ação desconhecida", estado: exemplo(100, 100, nil, nil), acao: Acao{"render", "a", 0}, erro: ErrAcaoDesconhecida},
+}
+
+func TestAplicar(t *testing.T) {
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			n, ev, err := aplicarConferindo(c.estado, c.acao)
+			if !errors.Is(err, c.erro) {
+				t.Fatalf("erro %v, esperado %v", err, c.erro)
+			}
+			if c.confere != nil {
+				if problema := c.confere(n, ev); problema != "" {
+					t.Fatal(problema)
+				}
+			}
+		})
+	}
+}
+
+// sorteia partidas com ações válidas e inválidas (jogadores de fora, cartas fora da mão, ações
+// fora da vez) e confere as propriedades a cada passo; no fim o admin encerra a partida
+func TestPropriedades(t *testing.T) {
+	partidas := 5000
+	if testing.Short() {
+		partidas = 500
+	}
+	rng := rand.New(rand.NewSource(1))
+	quem := []string{"a", "b", "a", "b", "", "c"}
+	for i := 0; i < partidas; i++ {
+		e := Novo("a", "b", sortearMao(rng), sortearMao(rng))
+		for passo := 0; passo < 40; passo++ {
+			a := sortearAcao(rng, e, quem)
+			n, err := conferirPasso(e, a)
+			if err != nil {
+				t.Fatalf("partida %d, passo %d (%+v): %v", i, passo, a, err)
+			}
+			e = n
+		}
+		if err := conferirEncerrar(e, quem[rng.Intn(len(quem))]); err != nil {
+			t.Fatalf("partida %d: %v", i, err)
+		}
+	}
+}
+
+// as mesmas propriedades sobre sequências de ações escolhidas pelo fuzzer: cada byte escolhe o
+// tipo da ação, o jogador e a carta
+func FuzzAplicar(f *testing.F) {
+	f.Add([]byte{1, 2, 3, 4, 5, 11, 12, 13, 14, 15}, []byte{0x00, 0x01, 0x10, 0x21, 0x32, 0x43, 0x54})
+	f.Add([]byte{1, 1, 1, 1, 1, 2, 2, 2, 2, 2}, []byte{0x00, 0x40, 0x00, 0x40, 0x00, 0x40, 0x00, 0x40})
+	f.Add([]byte{}, []byte{0x35, 0x66, 0xff})
+	tipos := []string{JogarCarta, JogarCarta, JogarCarta, FimTurno, Tempo, Desconexao, "???", JogarCarta}
+	quem := []string{"a", "b", "", "c"}
+	f.Fuzz(func(t *testing.T, maos []byte, passos []byte) {
+		carta := func(i int) int {
+			if i < len(maos) {
+				return int(maos[i])%TotalCartas + 1
+			}
+			return i%TotalCartas + 1
+		}
+		maoA, maoB := make([]int, 5), make([]int, 5)
+		for i := range maoA {
+			maoA[i], maoB[i] = carta(i), carta(i+5)
+		}
+		e := Novo("a", "b", maoA, maoB)
+		for i, p := range passos {
+			a := Acao{Tipo: tipos[p&7], Jogador: quem[(p>>3)&3]}
+			switch mao := e.Mao[a.Jogador]; {
+			case p>>5 < 6 && len(mao) > 0:
+				a.Carta = mao[int(p>>5)%len(mao)]
+			default:
+				a.Carta = int(p>>5) - 3
+			}
+			n, err := conferirPasso(e, a)
+			if err != nil {
+				t.Fatalf("passo %d (%+v): %v", i, a, err)
+			}
+			e = n
+		}
+		if len(passos) > 0 {
+			if err := conferirEncerrar(e, quem[int(passos[0])%len(quem)]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func sortearAcao(rng *rand.Rand, e Estado, quem []string) Acao {
+	tipos := []string{JogarCarta, JogarCarta, JogarCarta, FimTurno, Tempo, Desconexao, "???"}
+	a := Acao{Tipo: tipos[rng.Intn(len(tipos))], Jogador: quem[rng.Intn(len(quem))]}
+	if a.Tipo == Desconexao && rng.Intn(5) > 0 {
+		a.Tipo = FimTurno // desconexões encerrariam quase todas as partidas cedo
+	}
+	if mao := e.Mao[a.Jogador]; len(mao) > 0 && rng.Intn(4) > 0 {
+		a.Carta = mao[rng.Intn(len(mao))]
+	} else {
+		a.Carta = rng.Intn(TotalCartas+6) - 3
+	}
+	return a
+}
+
+// aplica a ação conferindo as propriedades; devolve o novo estado (o mesmo, se recusada)
+func conferirPasso(e Estado, a Acao) (Estado, error) {
+	n, _, err := aplicarConferindo(e, a)
+	if err == nil {
+		return n, nil
+	}
+	if !errors.Is(err, ErrCartaForaDaMao) && !errors.Is(err, ErrForaDaVez) && !errors.Is(err, ErrPartidaEncerrada) &&
+		!errors.Is(err, ErrJogadorDesconhecido) && !errors.Is(err, ErrAcaoDesconhecida) {
+		return e, fmt.Errorf("erro inesperado %v", err)
+	}
+	if problema := conferirErro(e, a, err); problema != "" {
+		return e, errors.New(problema)
+	}
+	return e, nil
+}
+
+// propriedades de Encerrar: não altera a entrada, recusa partidas encerradas e vencedores
+// de fora, e depois dele o motor não aceita mais ações
+func conferirEncerrar(e Estado, vencedor string) error {
+	antes := e.copiar()
+	n, ev, err := Encerrar(e, vencedor)
+	switch {
+	case !reflect.DeepEqual(e, antes):
+		return fmt.Errorf("Encerrar alterou o estado recebido")
+	case e.Encerrada && !errors.Is(err, ErrPartidaEncerrada):
+		return fmt.Errorf("Encerrar de partida encerrada: %v", err)
+	case !e.Encerrada && vencedor != "" && e.Oponente(vencedor) == "" && !errors.Is(err, ErrJogadorDesconhecido):
+		return fmt.Errorf("Encerrar com vencedor de fora %q: %v", vencedor, err)
+	case err != nil:
+		if !reflect.DeepEqual(n, e) {
+			return fmt.Errorf("Encerrar recusado (%v) mudou o estado", err)
+		}
+		return nil
+	}
+	if err := Verificar(n); err != nil {
+		return fmt.Errorf("invariante violada após Encerrar(%q): %w", vencedor, err)
+	}
+	if !n.Encerrada || n.Vencedor != vencedor || n.Motivo != MotivoAdmin || ev.Tipo != EventoFim {
+		return fmt.Errorf("Encerrar(%q) produziu %+v, %+v", vencedor, n, ev)
+	}
+	if _, _, err := Aplicar(n, Acao{FimTurno, n.Turno, 0}); !errors.Is(err, ErrPartidaEncerrada) {
+		return fmt.Errorf("ação aceita depois de Encerrar: %v", err)
+	}
+	return nil
+}
+
+// aplica a ação conferindo as propriedades que valem para qualquer ação
+func aplicarConferindo(e Estado, a Acao) (Estado, []Evento, error) {
+	antes := e.copiar()
+	n, ev, err := Aplicar(e, a)
+	n2, ev2, err2 := Aplicar(e, a)
+	switch {
+	case !reflect.DeepEqual(e, antes):
+		err = fmt.Errorf("Aplicar alterou o estado recebido")
+	case !reflect.DeepEqual(n, n2) || !reflect.DeepEqual(ev, ev2) || !errors.Is(err2, err):
+		err = fmt.Errorf("Aplicar não é determinística")
+	case err != nil && (!reflect.DeepEqual(n, e) || len(ev) > 0):
+		err = fmt.Errorf("ação recusada (%v) mudou o estado ou gerou eventos", err)
+	case err == nil:
+		err = conferirSucesso(e, a, n, ev)
+	}
+	return n, ev, err
+}
+
+// propriedades de uma ação aceita
+func conferirSucesso(e Estado, a Acao, n Estado, ev []Evento) error {
+	if err := Verificar(n); err != nil {
+		return fmt.Errorf("invariante violada após %+v: %w", a, err)
+	}
+	for _, id := range e.Jogadores {
+		if n.Vida[id] > e.Vida[id] {
+			return fmt.Errorf("vida de %s aumentou de %d para %d", id, e.Vida[id], n.Vida[id])
+		}
+	}
+	if a.Tipo != Desconexao && a.Jogador != e.Turno {
+		return fmt.Errorf("%s agiu fora da vez (%s)", a.Jogador, a.Tipo)
+	}
+	if len(ev) == 0 {
+		return fmt.Errorf("ação aceita sem eventos")
+	}
+	ultimo := ev[len(ev)-1]
+	switch {
+	case n.Encerrada && ultimo.Tipo != EventoFim:
+		return fmt.Errorf("partida encerrada sem evento de fim")
+	case !n.Encerrada && (ultimo.Tipo != EventoTurno || n.Turno == e.Turno):
+		return fmt.Errorf("ação aceita sem trocar a vez")
+	case a.Tipo == JogarCarta && len(n.Mao[a.Jogador]) != len(e.Mao[a.Jogador])-1:
+		return fmt.Errorf("a carta jogada não saiu da mão")
+	}
+	return nil
+}
+
+// a recusa precisa ter o motivo certo
+func conferirErro(e Estado, a Acao, err error) string {
+	var esperado error
+	switch {
+	case e.Encerrada:
+		esperado = ErrPartidaEncerrada
+	case e.Oponente(a.Jogador) == "":
+		esperado = ErrJogadorDesconhecido
+	case a.Tipo != JogarCarta && a.Tipo != FimTurno && a.Tipo != Tempo && a.Tipo != Desconexao:
+		esperado = ErrAcaoDesconhecida
+	case a.Tipo != Desconexao && a.Jogador != e.Turno:
+		esperado = ErrForaDaVez
+	default:
+		esperado = ErrCartaForaDaMao
+	}
+	if !errors.Is(err, esperado) {
+		return fmt.Sprintf("recusada com %v, esperado %v", err, esperado)
+	}
+	return ""
+}
+
+// mão de 5 cartas distintas do catálogo
+func sortearMao(rng *rand.Rand) []int {
+	ids := rng.Perm(TotalCartas)[:5]
+	for i := range ids {
+		ids[i]++
+	}
+	return ids
+}
@@ -0,0 +1,54 @@
+// propriedades.go
+package motor
+
+import "fmt"
+
+// confere as invariantes de um estado; retorna o primeiro problema encontrado
+func Verificar(e Estado) error {
+	a, b := e.Jogadores[0], e.Jogadores[1]
+	if a == "" || b == "" || a == b {
+		return fmt.Errorf("jogadores inválidos %q e %q", a, b)
+	}
+	if len(e.Vida) != 2 || len(e.Mao) > 2 {
+		return fmt.Errorf("vida ou mãos de jogadores que não participam: %v %v", e.Vida, e.Mao)
+	}
+	for _, id := range e.Jogadores {
+		v, ok := e.Vida[id]
+		if !ok || v < 0 || v > VidaInicial {
+			return fmt.Errorf("vida de %s fora de 0..%d: %d", id, VidaInicial, v)
+		}
+		for _, c := range e.Mao[id] {
+			if c < 1 || c > TotalCartas {
+				return fmt.Errorf("carta %d fora do catálogo na mão de %s", c, id)
+			}
+		}
+	}
+	if e.Turno != a && e.Turno != b {
+		return fmt.Errorf("turno de quem não joga: %q", e.Turno)
+	}
+	zerado := e.Vida[a] == 0 || e.Vida[b] == 0
+	if !e.Encerrada {
+		if zerado || e.Vencedor != "" || e.Motivo != "" {
+			return fmt.Errorf("partida em andamento com vida zerada ou vencedor: %v %q %q", e.Vida, e.Vencedor, e.Motivo)
+		}
+		return nil
+	}
+	if e.Motivo == MotivoAdmin {
+		if e.Vencedor != "" && e.Oponente(e.Vencedor) == "" {
+			return fmt.Errorf("vencedor %q não participa da partida", e.Vencedor)
+		}
+		return nil
+	}
+	perdedor := e.Oponente(e.Vencedor)
+	switch {
+	case perdedor == "":
+		return fmt.Errorf("vencedor %q não participa da partida", e.Vencedor)
+	case e.Motivo == MotivoVitoria && (e.Vida[perdedor] != 0 || e.Vida[e.Vencedor] == 0):
+		return fmt.Errorf("vitória de %s com vida %v", e.Vencedor, e.Vida)
+	case e.Motivo != MotivoVitoria && e.Motivo != MotivoDesconexao:
+		return fmt.Errorf("motivo de fim desconhecido %q", e.Motivo)
+	case e.Motivo == MotivoDesconexao && zerado && e.Vida[e.Vencedor] == 0:
+		return fmt.Errorf("vencedor por desconexão com vida zerada: %v", e.Vida)
+	}
+	return nil
+}